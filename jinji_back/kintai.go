@@ -0,0 +1,3984 @@
+package main
+
+import (
+  "database/sql"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "log"
+  "math"
+  "net/http"
+  "os"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+
+  "github.com/gin-gonic/gin"
+  "github.com/lib/pq"
+)
+
+// チームメンバーの勤怠
+type TeamAttendance struct {
+  Employee    Employee     `json:"employee"`
+  Attendances []Attendance `json:"attendances"`
+  Leaves      []Attendance `json:"leaves"`
+}
+
+// 指定月の勤怠データと休暇データを取得
+func fetchMonthlyAttendance(employeeID int, yearMonth string) ([]Attendance, []Attendance, error) {
+  rows, err := db.Query(`
+    SELECT a.atteid, a.attedt, a.attest, a.atteet, a.atteeo
+    FROM TBL_ATTEN a
+    WHERE a.atteid = $1 AND TO_CHAR(a.attedt, 'YYYYMM') = $2
+    ORDER BY a.attedt
+  `, employeeID, yearMonth)
+  if err != nil {
+    return nil, nil, err
+  }
+  defer rows.Close()
+
+  attendances := []Attendance{}
+  for rows.Next() {
+    var att Attendance
+    var startTime, endTime sql.NullString
+    if err := rows.Scan(&att.EmployeeID, &att.Date, &startTime, &endTime, &att.EndDayOffset); err != nil {
+      return nil, nil, err
+    }
+    att.StartTime = startTime.String
+    att.EndTime = endTime.String
+    attendances = append(attendances, att)
+  }
+  if err := rows.Err(); err != nil {
+    return nil, nil, err
+  }
+
+  leaveRows, err := db.Query(`
+    SELECT l.lereid, l.leredt, l.leretp, l.lerekb, l.lerehr, l.leresh
+    FROM TBL_LEAVE l
+    WHERE l.lereid = $1 AND TO_CHAR(l.leredt, 'YYYYMM') = $2
+    ORDER BY l.leredt
+  `, employeeID, yearMonth)
+  if err != nil {
+    return nil, nil, err
+  }
+  defer leaveRows.Close()
+
+  leaves := []Attendance{}
+  for leaveRows.Next() {
+    var leave Attendance
+    if err := leaveRows.Scan(&leave.EmployeeID, &leave.Date, &leave.LeaveType, &leave.LeaveUnit, &leave.LeaveHours, &leave.PlannedLeave); err != nil {
+      return nil, nil, err
+    }
+    leaves = append(leaves, leave)
+  }
+  return attendances, leaves, leaveRows.Err()
+}
+
+// チームの勤怠取得（月別）
+func getTeamAttendance(c *gin.Context) {
+  yearMonth := c.DefaultQuery("month", time.Now().Format("200601"))
+  if len(yearMonth) != 6 {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な月形式"})
+    return
+  }
+
+  members, err := getTeamMembers(currentEmployeeID(c))
+  if err != nil {
+    handleDatabaseError(c, err, "チーム情報の取得に失敗しました")
+    return
+  }
+
+  result := []TeamAttendance{}
+  for _, member := range members {
+    attendances, leaves, err := fetchMonthlyAttendance(member.ID, yearMonth)
+    if err != nil {
+      handleDatabaseError(c, err, "勤怠データの取得に失敗しました")
+      return
+    }
+    result = append(result, TeamAttendance{
+      Employee:    member,
+      Attendances: attendances,
+      Leaves:      leaves,
+    })
+  }
+
+  c.JSON(http.StatusOK, result)
+}
+
+// 勤怠締めの実行契機
+const (
+  closeTriggerAuto   = "auto"   // スケジューラによる自動実行
+  closeTriggerManual = "manual" // コマンドによる手動実行
+  closeTriggerHR     = "hr"     // 人事によるAPIからの実行
+)
+
+// 勤怠締め状態（TBL_GETSU.getsst, TBL_SHIME.shimst）
+const (
+  closeStatusOpen     = 1 // 入力受付中
+  closeStatusClosed   = 2 // 締め済み
+  closeStatusReopened = 3 // 締め解除（再入力受付中）
+)
+
+// 締め状態名
+var closeStatusNameMap = map[int]string{
+  closeStatusOpen:     "open",
+  closeStatusClosed:   "closed",
+  closeStatusReopened: "reopened",
+}
+
+// 勤怠締めジョブの実行記録（TBL_CLRUN）
+type CloseRun struct {
+  ID      int    `json:"id"`
+  Month   string `json:"month"` // YYYYMM形式
+  Trigger string `json:"trigger"`
+  Closed  int    `json:"closed"`  // 締め・給与計算を行った社員数
+  Skipped int    `json:"skipped"` // 締め済みのためスキップした社員数
+  Failed  int    `json:"failed"`  // エラーになった社員数
+}
+
+// 社員毎の締め状態
+type EmployeeCloseStatus struct {
+  EmployeeID int    `json:"employeeId"`
+  Name       string `json:"name"`
+  Status     string `json:"status"`
+  UpdatedAt  string `json:"updatedAt,omitempty"`
+}
+
+// 月の締め状態
+type MonthCloseStatus struct {
+  Month     string                `json:"month"`
+  Status    string                `json:"status"`
+  UpdatedAt string                `json:"updatedAt,omitempty"`
+  UpdatedBy *int                  `json:"updatedBy,omitempty"` // NULLはジョブによる更新
+  Employees []EmployeeCloseStatus `json:"employees"`
+}
+
+// 締め・締め解除リクエスト（社員IDを省略した場合は月全体が対象）
+type ClosingRequest struct {
+  EmployeeID int `json:"employeeId,omitempty"`
+}
+
+// 勤怠締め日（翌月の第一金曜日）を計算
+// 締め状態が記録されていない月は、この日時を過ぎると締め済みとみなす
+func attendanceCloseDeadline(year int, month time.Month) time.Time {
+  firstDayNextMonth := time.Date(year, month+1, 1, 0, 0, 0, 0, time.Local)
+  daysUntilFriday := (5 - int(firstDayNextMonth.Weekday()) + 7) % 7
+  if daysUntilFriday == 0 {
+    daysUntilFriday = 7
+  }
+  return firstDayNextMonth.AddDate(0, 0, daysUntilFriday)
+}
+
+// 月全体の締め状態を取得
+// persistedがfalseの場合はTBL_GETSUに記録がなく、締め日から判定した値を返す
+func monthCloseStatus(yearMonth string) (status int, persisted bool, err error) {
+  err = db.QueryRow("SELECT getsst FROM TBL_GETSU WHERE getsmt = $1", yearMonth).Scan(&status)
+  if err == nil {
+    return status, true, nil
+  }
+  if err != sql.ErrNoRows {
+    return 0, false, err
+  }
+
+  target, err := time.ParseInLocation("200601", yearMonth, time.Local)
+  if err != nil {
+    return 0, false, fmt.Errorf("無効な月形式: %s", yearMonth)
+  }
+  if time.Now().After(attendanceCloseDeadline(target.Year(), target.Month())) {
+    return closeStatusClosed, false, nil
+  }
+  return closeStatusOpen, false, nil
+}
+
+// 社員の指定月の締め状態を取得
+// 社員毎の記録（TBL_SHIME）があればそれを優先し、なければ月全体の状態を返す
+func attendanceCloseStatus(employeeID int, yearMonth string) (int, error) {
+  var status int
+  err := db.QueryRow(`
+    SELECT shimst
+    FROM TBL_SHIME
+    WHERE shimid = $1 AND shimmt = $2
+  `, employeeID, yearMonth).Scan(&status)
+  if err == nil {
+    return status, nil
+  }
+  if err != sql.ErrNoRows {
+    return 0, err
+  }
+
+  status, _, err = monthCloseStatus(yearMonth)
+  return status, err
+}
+
+// 月全体の締め状態を記録
+// operatorIDが0の場合はジョブ・コマンドによる更新として記録する
+func setMonthCloseStatus(yearMonth string, status int, operatorID int) error {
+  _, err := db.Exec(`
+    INSERT INTO TBL_GETSU (getsmt, getsst, getsdt, getsby)
+    VALUES ($1, $2, NOW(), $3)
+    ON CONFLICT (getsmt) DO UPDATE
+    SET getsst = $2, getsdt = NOW(), getsby = $3
+  `, yearMonth, status, sql.NullInt64{Int64: int64(operatorID), Valid: operatorID > 0})
+  return err
+}
+
+// 勤怠・休暇の書き込みエラーコード
+const (
+  errCodeInvalidDate = "INVALID_DATE" // 日付形式が不正
+  errCodeMonthClosed = "MONTH_CLOSED" // 対象月が締め済み
+)
+
+// 勤怠・休暇（TBL_ATTEN, TBL_LEAVE）への書き込み可否をチェックする
+// 書き込み経路はすべてこの関数を通すこと。書き込めない場合はエラーを返しfalseを返す
+func checkAttendanceWritable(c *gin.Context, employeeID int, date string) bool {
+  target, err := time.Parse("2006-01-02", date)
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な日付形式", "code": errCodeInvalidDate})
+    return false
+  }
+
+  status, err := attendanceCloseStatus(employeeID, target.Format("200601"))
+  if err != nil {
+    handleDatabaseError(c, err, "勤怠締め状態の取得に失敗しました")
+    return false
+  }
+  if status == closeStatusClosed {
+    c.JSON(http.StatusConflict, gin.H{"error": "締め済みの月の勤怠・休暇は更新できません", "code": errCodeMonthClosed})
+    return false
+  }
+  return true
+}
+
+// 勤怠締めジョブの実行間隔
+// CLOSE_JOB_INTERVAL（time.ParseDuration形式）で変更可能、0を指定すると自動実行しない
+func closeJobInterval() time.Duration {
+  interval := time.Hour
+  if v := os.Getenv("CLOSE_JOB_INTERVAL"); v != "" {
+    d, err := time.ParseDuration(v)
+    if err != nil {
+      log.Printf("環境変数 CLOSE_JOB_INTERVAL が不正です: %s（デフォルトの%sを使用）", v, interval)
+      return interval
+    }
+    interval = d
+  }
+  return interval
+}
+
+// 勤怠の自動締めスケジューラを起動
+// 前月の締め日を過ぎていれば前月分の締めと給与計算を行う（締め済みの社員はスキップ）
+// あわせて付与日が来た年次有給休暇の付与を登録する
+func startMonthlyCloseScheduler() {
+  interval := closeJobInterval()
+  if interval <= 0 {
+    log.Println("勤怠の自動締めは無効です")
+    return
+  }
+
+  go func() {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+      runDueMonthlyClose()
+      runDueAnnualLeaveGrants()
+      <-ticker.C
+    }
+  }()
+}
+
+// 締め日を過ぎた前月分の締めを実行
+func runDueMonthlyClose() {
+  target := time.Now().AddDate(0, -1, 0)
+  year, month, _ := target.Date()
+  if !time.Now().After(attendanceCloseDeadline(year, month)) {
+    return
+  }
+
+  yearMonth := target.Format("200601")
+
+  // 人事が締め解除した月は自動では締めない
+  status, persisted, err := monthCloseStatus(yearMonth)
+  if err != nil {
+    log.Printf("勤怠締め状態の取得エラー [%s]: %v", yearMonth, err)
+    return
+  }
+  if persisted && status == closeStatusReopened {
+    return
+  }
+
+  // 全社員の締めが完了している月は実行記録を残さない（社員毎に締め解除した社員は対象外）
+  var remaining int
+  err = db.QueryRow(`
+    SELECT COUNT(*)
+    FROM TBL_EMPLO e
+    WHERE NOT EXISTS (
+      SELECT 1 FROM TBL_SHIME s
+      WHERE s.shimid = e.emplid AND s.shimmt = $1 AND s.shimst IN ($2, $3)
+    )
+  `, yearMonth, closeStatusClosed, closeStatusReopened).Scan(&remaining)
+  if err != nil {
+    log.Printf("勤怠締め対象の確認エラー [%s]: %v", yearMonth, err)
+    return
+  }
+  if remaining == 0 {
+    return
+  }
+
+  run, err := runMonthlyClose(yearMonth, closeTriggerAuto, 0)
+  if err != nil {
+    log.Printf("勤怠の自動締めエラー [%s]: %v", yearMonth, err)
+    return
+  }
+  log.Printf("勤怠の自動締め完了 [%s]: 締め%d件 スキップ%d件 エラー%d件", run.Month, run.Closed, run.Skipped, run.Failed)
+}
+
+// 指定月の勤怠締めと給与計算を全社員に対して実行する
+// 締め済み（TBL_SHIMEに締め済みで記録済み）の社員はスキップするため、何度実行しても結果は変わらない
+// 自動実行では社員毎に締め解除した社員もスキップする（締め解除した月は自動では締めない）
+// 全社員の締めが完了した場合は月全体の状態も締め済みにする
+func runMonthlyClose(yearMonth string, trigger string, operatorID int) (*CloseRun, error) {
+  if _, err := time.Parse("200601", yearMonth); err != nil {
+    return nil, fmt.Errorf("無効な月形式: %s", yearMonth)
+  }
+
+  run := &CloseRun{Month: yearMonth, Trigger: trigger}
+  err := db.QueryRow(`
+    INSERT INTO TBL_CLRUN (clrumt, clrutg, clrusd)
+    VALUES ($1, $2, NOW())
+    RETURNING clruid
+  `, yearMonth, trigger).Scan(&run.ID)
+  if err != nil {
+    return nil, err
+  }
+
+  // 未締めの社員を取得（失敗した場合も実行中のまま残さず、エラー内容を記録して終了にする）
+  targets, err := closeTargets(run, yearMonth, trigger)
+  if err != nil {
+    if ferr := finishCloseRun(run, "社員の取得に失敗しました: "+err.Error()); ferr != nil {
+      log.Printf("勤怠締め実行記録の更新エラー [%d]: %v", run.ID, ferr)
+    }
+    return nil, err
+  }
+
+  var errorMessages []string
+  for _, employeeID := range targets {
+    if err := closeEmployeeMonth(employeeID, yearMonth, run.ID); err != nil {
+      log.Printf("勤怠締めエラー [%d %s]: %v", employeeID, yearMonth, err)
+      errorMessages = append(errorMessages, fmt.Sprintf("%d: %v", employeeID, err))
+      run.Failed++
+      continue
+    }
+    run.Closed++
+  }
+
+  // 実行記録を更新
+  if err := finishCloseRun(run, strings.Join(errorMessages, "\n")); err != nil {
+    return nil, err
+  }
+
+  if run.Failed == 0 {
+    if err := setMonthCloseStatus(yearMonth, closeStatusClosed, operatorID); err != nil {
+      return nil, err
+    }
+  }
+
+  return run, nil
+}
+
+// 締めの対象となる社員を取得し、締め済みの社員はスキップ数に数える
+func closeTargets(run *CloseRun, yearMonth string, trigger string) ([]int, error) {
+  rows, err := db.Query(`
+    SELECT e.emplid, COALESCE(s.shimst, 0)
+    FROM TBL_EMPLO e
+    LEFT JOIN TBL_SHIME s ON s.shimid = e.emplid AND s.shimmt = $1
+    ORDER BY e.emplid
+  `, yearMonth)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var targets []int
+  for rows.Next() {
+    var employeeID, status int
+    if err := rows.Scan(&employeeID, &status); err != nil {
+      return nil, err
+    }
+    if status == closeStatusClosed || (trigger == closeTriggerAuto && status == closeStatusReopened) {
+      run.Skipped++
+      continue
+    }
+    targets = append(targets, employeeID)
+  }
+  return targets, rows.Err()
+}
+
+// 実行記録に終了日時・件数・エラー内容を記録する
+func finishCloseRun(run *CloseRun, message string) error {
+  runes := []rune(message)
+  if len(runes) > 1000 {
+    runes = runes[:1000]
+  }
+  _, err := db.Exec(`
+    UPDATE TBL_CLRUN
+    SET clrued = NOW(), clrucl = $2, clrusk = $3, clruer = $4, clrums = $5
+    WHERE clruid = $1
+  `, run.ID, run.Closed, run.Skipped, run.Failed, string(runes))
+  return err
+}
+
+// 社員1名分の締め処理（給与計算後に締め済みを記録する）
+// runIDが0の場合はジョブ外（人事による個別締め）として記録する
+func closeEmployeeMonth(employeeID int, yearMonth string, runID int) error {
+  tx, err := db.Begin()
+  if err != nil {
+    return err
+  }
+  defer tx.Rollback()
+
+  if err := calculateSalary(tx, employeeID, yearMonth); err != nil {
+    return err
+  }
+
+  _, err = tx.Exec(`
+    INSERT INTO TBL_SHIME (shimid, shimmt, shimst, shimdt, shimrn)
+    VALUES ($1, $2, $3, NOW(), $4)
+    ON CONFLICT (shimid, shimmt) DO UPDATE
+    SET shimst = $3, shimdt = NOW(), shimrn = $4
+  `, employeeID, yearMonth, closeStatusClosed, sql.NullInt64{Int64: int64(runID), Valid: runID > 0})
+  if err != nil {
+    return err
+  }
+
+  return tx.Commit()
+}
+
+// 月パラメータの検証
+func parseMonthParam(c *gin.Context) (string, bool) {
+  month := c.Param("month")
+  if _, err := time.Parse("200601", month); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な月形式"})
+    return "", false
+  }
+  return month, true
+}
+
+// 締め・締め解除リクエストの読み込み（ボディは省略可）
+func bindClosingRequest(c *gin.Context) (ClosingRequest, bool) {
+  var req ClosingRequest
+  if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト"})
+    return req, false
+  }
+  return req, true
+}
+
+// 勤怠締め状態の取得（人事のみ）
+func getClosingStatus(c *gin.Context) {
+  month, ok := parseMonthParam(c)
+  if !ok {
+    return
+  }
+
+  result := MonthCloseStatus{Month: month, Employees: []EmployeeCloseStatus{}}
+
+  status, persisted, err := monthCloseStatus(month)
+  if err != nil {
+    handleDatabaseError(c, err, "勤怠締め状態の取得に失敗しました")
+    return
+  }
+  result.Status = closeStatusNameMap[status]
+
+  if persisted {
+    var updatedAt time.Time
+    var updatedBy sql.NullInt64
+    err := db.QueryRow("SELECT getsdt, getsby FROM TBL_GETSU WHERE getsmt = $1", month).Scan(&updatedAt, &updatedBy)
+    if err != nil {
+      handleDatabaseError(c, err, "勤怠締め状態の取得に失敗しました")
+      return
+    }
+    result.UpdatedAt = updatedAt.Format(time.RFC3339)
+    if updatedBy.Valid {
+      operatorID := int(updatedBy.Int64)
+      result.UpdatedBy = &operatorID
+    }
+  }
+
+  rows, err := db.Query(`
+    SELECT e.emplid, e.emplnm, s.shimst, s.shimdt
+    FROM TBL_EMPLO e
+    LEFT JOIN TBL_SHIME s ON s.shimid = e.emplid AND s.shimmt = $1
+    ORDER BY e.emplid
+  `, month)
+  if err != nil {
+    handleDatabaseError(c, err, "勤怠締め状態の取得に失敗しました")
+    return
+  }
+  defer rows.Close()
+
+  for rows.Next() {
+    var employee EmployeeCloseStatus
+    var employeeStatus sql.NullInt64
+    var updatedAt sql.NullTime
+    if err := rows.Scan(&employee.EmployeeID, &employee.Name, &employeeStatus, &updatedAt); err != nil {
+      c.JSON(http.StatusInternalServerError, gin.H{"error": "データ読み取りエラー"})
+      return
+    }
+
+    // 社員毎の記録がなければ月全体の状態に従う
+    employee.Status = result.Status
+    if employeeStatus.Valid {
+      employee.Status = closeStatusNameMap[int(employeeStatus.Int64)]
+      employee.UpdatedAt = updatedAt.Time.Format(time.RFC3339)
+    }
+    result.Employees = append(result.Employees, employee)
+  }
+
+  c.JSON(http.StatusOK, result)
+}
+
+// 勤怠締め（人事のみ）
+// 社員IDを指定した場合はその社員のみ、省略した場合は月全体を締めて給与計算を行う
+func closeMonth(c *gin.Context) {
+  month, ok := parseMonthParam(c)
+  if !ok {
+    return
+  }
+  req, ok := bindClosingRequest(c)
+  if !ok {
+    return
+  }
+
+  if req.EmployeeID > 0 {
+    if err := closeEmployeeMonth(req.EmployeeID, month, 0); err != nil {
+      handleDatabaseError(c, err, "勤怠締めに失敗しました")
+      return
+    }
+    log.Printf("勤怠締め [%d %s] 実行者%d", req.EmployeeID, month, currentEmployeeID(c))
+    c.JSON(http.StatusOK, gin.H{"message": "勤怠を締めました"})
+    return
+  }
+
+  run, err := runMonthlyClose(month, closeTriggerHR, currentEmployeeID(c))
+  if err != nil {
+    handleDatabaseError(c, err, "勤怠締めに失敗しました")
+    return
+  }
+  c.JSON(http.StatusOK, run)
+}
+
+// 勤怠締め解除（人事のみ）
+// 社員IDを指定した場合はその社員のみ、省略した場合は月全体を再入力可能にする
+func reopenMonth(c *gin.Context) {
+  month, ok := parseMonthParam(c)
+  if !ok {
+    return
+  }
+  req, ok := bindClosingRequest(c)
+  if !ok {
+    return
+  }
+
+  operatorID := currentEmployeeID(c)
+
+  if req.EmployeeID > 0 {
+    _, err := db.Exec(`
+      INSERT INTO TBL_SHIME (shimid, shimmt, shimst, shimdt)
+      VALUES ($1, $2, $3, NOW())
+      ON CONFLICT (shimid, shimmt) DO UPDATE
+      SET shimst = $3, shimdt = NOW()
+    `, req.EmployeeID, month, closeStatusReopened)
+    if err != nil {
+      handleDatabaseError(c, err, "勤怠締め解除に失敗しました")
+      return
+    }
+    log.Printf("勤怠締め解除 [%d %s] 実行者%d", req.EmployeeID, month, operatorID)
+    c.JSON(http.StatusOK, gin.H{"message": "勤怠の締めを解除しました"})
+    return
+  }
+
+  executeWithTransaction(c, func(tx *sql.Tx) error {
+    _, err := tx.Exec(`
+      INSERT INTO TBL_GETSU (getsmt, getsst, getsdt, getsby)
+      VALUES ($1, $2, NOW(), $3)
+      ON CONFLICT (getsmt) DO UPDATE
+      SET getsst = $2, getsdt = NOW(), getsby = $3
+    `, month, closeStatusReopened, operatorID)
+    if err != nil {
+      return err
+    }
+
+    // 社員毎の締め済み記録も解除
+    _, err = tx.Exec(`
+      UPDATE TBL_SHIME
+      SET shimst = $2, shimdt = NOW()
+      WHERE shimmt = $1
+    `, month, closeStatusReopened)
+    if err != nil {
+      return err
+    }
+
+    log.Printf("勤怠締め解除 [%s] 実行者%d", month, operatorID)
+    return nil
+  }, "勤怠の締めを解除しました")
+}
+
+// 労働時間の法定基準（分）
+const (
+  statutoryDailyMinutes  = 8 * 60  // 1日8時間
+  statutoryWeeklyMinutes = 40 * 60 // 1週40時間（週は日曜日から）
+  longOvertimeMinutes    = 60 * 60 // 月60時間を超える時間外労働は割増率を引き上げる
+  lateNightStartMinute   = 22 * 60 // 深夜労働 22:00〜
+  lateNightEndMinute     = 5 * 60  // 〜5:00
+  minutesPerDay          = 24 * 60
+)
+
+// 勤務形態（所定労働時間・休憩・休日）
+type WorkSchedule struct {
+  EffectiveFrom string `json:"effectiveFrom"` // 適用開始日YYYY-MM-DD
+  StartTime     string `json:"startTime"`     // 始業時刻HH:MM
+  EndTime       string `json:"endTime"`       // 終業時刻HH:MM
+  BreakStart    string `json:"breakStart"`    // 休憩開始時刻HH:MM（休憩なしの場合は空）
+  BreakEnd      string `json:"breakEnd"`      // 休憩終了時刻HH:MM
+  RestDays      []int  `json:"restDays"`      // 所定休日の曜日（0:日〜6:土、法定休日を含む）
+  LegalHoliday  int    `json:"legalHoliday"`  // 法定休日の曜日
+}
+
+// 勤務形態の登録リクエスト
+type WorkScheduleRequest struct {
+  EmployeeID int `json:"employeeId" binding:"required"`
+  WorkSchedule
+}
+
+// TBL_KINMUに登録がない社員の勤務形態（9:00〜18:00、休憩12:00〜13:00、土日休み、日曜日が法定休日）
+var defaultWorkSchedule = WorkSchedule{
+  StartTime:    "09:00",
+  EndTime:      "18:00",
+  BreakStart:   "12:00",
+  BreakEnd:     "13:00",
+  RestDays:     []int{0, 6},
+  LegalHoliday: 0,
+}
+
+// 時刻（HH:MMまたはHH:MM:SS）を0時からの分に変換
+func parseClock(clock string) (int, error) {
+  for _, layout := range []string{"15:04:05", "15:04"} {
+    if t, err := time.Parse(layout, clock); err == nil {
+      return t.Hour()*60 + t.Minute(), nil
+    }
+  }
+  return 0, fmt.Errorf("無効な時刻形式: %s", clock)
+}
+
+// 勤務形態の検証
+func (s *WorkSchedule) validate() error {
+  start, err := parseClock(s.StartTime)
+  if err != nil {
+    return err
+  }
+  end, err := parseClock(s.EndTime)
+  if err != nil {
+    return err
+  }
+  if end <= start {
+    return fmt.Errorf("終業時刻は始業時刻より後を指定してください")
+  }
+
+  if s.BreakStart != "" || s.BreakEnd != "" {
+    breakStart, err := parseClock(s.BreakStart)
+    if err != nil {
+      return err
+    }
+    breakEnd, err := parseClock(s.BreakEnd)
+    if err != nil {
+      return err
+    }
+    if breakEnd <= breakStart || breakStart < start || breakEnd > end {
+      return fmt.Errorf("休憩時間は始業時刻から終業時刻の間で指定してください")
+    }
+  }
+
+  seen := map[int]bool{}
+  for _, day := range s.RestDays {
+    if day < 0 || day > 6 || seen[day] {
+      return fmt.Errorf("所定休日は0（日）〜6（土）の曜日を重複なく指定してください")
+    }
+    seen[day] = true
+  }
+  if !seen[s.LegalHoliday] {
+    return fmt.Errorf("法定休日は所定休日の中から指定してください")
+  }
+  return nil
+}
+
+// 所定休日かどうか
+func (s *WorkSchedule) isRestDay(weekday time.Weekday) bool {
+  for _, day := range s.RestDays {
+    if day == int(weekday) {
+      return true
+    }
+  }
+  return false
+}
+
+// 所定労働時間内の時刻か（0時からの分、所定休日は常にfalse）
+func (s *WorkSchedule) isScheduled(weekday time.Weekday, minute int) bool {
+  if s.isRestDay(weekday) {
+    return false
+  }
+  start, _ := parseClock(s.StartTime)
+  end, _ := parseClock(s.EndTime)
+  if minute < start || minute >= end {
+    return false
+  }
+  return !s.isBreak(minute)
+}
+
+// 休憩時間内の時刻か（0時からの分）
+func (s *WorkSchedule) isBreak(minute int) bool {
+  if s.BreakStart == "" {
+    return false
+  }
+  breakStart, _ := parseClock(s.BreakStart)
+  breakEnd, _ := parseClock(s.BreakEnd)
+  return minute >= breakStart && minute < breakEnd
+}
+
+// 1日の所定労働時間（分）
+func (s *WorkSchedule) dailyMinutes() int {
+  start, _ := parseClock(s.StartTime)
+  end, _ := parseClock(s.EndTime)
+  minutes := end - start
+  if s.BreakStart != "" {
+    breakStart, _ := parseClock(s.BreakStart)
+    breakEnd, _ := parseClock(s.BreakEnd)
+    minutes -= breakEnd - breakStart
+  }
+  return minutes
+}
+
+// 1か月平均所定労働時間（時間）
+// 年間の所定労働日数を所定休日の曜日数から求め、12で割る
+func (s *WorkSchedule) monthlyHours() float64 {
+  workDays := 365 - 52*len(s.RestDays)
+  return float64(s.dailyMinutes()) / 60 * float64(workDays) / 12
+}
+
+// 社員の勤務形態の履歴を取得（適用開始日の降順、登録がない場合は空）
+func fetchWorkSchedules(employeeID int) ([]WorkSchedule, error) {
+  rows, err := db.Query(`
+    SELECT kinmfm, kinmst, kinmet, kinmbs, kinmbe, kinmkd, kinmhd
+    FROM TBL_KINMU
+    WHERE kinmid = $1
+    ORDER BY kinmfm DESC
+  `, employeeID)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  schedules := []WorkSchedule{}
+  for rows.Next() {
+    var s WorkSchedule
+    var effectiveFrom time.Time
+    var breakStart, breakEnd sql.NullString
+    var restDays string
+    err := rows.Scan(&effectiveFrom, &s.StartTime, &s.EndTime, &breakStart, &breakEnd, &restDays, &s.LegalHoliday)
+    if err != nil {
+      return nil, err
+    }
+    s.EffectiveFrom = effectiveFrom.Format("2006-01-02")
+    s.StartTime = s.StartTime[:5]
+    s.EndTime = s.EndTime[:5]
+    if breakStart.Valid && breakEnd.Valid {
+      s.BreakStart = breakStart.String[:5]
+      s.BreakEnd = breakEnd.String[:5]
+    }
+    s.RestDays = []int{}
+    for _, r := range restDays {
+      s.RestDays = append(s.RestDays, int(r-'0'))
+    }
+    schedules = append(schedules, s)
+  }
+  return schedules, rows.Err()
+}
+
+// 指定日に有効な勤務形態（historyは適用開始日の降順）
+func workScheduleOn(history []WorkSchedule, date time.Time) *WorkSchedule {
+  day := date.Format("2006-01-02")
+  for i := range history {
+    if history[i].EffectiveFrom <= day {
+      return &history[i]
+    }
+  }
+  return &defaultWorkSchedule
+}
+
+// 月の労働時間の集計（残業代の計算に使用、単位は分）
+type WorkTimeSummary struct {
+  ScheduledHours      float64 `json:"scheduledHours"`      // 1か月平均所定労働時間（時間）
+  WorkedMinutes       int     `json:"workedMinutes"`       // 実労働時間
+  NonStatutoryMinutes int     `json:"nonStatutoryMinutes"` // 所定時間外・法定時間内の労働（割増なし）
+  OvertimeMinutes     int     `json:"overtimeMinutes"`     // 法定時間外労働（月60時間まで）
+  LongOvertimeMinutes int     `json:"longOvertimeMinutes"` // 法定時間外労働（月60時間超）
+  HolidayMinutes      int     `json:"holidayMinutes"`      // 法定休日労働
+  LateNightMinutes    int     `json:"lateNightMinutes"`    // 深夜労働（22:00〜5:00、他の区分と重複して数える）
+  PaidLeaveDays       float64 `json:"paidLeaveDays"`       // 有給の休暇の取得日数（半休・時間単位を含む）
+  PaidLeaveHours      int     `json:"paidLeaveHours"`      // うち時間単位の休暇の時間数
+}
+
+// 時間帯 [Start, End)
+type timeRange struct {
+  Start time.Time
+  End   time.Time
+}
+
+// 勤怠の区間の種類（TBL_KUKAN.kukakb）
+const (
+  segmentKindWork  = 1 // 勤務
+  segmentKindBreak = 2 // 休憩
+)
+
+// 勤務・休憩の区間（時刻は勤怠の日付からの相対で、翌日の場合はオフセット1）
+type AttendanceSegment struct {
+  StartTime      string `json:"startTime"`                // HH:MM
+  StartDayOffset int    `json:"startDayOffset,omitempty"` // 開始が翌日の場合は1
+  EndTime        string `json:"endTime,omitempty"`        // HH:MM（勤務中・休憩中は空）
+  EndDayOffset   int    `json:"endDayOffset,omitempty"`   // 終了が翌日の場合は1
+}
+
+// 区間の時間帯（終了時刻が未入力の場合はEndがゼロ値）
+func (seg *AttendanceSegment) toRange(date time.Time) (timeRange, error) {
+  if seg.StartDayOffset < 0 || seg.StartDayOffset > 1 || seg.EndDayOffset < 0 || seg.EndDayOffset > 1 {
+    return timeRange{}, fmt.Errorf("日のオフセットは0または1を指定してください")
+  }
+  start, err := parseClock(seg.StartTime)
+  if err != nil {
+    return timeRange{}, err
+  }
+  r := timeRange{Start: date.Add(time.Duration(start+seg.StartDayOffset*minutesPerDay) * time.Minute)}
+  if seg.EndTime == "" {
+    if seg.EndDayOffset != 0 {
+      return timeRange{}, fmt.Errorf("終了時刻を入力してください")
+    }
+    return r, nil
+  }
+  end, err := parseClock(seg.EndTime)
+  if err != nil {
+    return timeRange{}, err
+  }
+  r.End = date.Add(time.Duration(end+seg.EndDayOffset*minutesPerDay) * time.Minute)
+  if !r.End.After(r.Start) {
+    return timeRange{}, fmt.Errorf("終了時刻は開始時刻より後を指定してください（日付をまたぐ場合は翌日を指定）")
+  }
+  return r, nil
+}
+
+// 時刻から区間を作る（dateからの相対）
+func newAttendanceSegment(date time.Time, r timeRange) AttendanceSegment {
+  offset := func(t time.Time) int {
+    d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, date.Location())
+    return int(d.Sub(date).Hours() / 24)
+  }
+  seg := AttendanceSegment{
+    StartTime:      r.Start.Format("15:04"),
+    StartDayOffset: offset(r.Start),
+  }
+  if !r.End.IsZero() {
+    seg.EndTime = r.End.Format("15:04")
+    seg.EndDayOffset = offset(r.End)
+  }
+  return seg
+}
+
+// 区間の一覧を時間帯に変換する（開始時刻の昇順で重複なし、終了時刻の未入力は最後の区間のみ可）
+func parseSegments(date time.Time, segments []AttendanceSegment, label string) ([]timeRange, error) {
+  ranges := make([]timeRange, 0, len(segments))
+  for i := range segments {
+    r, err := segments[i].toRange(date)
+    if err != nil {
+      return nil, fmt.Errorf("%s区間%d: %w", label, i+1, err)
+    }
+    if i > 0 {
+      previous := ranges[i-1]
+      if previous.End.IsZero() {
+        return nil, fmt.Errorf("%s区間%d: 終了時刻を入力してください", label, i)
+      }
+      if r.Start.Before(previous.End) {
+        return nil, fmt.Errorf("%s区間%d: 前の区間と重複しているか、開始時刻の順に並んでいません", label, i+1)
+      }
+    }
+    ranges = append(ranges, r)
+  }
+  return ranges, nil
+}
+
+// 勤務・休憩の区間を検証し、勤怠の出勤・退勤時刻（最初の開始・最後の終了）を設定する
+// 区間を指定しない従来の形式（startTime/endTime）はそのまま
+func normalizeAttendanceSegments(att *Attendance) error {
+  if len(att.Segments) == 0 {
+    if len(att.Breaks) > 0 {
+      return fmt.Errorf("休憩を指定する場合は勤務区間（segments）も指定してください")
+    }
+    return nil
+  }
+
+  date, err := time.ParseInLocation("2006-01-02", att.Date, time.Local)
+  if err != nil {
+    return fmt.Errorf("無効な日付形式")
+  }
+  work, err := parseSegments(date, att.Segments, "勤務")
+  if err != nil {
+    return err
+  }
+  breaks, err := parseSegments(date, att.Breaks, "休憩")
+  if err != nil {
+    return err
+  }
+  if att.Segments[0].StartDayOffset != 0 {
+    return fmt.Errorf("最初の勤務区間は当日に開始してください")
+  }
+
+  // 休憩はいずれかの勤務区間の中に含まれること
+  for i, b := range breaks {
+    inside := false
+    for _, w := range work {
+      if b.Start.Before(w.Start) {
+        continue
+      }
+      if w.End.IsZero() || (!b.End.IsZero() && !b.End.After(w.End)) {
+        inside = true
+        break
+      }
+    }
+    if !inside {
+      return fmt.Errorf("休憩区間%d: 勤務区間の中で指定してください", i+1)
+    }
+  }
+
+  // 勤怠の出勤・退勤時刻は最初の勤務の開始と最後の勤務の終了
+  first := att.Segments[0]
+  last := att.Segments[len(att.Segments)-1]
+  att.StartTime = first.StartTime
+  att.EndTime = last.EndTime
+  att.EndDayOffset = last.EndDayOffset
+  return nil
+}
+
+// 勤怠の区間を登録し直す（区間を指定しない従来の形式の場合は削除のみ）
+func replaceAttendanceSegments(tx *sql.Tx, att *Attendance) error {
+  _, err := tx.Exec("DELETE FROM TBL_KUKAN WHERE kukaid = $1 AND kukadt = $2", att.EmployeeID, att.Date)
+  if err != nil || len(att.Segments) == 0 {
+    return err
+  }
+
+  date, err := time.ParseInLocation("2006-01-02", att.Date, time.Local)
+  if err != nil {
+    return err
+  }
+  seq := 0
+  insert := func(kind int, segments []AttendanceSegment) error {
+    for i := range segments {
+      r, err := segments[i].toRange(date)
+      if err != nil {
+        return err
+      }
+      seq++
+      end := sql.NullString{String: r.End.Format("2006-01-02 15:04:05"), Valid: !r.End.IsZero()}
+      _, err = tx.Exec(`
+        INSERT INTO TBL_KUKAN (kukaid, kukadt, kukasq, kukakb, kukast, kukaet)
+        VALUES ($1, $2, $3, $4, $5, $6)
+      `, att.EmployeeID, att.Date, seq, kind, r.Start.Format("2006-01-02 15:04:05"), end)
+      if err != nil {
+        return err
+      }
+    }
+    return nil
+  }
+  if err := insert(segmentKindWork, att.Segments); err != nil {
+    return err
+  }
+  return insert(segmentKindBreak, att.Breaks)
+}
+
+// 指定期間の勤怠の区間を取得（日付YYYY-MM-DD毎、toは含まない）
+func fetchAttendanceSegments(employeeID int, from string, to string) (map[string]*Attendance, error) {
+  rows, err := db.Query(`
+    SELECT kukadt, kukakb, kukast, kukaet
+    FROM TBL_KUKAN
+    WHERE kukaid = $1 AND kukadt >= $2 AND kukadt < $3
+    ORDER BY kukadt, kukakb, kukast
+  `, employeeID, from, to)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  days := map[string]*Attendance{}
+  for rows.Next() {
+    var d, start time.Time
+    var end sql.NullTime
+    var kind int
+    if err := rows.Scan(&d, &kind, &start, &end); err != nil {
+      return nil, err
+    }
+
+    // TIMESTAMPは時差なしで読み込まれるため、日付も同じ基準で扱う
+    date := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, start.Location())
+    key := date.Format("2006-01-02")
+    day, ok := days[key]
+    if !ok {
+      day = &Attendance{}
+      days[key] = day
+    }
+
+    r := timeRange{Start: start}
+    if end.Valid {
+      r.End = end.Time
+    }
+    if kind == segmentKindBreak {
+      day.Breaks = append(day.Breaks, newAttendanceSegment(date, r))
+    } else {
+      day.Segments = append(day.Segments, newAttendanceSegment(date, r))
+    }
+  }
+  return days, rows.Err()
+}
+
+// 1日分の勤怠に区間と労働時間を設定する
+// 区間の登録がない従来の形式は出勤〜退勤を1つの勤務区間とし、所定の休憩時間を休憩区間として返す
+func loadAttendanceDetail(att *Attendance, dateStr string) error {
+  date, err := time.ParseInLocation("2006-01-02", dateStr, time.Local)
+  if err != nil {
+    return err
+  }
+
+  segments, err := fetchAttendanceSegments(att.EmployeeID, dateStr, date.AddDate(0, 0, 1).Format("2006-01-02"))
+  if err != nil {
+    return err
+  }
+  history, err := fetchWorkSchedules(att.EmployeeID)
+  if err != nil {
+    return err
+  }
+  schedule := workScheduleOn(history, date)
+
+  if day, ok := segments[dateStr]; ok {
+    att.Segments = day.Segments
+    att.Breaks = day.Breaks
+  } else if att.StartTime != "" {
+    work, breaks := dayWorkRanges(date, att, schedule)
+    if len(work) == 0 {
+      // 退勤前（出勤時刻のみ）
+      start := AttendanceSegment{StartTime: att.StartTime}
+      if r, err := start.toRange(date); err == nil {
+        work = []timeRange{r}
+      }
+    }
+    for _, r := range work {
+      att.Segments = append(att.Segments, newAttendanceSegment(date, r))
+    }
+    for _, r := range breaks {
+      att.Breaks = append(att.Breaks, newAttendanceSegment(date, r))
+    }
+  }
+
+  calculateAttendanceMinutes(date, att, schedule)
+  return nil
+}
+
+// 1日分の勤務と休憩の時間帯（終了時刻が未入力の区間は含めない）
+// 区間の登録がない従来の形式は出勤〜退勤を勤務とし、所定の休憩時間を休憩とする
+func dayWorkRanges(date time.Time, att *Attendance, schedule *WorkSchedule) (work []timeRange, breaks []timeRange) {
+  closed := func(segments []AttendanceSegment) []timeRange {
+    var ranges []timeRange
+    for i := range segments {
+      if r, err := segments[i].toRange(date); err == nil && !r.End.IsZero() {
+        ranges = append(ranges, r)
+      }
+    }
+    return ranges
+  }
+
+  if len(att.Segments) > 0 {
+    return closed(att.Segments), closed(att.Breaks)
+  }
+
+  if att.StartTime == "" || att.EndTime == "" {
+    return nil, nil
+  }
+  legacy := AttendanceSegment{StartTime: att.StartTime, EndTime: att.EndTime, EndDayOffset: att.EndDayOffset}
+  work = closed([]AttendanceSegment{legacy})
+  if len(work) == 0 || schedule.BreakStart == "" {
+    return work, nil
+  }
+
+  scheduled := AttendanceSegment{StartTime: schedule.BreakStart, EndTime: schedule.BreakEnd}
+  if r, err := scheduled.toRange(date); err == nil {
+    start := r.Start
+    if start.Before(work[0].Start) {
+      start = work[0].Start
+    }
+    end := r.End
+    if end.After(work[0].End) {
+      end = work[0].End
+    }
+    if end.After(start) {
+      breaks = []timeRange{{start, end}}
+    }
+  }
+  return work, breaks
+}
+
+// 勤務の時間帯から休憩の時間帯を除く
+func subtractRanges(work []timeRange, breaks []timeRange) []timeRange {
+  var result []timeRange
+  for _, w := range work {
+    pieces := []timeRange{w}
+    for _, b := range breaks {
+      var next []timeRange
+      for _, p := range pieces {
+        if !b.Start.Before(p.End) || !b.End.After(p.Start) {
+          next = append(next, p)
+          continue
+        }
+        if b.Start.After(p.Start) {
+          next = append(next, timeRange{p.Start, b.Start})
+        }
+        if b.End.Before(p.End) {
+          next = append(next, timeRange{b.End, p.End})
+        }
+      }
+      pieces = next
+    }
+    result = append(result, pieces...)
+  }
+  return result
+}
+
+// 時間帯の合計（分）
+func rangeMinutes(ranges []timeRange) int {
+  total := 0
+  for _, r := range ranges {
+    total += int(r.End.Sub(r.Start).Minutes())
+  }
+  return total
+}
+
+// 1日分の勤怠から労働時間帯を求める（休憩を除く）
+func workIntervals(date time.Time, att *Attendance, schedule *WorkSchedule) []timeRange {
+  return subtractRanges(dayWorkRanges(date, att, schedule))
+}
+
+// 1日分の実労働時間・休憩時間・1日8時間を超える労働時間を計算して設定する
+func calculateAttendanceMinutes(date time.Time, att *Attendance, schedule *WorkSchedule) {
+  work, breaks := dayWorkRanges(date, att, schedule)
+  att.WorkedMinutes = rangeMinutes(subtractRanges(work, breaks))
+  att.BreakMinutes = rangeMinutes(work) - att.WorkedMinutes
+  att.OvertimeMinutes = 0
+  if att.WorkedMinutes > statutoryDailyMinutes {
+    att.OvertimeMinutes = att.WorkedMinutes - statutoryDailyMinutes
+  }
+}
+
+func minInt(a, b int) int {
+  if a < b {
+    return a
+  }
+  return b
+}
+
+func maxInt(a, b int) int {
+  if a > b {
+    return a
+  }
+  return b
+}
+
+// 1日分の労働（勤怠の日付に属する労働時間帯）
+type workDay struct {
+  Date      time.Time
+  Schedule  *WorkSchedule
+  Intervals []timeRange
+}
+
+// 月の労働時間を集計する
+// 月初を含む週は前月分の労働時間も週40時間の判定に含める
+func summarizeWorkTime(employeeID int, yearMonth string) (*WorkTimeSummary, error) {
+  monthStart, err := time.ParseInLocation("200601", yearMonth, time.Local)
+  if err != nil {
+    return nil, err
+  }
+  monthEnd := monthStart.AddDate(0, 1, 0)
+  weekStart := monthStart.AddDate(0, 0, -int(monthStart.Weekday()))
+
+  history, err := fetchWorkSchedules(employeeID)
+  if err != nil {
+    return nil, err
+  }
+
+  segments, err := fetchAttendanceSegments(employeeID, weekStart.Format("2006-01-02"), monthEnd.Format("2006-01-02"))
+  if err != nil {
+    return nil, err
+  }
+
+  rows, err := db.Query(`
+    SELECT attedt, attest, atteet, atteeo
+    FROM TBL_ATTEN
+    WHERE atteid = $1 AND attedt >= $2 AND attedt < $3
+    ORDER BY attedt
+  `, employeeID, weekStart.Format("2006-01-02"), monthEnd.Format("2006-01-02"))
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var days []workDay
+  for rows.Next() {
+    var d time.Time
+    var startTime, endTime sql.NullString
+    var att Attendance
+    if err := rows.Scan(&d, &startTime, &endTime, &att.EndDayOffset); err != nil {
+      return nil, err
+    }
+    att.StartTime = startTime.String
+    att.EndTime = endTime.String
+    date := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Local)
+    if day, ok := segments[date.Format("2006-01-02")]; ok {
+      att.Segments = day.Segments
+      att.Breaks = day.Breaks
+    }
+
+    schedule := workScheduleOn(history, date)
+    days = append(days, workDay{
+      Date:      date,
+      Schedule:  schedule,
+      Intervals: workIntervals(date, &att, schedule),
+    })
+  }
+  if err := rows.Err(); err != nil {
+    return nil, err
+  }
+
+  summary := tallyWorkTime(days, monthStart)
+  summary.ScheduledHours = workScheduleOn(history, monthEnd.AddDate(0, 0, -1)).monthlyHours()
+
+  // 月内の有給の休暇（半休は0.5日、時間単位は1日の時間数で換算）
+  usage, err := fetchLeaveUsage(employeeID, "l.leretp IN (SELECT kyukid FROM TBL_KYUKA WHERE kyukyk) AND l.leredt >= $2 AND l.leredt < $3",
+    monthStart.Format("2006-01-02"), monthEnd.Format("2006-01-02"))
+  if err != nil {
+    return nil, err
+  }
+  for _, u := range usage {
+    summary.PaidLeaveDays += u.days
+    summary.PaidLeaveHours += u.hours
+  }
+  return summary, nil
+}
+
+// 労働時間を区分毎に集計する（daysは日付の昇順）
+// 1日8時間・1週40時間（日曜日起算）を超える労働を法定時間外労働とし、法定休日の労働は別に集計する
+// monthStartより前の日は週40時間の判定にのみ使う
+func tallyWorkTime(days []workDay, monthStart time.Time) *WorkTimeSummary {
+  summary := &WorkTimeSummary{}
+  var currentWeek time.Time
+  weeklyMinutes := 0 // 週の法定時間内の労働
+  monthlyOvertime := 0
+  for _, day := range days {
+    schedule := day.Schedule
+    inMonth := !day.Date.Before(monthStart)
+
+    week := day.Date.AddDate(0, 0, -int(day.Date.Weekday()))
+    if !week.Equal(currentWeek) {
+      currentWeek = week
+      weeklyMinutes = 0
+    }
+
+    dailyMinutes := 0 // 1日の法定時間内の労働
+    for _, interval := range day.Intervals {
+      for t := interval.Start; t.Before(interval.End); t = t.Add(time.Minute) {
+        minute := t.Hour()*60 + t.Minute()
+        weekday := t.Weekday()
+        legalHoliday := weekday == time.Weekday(schedule.LegalHoliday)
+        withinStatutory := dailyMinutes < statutoryDailyMinutes && weeklyMinutes < statutoryWeeklyMinutes
+
+        if !inMonth {
+          if !legalHoliday && withinStatutory {
+            dailyMinutes++
+            weeklyMinutes++
+          }
+          continue
+        }
+
+        summary.WorkedMinutes++
+        if minute >= lateNightStartMinute || minute < lateNightEndMinute {
+          summary.LateNightMinutes++
+        }
+
+        switch {
+        case legalHoliday:
+          summary.HolidayMinutes++
+        case withinStatutory:
+          dailyMinutes++
+          weeklyMinutes++
+          if !schedule.isScheduled(weekday, minute) {
+            summary.NonStatutoryMinutes++
+          }
+        case monthlyOvertime < longOvertimeMinutes:
+          monthlyOvertime++
+          summary.OvertimeMinutes++
+        default:
+          monthlyOvertime++
+          summary.LongOvertimeMinutes++
+        }
+      }
+    }
+  }
+  return summary
+}
+
+// 勤務形態の取得（現在の勤務形態と履歴）
+func getWorkSchedule(c *gin.Context) {
+  id, err := strconv.Atoi(c.Param("id"))
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なID"})
+    return
+  }
+
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, id) {
+    return
+  }
+
+  history, err := fetchWorkSchedules(id)
+  if err != nil {
+    handleDatabaseError(c, err, "勤務形態の取得に失敗しました")
+    return
+  }
+
+  c.JSON(http.StatusOK, gin.H{
+    "current": workScheduleOn(history, time.Now()),
+    "history": history,
+  })
+}
+
+// 勤務形態の登録（人事のみ）
+func setWorkSchedule(c *gin.Context) {
+  var req WorkScheduleRequest
+  if err := c.ShouldBindJSON(&req); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト"})
+    return
+  }
+
+  if req.EffectiveFrom == "" {
+    req.EffectiveFrom = time.Now().Format("2006-01-02")
+  }
+  if _, err := time.Parse("2006-01-02", req.EffectiveFrom); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な日付形式"})
+    return
+  }
+  if err := req.validate(); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    return
+  }
+
+  restDays := ""
+  for _, day := range req.RestDays {
+    restDays += strconv.Itoa(day)
+  }
+  breakStart := sql.NullString{String: req.BreakStart, Valid: req.BreakStart != ""}
+  breakEnd := sql.NullString{String: req.BreakEnd, Valid: req.BreakEnd != ""}
+
+  _, err := db.Exec(`
+    INSERT INTO TBL_KINMU (kinmid, kinmfm, kinmst, kinmet, kinmbs, kinmbe, kinmkd, kinmhd, kinmby, kinmdt)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+    ON CONFLICT (kinmid, kinmfm) DO UPDATE
+    SET kinmst = $3, kinmet = $4, kinmbs = $5, kinmbe = $6, kinmkd = $7, kinmhd = $8, kinmby = $9, kinmdt = NOW()
+  `, req.EmployeeID, req.EffectiveFrom, req.StartTime, req.EndTime, breakStart, breakEnd, restDays, req.LegalHoliday, currentEmployeeID(c))
+  if err != nil {
+    handleDatabaseError(c, err, "勤務形態の登録に失敗しました")
+    return
+  }
+
+  c.JSON(http.StatusOK, gin.H{"message": "勤務形態を登録しました"})
+}
+
+// 出退勤時刻の形式・日付をまたぐ勤務・前後の日の勤務との重複をチェックし、不正な場合は400を返す
+// 呼び出し元はfalseが返った場合にそのままreturnすること
+func checkAttendanceTimes(c *gin.Context, att *Attendance) bool {
+  if err := validateAttendanceTimes(att); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    return false
+  }
+
+  overlap, err := attendanceOverlaps(att)
+  if err != nil {
+    log.Printf("勤怠重複チェックエラー [%d %s]: %v", att.EmployeeID, att.Date, err)
+    c.JSON(http.StatusInternalServerError, gin.H{"error": "勤怠データの確認に失敗しました"})
+    return false
+  }
+  if overlap {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "前後の日の勤務と時間が重複しています"})
+    return false
+  }
+  return true
+}
+
+// 出退勤時刻の検証
+// 退勤時刻が出勤時刻以前の場合は日付をまたぐ勤務（endDayOffset: 1）として指定する
+func validateAttendanceTimes(att *Attendance) error {
+  if att.EndDayOffset != 0 && att.EndDayOffset != 1 {
+    return fmt.Errorf("endDayOffsetは0または1を指定してください")
+  }
+  if att.StartTime == "" {
+    if att.EndTime != "" {
+      return fmt.Errorf("出勤時刻を入力してください")
+    }
+    return nil
+  }
+
+  start, err := parseClock(att.StartTime)
+  if err != nil {
+    return err
+  }
+  if att.EndTime == "" {
+    if att.EndDayOffset != 0 {
+      return fmt.Errorf("退勤時刻を入力してください")
+    }
+    return nil
+  }
+  end, err := parseClock(att.EndTime)
+  if err != nil {
+    return err
+  }
+
+  end += att.EndDayOffset * minutesPerDay
+  if end <= start {
+    return fmt.Errorf("退勤時刻は出勤時刻より後を指定してください（日付をまたぐ場合は翌日を指定）")
+  }
+  if end-start > minutesPerDay {
+    return fmt.Errorf("勤務時間は24時間以内で指定してください")
+  }
+  return nil
+}
+
+// 前日の日付をまたぐ勤務・翌日の勤務と時間が重複するか
+func attendanceOverlaps(att *Attendance) (bool, error) {
+  if att.StartTime == "" {
+    return false, nil
+  }
+
+  var overlap bool
+  err := db.QueryRow(`
+    SELECT EXISTS (
+      SELECT 1 FROM TBL_ATTEN
+      WHERE atteid = $1 AND attedt = $2::date - 1
+      AND atteeo = 1 AND atteet > $3::time
+    )
+  `, att.EmployeeID, att.Date, att.StartTime).Scan(&overlap)
+  if err != nil || overlap || att.EndDayOffset == 0 {
+    return overlap, err
+  }
+
+  err = db.QueryRow(`
+    SELECT EXISTS (
+      SELECT 1 FROM TBL_ATTEN
+      WHERE atteid = $1 AND attedt = $2::date + 1
+      AND attest < $3::time
+    )
+  `, att.EmployeeID, att.Date, att.EndTime).Scan(&overlap)
+  return overlap, err
+}
+
+// 打刻の種類
+const (
+  punchIn         = "in"          // 出勤
+  punchOut        = "out"         // 退勤
+  punchBreakStart = "break-start" // 休憩開始
+  punchBreakEnd   = "break-end"   // 休憩終了
+)
+
+// 打刻完了メッセージ
+var punchMessageMap = map[string]string{
+  punchIn:         "出勤を打刻しました",
+  punchOut:        "退勤を打刻しました",
+  punchBreakStart: "休憩開始を打刻しました",
+  punchBreakEnd:   "休憩終了を打刻しました",
+}
+
+// 打刻のエラーコード
+const (
+  errCodeAlreadyClockedIn = "ALREADY_CLOCKED_IN" // 既に出勤中
+  errCodeNotClockedIn     = "NOT_CLOCKED_IN"     // 出勤していない
+  errCodeOnBreak          = "ON_BREAK"           // 休憩中
+  errCodeNotOnBreak       = "NOT_ON_BREAK"       // 休憩中ではない
+  errCodeDuplicatePunch   = "DUPLICATE_PUNCH"    // 直前の打刻と同じ時刻
+  errCodeMissingClockOut  = "MISSING_CLOCK_OUT"  // 前日以前の退勤が未打刻
+  errCodeManualEntry      = "MANUAL_ENTRY"       // 手入力の勤怠が登録済み
+  errCodeOnLeave          = "ON_LEAVE"           // 休暇が登録済み
+)
+
+// 打刻できない場合のエラー（409で返す）
+type punchError struct {
+  code    string
+  message string
+}
+
+func (e *punchError) Error() string {
+  return e.message
+}
+
+// 終了していない区間（TBL_KUKAN.kukaetがNULL）
+type openSegment struct {
+  Date  time.Time // 勤怠の日付
+  Seq   int
+  Start time.Time
+}
+
+// 打刻時点の状態（勤務中・休憩中の区間）
+type punchState struct {
+  Work  *openSegment
+  Break *openSegment
+}
+
+// TIMESTAMP・DATEは時差なしで読み込まれるため、サーバーの時刻と同じ基準に揃える
+func wallClock(t time.Time) time.Time {
+  return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+}
+
+// 社員の終了していない勤務・休憩の区間を取得
+func loadPunchState(tx *sql.Tx, employeeID int) (*punchState, error) {
+  state := &punchState{}
+  for _, kind := range []int{segmentKindWork, segmentKindBreak} {
+    var seg openSegment
+    err := tx.QueryRow(`
+      SELECT kukadt, kukasq, kukast
+      FROM TBL_KUKAN
+      WHERE kukaid = $1 AND kukakb = $2 AND kukaet IS NULL
+      ORDER BY kukadt DESC, kukast DESC
+      LIMIT 1
+    `, employeeID, kind).Scan(&seg.Date, &seg.Seq, &seg.Start)
+    if err == sql.ErrNoRows {
+      continue
+    }
+    if err != nil {
+      return nil, err
+    }
+    seg.Date = wallClock(seg.Date)
+    seg.Start = wallClock(seg.Start)
+    if kind == segmentKindWork {
+      state.Work = &seg
+    } else {
+      state.Break = &seg
+    }
+  }
+  return state, nil
+}
+
+// 打刻の対象となる勤怠の日付
+// 出勤は当日、それ以外は勤務中の区間の日付（日付をまたぐ勤務の場合は前日）
+func (s *punchState) target(kind string, now time.Time) (time.Time, error) {
+  today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+  if s.Work != nil && s.Work.Date.Before(today.AddDate(0, 0, -1)) {
+    return time.Time{}, &punchError{errCodeMissingClockOut,
+      fmt.Sprintf("%sの退勤が打刻されていません。修正申請から退勤時刻を登録してください", s.Work.Date.Format("2006-01-02"))}
+  }
+
+  switch kind {
+  case punchIn:
+    if s.Work != nil {
+      return time.Time{}, &punchError{errCodeAlreadyClockedIn, "既に出勤しています"}
+    }
+    return today, nil
+  case punchOut, punchBreakStart:
+    if s.Work == nil {
+      return time.Time{}, &punchError{errCodeNotClockedIn, "出勤が打刻されていません"}
+    }
+    if s.Break != nil {
+      return time.Time{}, &punchError{errCodeOnBreak, "休憩中です。先に休憩終了を打刻してください"}
+    }
+    if !now.After(s.Work.Start) {
+      return time.Time{}, &punchError{errCodeDuplicatePunch, "直前の打刻と同じ時刻です"}
+    }
+    return s.Work.Date, nil
+  case punchBreakEnd:
+    if s.Break == nil {
+      return time.Time{}, &punchError{errCodeNotOnBreak, "休憩開始が打刻されていません"}
+    }
+    if !now.After(s.Break.Start) {
+      return time.Time{}, &punchError{errCodeDuplicatePunch, "直前の打刻と同じ時刻です"}
+    }
+    return s.Break.Date, nil
+  }
+  return time.Time{}, fmt.Errorf("不明な打刻の種類: %s", kind)
+}
+
+// 打刻を勤怠（TBL_ATTEN）と区間（TBL_KUKAN）に書き込む
+func writePunch(tx *sql.Tx, employeeID int, kind string, date time.Time, now time.Time, state *punchState) error {
+  dateStr := date.Format("2006-01-02")
+  stamp := now.Format("2006-01-02 15:04:05")
+
+  switch kind {
+  case punchIn:
+    // 手入力の勤怠・全日の休暇が登録されている日は打刻しない（修正は勤怠入力から行う）
+    var manual, leave bool
+    err := tx.QueryRow(`
+      SELECT
+        EXISTS (
+          SELECT 1 FROM TBL_ATTEN a
+          WHERE a.atteid = $1 AND a.attedt = $2 AND a.attest IS NOT NULL
+          AND NOT EXISTS (SELECT 1 FROM TBL_KUKAN k WHERE k.kukaid = a.atteid AND k.kukadt = a.attedt)
+        ),
+        EXISTS (SELECT 1 FROM TBL_LEAVE WHERE lereid = $1 AND leredt = $2 AND lerekb = $3)
+    `, employeeID, dateStr, leaveUnitFullDay).Scan(&manual, &leave)
+    if err != nil {
+      return err
+    }
+    if leave {
+      return &punchError{errCodeOnLeave, "全日の休暇が登録されている日は出勤を打刻できません"}
+    }
+    if manual {
+      return &punchError{errCodeManualEntry, "勤怠入力で登録済みの日は打刻できません。勤怠入力から修正してください"}
+    }
+
+    // 出勤時刻は最初の出勤のまま、退勤時刻は次の退勤まで未入力にする
+    _, err = tx.Exec(`
+      INSERT INTO TBL_ATTEN (atteid, attedt, attest, atteet, atteeo)
+      VALUES ($1, $2, $3, NULL, 0)
+      ON CONFLICT (atteid, attedt) DO UPDATE
+      SET attest = COALESCE(TBL_ATTEN.attest, $3), atteet = NULL, atteeo = 0
+    `, employeeID, dateStr, now.Format("15:04"))
+    if err != nil {
+      return err
+    }
+    return insertPunchSegment(tx, employeeID, dateStr, segmentKindWork, stamp)
+
+  case punchOut:
+    // 勤務時間は出勤から24時間以内
+    var first string
+    err := tx.QueryRow("SELECT attest FROM TBL_ATTEN WHERE atteid = $1 AND attedt = $2", employeeID, dateStr).Scan(&first)
+    if err != nil {
+      return err
+    }
+    start, err := parseClock(first[:minInt(len(first), 5)])
+    if err != nil {
+      return err
+    }
+    if now.Sub(date.Add(time.Duration(start)*time.Minute)) > 24*time.Hour {
+      return &punchError{errCodeMissingClockOut, "出勤から24時間を超えています。修正申請から退勤時刻を登録してください"}
+    }
+
+    if err := closePunchSegment(tx, employeeID, state.Work, stamp); err != nil {
+      return err
+    }
+    offset := 0
+    if now.Format("2006-01-02") != dateStr {
+      offset = 1
+    }
+    _, err = tx.Exec(`
+      UPDATE TBL_ATTEN
+      SET atteet = $3, atteeo = $4
+      WHERE atteid = $1 AND attedt = $2
+    `, employeeID, dateStr, now.Format("15:04"), offset)
+    return err
+
+  case punchBreakStart:
+    return insertPunchSegment(tx, employeeID, dateStr, segmentKindBreak, stamp)
+
+  case punchBreakEnd:
+    return closePunchSegment(tx, employeeID, state.Break, stamp)
+  }
+  return fmt.Errorf("不明な打刻の種類: %s", kind)
+}
+
+// 打刻で区間を開始する
+func insertPunchSegment(tx *sql.Tx, employeeID int, date string, kind int, stamp string) error {
+  _, err := tx.Exec(`
+    INSERT INTO TBL_KUKAN (kukaid, kukadt, kukasq, kukakb, kukast)
+    SELECT $1, $2, COALESCE(MAX(kukasq), 0) + 1, $3, $4
+    FROM TBL_KUKAN
+    WHERE kukaid = $1 AND kukadt = $2
+  `, employeeID, date, kind, stamp)
+  return err
+}
+
+// 打刻で区間を終了する
+func closePunchSegment(tx *sql.Tx, employeeID int, seg *openSegment, stamp string) error {
+  _, err := tx.Exec(`
+    UPDATE TBL_KUKAN
+    SET kukaet = $4
+    WHERE kukaid = $1 AND kukadt = $2 AND kukasq = $3
+  `, employeeID, seg.Date.Format("2006-01-02"), seg.Seq, stamp)
+  return err
+}
+
+// 打刻（本人のみ、時刻はサーバーの現在時刻）
+func punch(kind string) gin.HandlerFunc {
+  return func(c *gin.Context) {
+    employeeID := currentEmployeeID(c)
+    now := time.Now().Truncate(time.Minute)
+
+    tx, err := db.Begin()
+    if err != nil {
+      log.Printf("トランザクション開始エラー: %v", err)
+      c.JSON(http.StatusInternalServerError, gin.H{"error": "データベーストランザクションの開始に失敗しました"})
+      return
+    }
+    defer tx.Rollback()
+
+    // 同じ社員の打刻が同時に処理されないようにする（二重打刻の防止）
+    if _, err := tx.Exec("SELECT emplid FROM TBL_EMPLO WHERE emplid = $1 FOR UPDATE", employeeID); err != nil {
+      handleDatabaseError(c, err, "打刻に失敗しました")
+      return
+    }
+
+    state, err := loadPunchState(tx, employeeID)
+    if err != nil {
+      handleDatabaseError(c, err, "打刻状態の取得に失敗しました")
+      return
+    }
+
+    respondError := func(err error) {
+      if perr, ok := err.(*punchError); ok {
+        c.JSON(http.StatusConflict, gin.H{"error": perr.message, "code": perr.code})
+        return
+      }
+      handleDatabaseError(c, err, "打刻に失敗しました")
+    }
+
+    date, err := state.target(kind, now)
+    if err != nil {
+      respondError(err)
+      return
+    }
+
+    // 入力期間（締め状態）のチェック
+    if !checkAttendanceWritable(c, employeeID, date.Format("2006-01-02")) {
+      return
+    }
+
+    if err := writePunch(tx, employeeID, kind, date, now, state); err != nil {
+      respondError(err)
+      return
+    }
+    if err := tx.Commit(); err != nil {
+      handleDatabaseError(c, err, "打刻に失敗しました")
+      return
+    }
+
+    log.Printf("打刻: 社員%d %s %s", employeeID, kind, now.Format("2006-01-02 15:04"))
+    c.JSON(http.StatusOK, gin.H{
+      "message": punchMessageMap[kind],
+      "date":    date.Format("2006-01-02"),
+      "time":    now.Format("15:04"),
+    })
+  }
+}
+
+// 勤怠の変更履歴（勤怠入力・休暇登録による手入力の変更）
+type AttendanceChange struct {
+  ID            int         `json:"id"`
+  EmployeeID    int         `json:"employeeId"`
+  Date          string      `json:"date"`
+  Before        *Attendance `json:"before"` // 変更前（登録がなかった場合はnull）
+  After         *Attendance `json:"after"`  // 変更後（削除した場合はnull）
+  ChangedBy     int         `json:"changedBy"`
+  ChangedByName string      `json:"changedByName"`
+  ChangedAt     string      `json:"changedAt"`
+}
+
+// 登録済みの1日分の勤怠・休暇を取得（変更履歴用、労働時間は計算しない）
+// 勤怠・休暇のどちらも登録がない場合はnilを返す
+func fetchStoredAttendance(employeeID int, date string) (*Attendance, error) {
+  att := &Attendance{EmployeeID: employeeID, Date: date}
+  found := false
+
+  var startTime, endTime sql.NullString
+  err := db.QueryRow(`
+    SELECT attest, atteet, atteeo
+    FROM TBL_ATTEN
+    WHERE atteid = $1 AND attedt = $2
+  `, employeeID, date).Scan(&startTime, &endTime, &att.EndDayOffset)
+  if err != nil && err != sql.ErrNoRows {
+    return nil, err
+  }
+  if err == nil {
+    found = true
+    att.StartTime = startTime.String
+    att.EndTime = endTime.String
+
+    target, err := time.ParseInLocation("2006-01-02", date, time.Local)
+    if err != nil {
+      return nil, err
+    }
+    segments, err := fetchAttendanceSegments(employeeID, date, target.AddDate(0, 0, 1).Format("2006-01-02"))
+    if err != nil {
+      return nil, err
+    }
+    if day, ok := segments[date]; ok {
+      att.Segments = day.Segments
+      att.Breaks = day.Breaks
+    }
+  }
+
+  err = db.QueryRow("SELECT leretp, lerekb, lerehr, leresh FROM TBL_LEAVE WHERE lereid = $1 AND leredt = $2", employeeID, date).
+    Scan(&att.LeaveType, &att.LeaveUnit, &att.LeaveHours, &att.PlannedLeave)
+  if err != nil && err != sql.ErrNoRows {
+    return nil, err
+  }
+  if err == nil {
+    found = true
+  }
+
+  if !found {
+    return nil, nil
+  }
+  return att, nil
+}
+
+// 勤怠の変更履歴を記録する（TBL_HENKO）
+func recordAttendanceChange(tx *sql.Tx, employeeID int, date string, before *Attendance, after *Attendance, operatorID int) error {
+  toJSON := func(att *Attendance) (sql.NullString, error) {
+    if att == nil {
+      return sql.NullString{}, nil
+    }
+    b, err := json.Marshal(att)
+    return sql.NullString{String: string(b), Valid: true}, err
+  }
+  beforeJSON, err := toJSON(before)
+  if err != nil {
+    return err
+  }
+  afterJSON, err := toJSON(after)
+  if err != nil {
+    return err
+  }
+
+  _, err = tx.Exec(`
+    INSERT INTO TBL_HENKO (henkem, henkdt, henkbf, henkaf, henkby, henkat)
+    VALUES ($1, $2, $3, $4, $5, NOW())
+  `, employeeID, date, beforeJSON, afterJSON, operatorID)
+  return err
+}
+
+// 勤怠の変更履歴取得（日別）
+func getAttendanceHistory(c *gin.Context) {
+  id, err := strconv.Atoi(c.Param("id"))
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なID"})
+    return
+  }
+
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, id) {
+    return
+  }
+
+  date := c.Param("date")
+  if _, err := time.Parse("2006-01-02", date); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な日付形式", "code": errCodeInvalidDate})
+    return
+  }
+
+  rows, err := db.Query(`
+    SELECT h.henkid, h.henkbf, h.henkaf, h.henkby, COALESCE(e.emplnm, ''), h.henkat
+    FROM TBL_HENKO h
+    LEFT JOIN TBL_EMPLO e ON e.emplid = h.henkby
+    WHERE h.henkem = $1 AND h.henkdt = $2
+    ORDER BY h.henkat, h.henkid
+  `, id, date)
+  if err != nil {
+    handleDatabaseError(c, err, "勤怠の変更履歴の取得に失敗しました")
+    return
+  }
+  defer rows.Close()
+
+  history := []AttendanceChange{}
+  for rows.Next() {
+    change := AttendanceChange{EmployeeID: id, Date: date}
+    var before, after []byte
+    var changedAt time.Time
+    if err := rows.Scan(&change.ID, &before, &after, &change.ChangedBy, &change.ChangedByName, &changedAt); err != nil {
+      handleDatabaseError(c, err, "勤怠の変更履歴の取得に失敗しました")
+      return
+    }
+    if before != nil {
+      if err := json.Unmarshal(before, &change.Before); err != nil {
+        handleDatabaseError(c, err, "勤怠の変更履歴の取得に失敗しました")
+        return
+      }
+    }
+    if after != nil {
+      if err := json.Unmarshal(after, &change.After); err != nil {
+        handleDatabaseError(c, err, "勤怠の変更履歴の取得に失敗しました")
+        return
+      }
+    }
+    change.ChangedAt = changedAt.Format("2006-01-02 15:04:05")
+    history = append(history, change)
+  }
+  if err := rows.Err(); err != nil {
+    handleDatabaseError(c, err, "勤怠の変更履歴の取得に失敗しました")
+    return
+  }
+
+  c.JSON(http.StatusOK, history)
+}
+
+// 勤怠入力の内容をチェックし、不正な場合はエラーを返しfalseを返す
+// 締め状態・時季指定・休暇タイプ・休暇の単位・休暇タイプの上限日数・年次有給休暇の残日数・勤務と休憩の区間（区間を指定した場合は出退勤時刻を区間から設定）・出退勤時刻をチェックする
+func checkAttendanceInput(c *gin.Context, att *Attendance) bool {
+  if !checkAttendanceWritable(c, att.EmployeeID, att.Date) {
+    return false
+  }
+
+  // 時季指定は人事の時季指定登録でのみ設定し、時季指定された日は人事のみ変更できる
+  att.PlannedLeave = false
+  if currentRole(c) != roleHR {
+    var planned bool
+    err := db.QueryRow(`
+      SELECT EXISTS (
+        SELECT 1 FROM TBL_LEAVE
+        WHERE lereid = $1 AND leredt = $2 AND leresh
+      )
+    `, att.EmployeeID, att.Date).Scan(&planned)
+    if err != nil {
+      handleDatabaseError(c, err, "休暇データの取得に失敗しました")
+      return false
+    }
+    if planned {
+      c.JSON(http.StatusForbidden, gin.H{"error": "時季指定された年次有給休暇の日は人事のみ変更できます"})
+      return false
+    }
+  }
+
+  if att.LeaveType > 0 {
+    leaveType := checkLeaveType(c, att.LeaveType)
+    if leaveType == nil {
+      return false
+    }
+    if !checkLeaveUnit(c, att) || !checkLeaveTypeCap(c, att, leaveType) {
+      return false
+    }
+    if leaveType.ConsumesAnnual {
+      return checkAnnualLeaveBalance(c, att.EmployeeID, *att)
+    }
+    return true
+  }
+
+  if err := normalizeAttendanceSegments(att); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    return false
+  }
+  return checkAttendanceTimes(c, att)
+}
+
+// 休暇の単位をチェックし、不正な場合は400を返す（省略時は全日）
+// 時間単位の休暇は1時間以上、1日分の時間数未満とする
+func checkLeaveUnit(c *gin.Context, att *Attendance) bool {
+  if att.LeaveUnit == 0 {
+    att.LeaveUnit = leaveUnitFullDay
+  }
+  if _, ok := leaveUnitNameMap[att.LeaveUnit]; !ok {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な休暇の単位"})
+    return false
+  }
+  if att.LeaveUnit != leaveUnitHourly {
+    att.LeaveHours = 0
+    return true
+  }
+
+  date, err := time.ParseInLocation("2006-01-02", att.Date, time.Local)
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な日付形式", "code": errCodeInvalidDate})
+    return false
+  }
+  history, err := fetchWorkSchedules(att.EmployeeID)
+  if err != nil {
+    handleDatabaseError(c, err, "勤務形態の取得に失敗しました")
+    return false
+  }
+  dayHours := leaveDayHours(workScheduleOn(history, date))
+  if att.LeaveHours < 1 || att.LeaveHours >= dayHours {
+    c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("時間単位の休暇は1〜%d時間で指定してください", dayHours-1)})
+    return false
+  }
+  return true
+}
+
+// 勤怠・休暇を登録し、変更履歴を記録する（勤怠入力と承認された修正申請で共通）
+// 半休・時間単位の休暇は同じ日の勤怠と併せて登録でき、全日の休暇と勤怠は排他的
+// beforeは変更前の勤怠・休暇（fetchStoredAttendance）、operatorIDは変更者
+func saveAttendance(tx *sql.Tx, att *Attendance, before *Attendance, operatorID int) error {
+  if att.LeaveType > 0 && att.LeaveUnit == 0 {
+    att.LeaveUnit = leaveUnitFullDay
+  }
+  after := storedAttendance(att)
+  if att.LeaveType > 0 {
+    // 休暇情報を登録
+    _, err := tx.Exec(`
+      INSERT INTO TBL_LEAVE (lereid, leredt, leretp, lerekb, lerehr, leresh)
+      VALUES ($1, $2, $3, $4, $5, $6)
+      ON CONFLICT (lereid, leredt) DO UPDATE
+      SET leretp = $3, lerekb = $4, lerehr = $5, leresh = $6
+    `, att.EmployeeID, att.Date, att.LeaveType, att.LeaveUnit, att.LeaveHours, att.PlannedLeave)
+    if err != nil {
+      return err
+    }
+
+    if att.LeaveUnit == leaveUnitFullDay {
+      // 関連する勤怠データを削除（全日の休暇と出勤は排他的）
+      _, err = tx.Exec(`
+        DELETE FROM TBL_ATTEN
+        WHERE atteid = $1 AND attedt = $2
+      `, att.EmployeeID, att.Date)
+      if err != nil {
+        return err
+      }
+    } else if before != nil {
+      // 同じ日の勤怠はそのまま残る
+      after.StartTime = before.StartTime
+      after.EndTime = before.EndTime
+      after.EndDayOffset = before.EndDayOffset
+      after.Segments = before.Segments
+      after.Breaks = before.Breaks
+    }
+  } else {
+    // 全日の休暇があれば削除（半休・時間単位の休暇は残す）
+    result, err := tx.Exec(`
+      DELETE FROM TBL_LEAVE
+      WHERE lereid = $1 AND leredt = $2 AND lerekb = $3
+    `, att.EmployeeID, att.Date, leaveUnitFullDay)
+    if err != nil {
+      return err
+    }
+    if count, err := result.RowsAffected(); err != nil {
+      return err
+    } else if count > 0 {
+      if err := cancelApprovedLeaveRequests(tx, att.EmployeeID, att.Date, "勤怠の登録により取消", operatorID); err != nil {
+        return err
+      }
+    }
+    if before != nil && before.LeaveType > 0 && before.LeaveUnit != leaveUnitFullDay {
+      after.LeaveType = before.LeaveType
+      after.LeaveUnit = before.LeaveUnit
+      after.LeaveHours = before.LeaveHours
+      after.PlannedLeave = before.PlannedLeave
+    }
+
+    // 勤怠情報を登録・更新
+    _, err = tx.Exec(`
+      INSERT INTO TBL_ATTEN (atteid, attedt, attest, atteet, atteeo)
+      VALUES ($1, $2, $3, $4, $5)
+      ON CONFLICT (atteid, attedt) DO UPDATE
+      SET attest = $3, atteet = $4, atteeo = $5
+    `, att.EmployeeID, att.Date, sql.NullString{String: att.StartTime, Valid: att.StartTime != ""},
+      sql.NullString{String: att.EndTime, Valid: att.EndTime != ""}, att.EndDayOffset)
+    if err != nil {
+      return err
+    }
+
+    // 勤務・休憩の区間を登録
+    if err := replaceAttendanceSegments(tx, att); err != nil {
+      return err
+    }
+  }
+
+  // 変更履歴を記録（労働時間などの計算項目は含めない）
+  return recordAttendanceChange(tx, att.EmployeeID, att.Date, before, after, operatorID)
+}
+
+// 休暇を削除し、変更履歴を記録する（休暇削除と承認済みの休暇申請の取消で共通）
+// beforeは削除前の勤怠・休暇（fetchStoredAttendance）、承認済みの休暇申請は取消にする
+func removeLeave(tx *sql.Tx, before *Attendance, operatorID int) error {
+  _, err := tx.Exec(`
+    DELETE FROM TBL_LEAVE
+    WHERE lereid = $1 AND leredt = $2
+  `, before.EmployeeID, before.Date)
+  if err != nil {
+    return err
+  }
+  if err := cancelApprovedLeaveRequests(tx, before.EmployeeID, before.Date, "休暇の削除により取消", operatorID); err != nil {
+    return err
+  }
+
+  // 変更履歴を記録（半休・時間単位の休暇の日は同じ日の勤怠が残る）
+  var after *Attendance
+  if before.StartTime != "" || len(before.Segments) > 0 {
+    after = &Attendance{
+      EmployeeID:   before.EmployeeID,
+      Date:         before.Date,
+      StartTime:    before.StartTime,
+      EndTime:      before.EndTime,
+      EndDayOffset: before.EndDayOffset,
+      Segments:     before.Segments,
+      Breaks:       before.Breaks,
+    }
+  }
+  return recordAttendanceChange(tx, before.EmployeeID, before.Date, before, after, operatorID)
+}
+
+// 登録する内容だけの勤怠（変更履歴・修正申請の保存用）
+func storedAttendance(att *Attendance) *Attendance {
+  stored := &Attendance{
+    EmployeeID:   att.EmployeeID,
+    Date:         att.Date,
+    LeaveType:    att.LeaveType,
+    LeaveUnit:    att.LeaveUnit,
+    LeaveHours:   att.LeaveHours,
+    PlannedLeave: att.PlannedLeave,
+  }
+  if att.LeaveType == 0 {
+    stored.StartTime = att.StartTime
+    stored.EndTime = att.EndTime
+    stored.EndDayOffset = att.EndDayOffset
+    stored.Segments = att.Segments
+    stored.Breaks = att.Breaks
+  }
+  return stored
+}
+
+// 勤怠の修正申請の状態（TBL_SHUSE.shusst）
+const (
+  correctionStatusPending   = 1 // 申請中
+  correctionStatusApproved  = 2 // 承認
+  correctionStatusRejected  = 3 // 却下
+  correctionStatusCancelled = 4 // 取下げ
+)
+
+// 修正申請の状態名
+var correctionStatusNameMap = map[int]string{
+  correctionStatusPending:   "申請中",
+  correctionStatusApproved:  "承認",
+  correctionStatusRejected:  "却下",
+  correctionStatusCancelled: "取下げ",
+}
+
+// 修正申請のエラーコード
+const (
+  errCodeCorrectionRequired   = "CORRECTION_REQUIRED"    // 過去の日の勤怠は修正申請が必要
+  errCodeCorrectionPending    = "CORRECTION_PENDING"     // 同じ日の申請が申請中
+  errCodeCorrectionNotPending = "CORRECTION_NOT_PENDING" // 申請中ではない（処理済み）
+)
+
+// 勤怠の修正申請
+type AttendanceCorrection struct {
+  ID           int               `json:"id"`
+  EmployeeID   int               `json:"employeeId"`
+  EmployeeName string            `json:"employeeName"`
+  Date         string            `json:"date"`
+  Before       *Attendance       `json:"before"`    // 申請時点の勤怠（登録がなかった場合はnull）
+  Requested    *Attendance       `json:"requested"` // 修正後の勤怠
+  Reason       string            `json:"reason"`
+  Status       int               `json:"status"`
+  StatusName   string            `json:"statusName"`
+  RequestedAt  string            `json:"requestedAt"`
+  History      []CorrectionEvent `json:"history"` // 申請・承認・却下・取下げの履歴
+}
+
+// 修正申請の履歴（TBL_SHURK）
+type CorrectionEvent struct {
+  Status      int    `json:"status"`
+  StatusName  string `json:"statusName"`
+  Comment     string `json:"comment,omitempty"`
+  ActedBy     int    `json:"actedBy"`
+  ActedByName string `json:"actedByName"`
+  ActedAt     string `json:"actedAt"`
+}
+
+// 修正申請の登録リクエスト
+type CorrectionRequest struct {
+  Attendance Attendance `json:"attendance"` // 修正後の勤怠（休暇は休暇申請から申請する）
+  Reason     string     `json:"reason" binding:"required"`
+}
+
+// 修正申請の承認・却下・取下げリクエスト
+type CorrectionDecision struct {
+  Comment string `json:"comment,omitempty"`
+}
+
+// 修正申請を取得（conditionはTBL_SHUSE sに対する条件、新しい順）
+func queryCorrections(condition string, args ...interface{}) ([]AttendanceCorrection, error) {
+  rows, err := db.Query(`
+    SELECT s.shusid, s.shusem, e.emplnm, TO_CHAR(s.shusdt, 'YYYY-MM-DD'), s.shusbf, s.shusjs,
+      s.shusry, s.shusst, TO_CHAR(s.shusat, 'YYYY-MM-DD HH24:MI:SS')
+    FROM TBL_SHUSE s
+    JOIN TBL_EMPLO e ON e.emplid = s.shusem
+    WHERE `+condition+`
+    ORDER BY s.shusat DESC, s.shusid DESC
+  `, args...)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  corrections := []AttendanceCorrection{}
+  index := map[int]int{}
+  ids := []int64{}
+  for rows.Next() {
+    var corr AttendanceCorrection
+    var before, requested []byte
+    err := rows.Scan(&corr.ID, &corr.EmployeeID, &corr.EmployeeName, &corr.Date, &before, &requested,
+      &corr.Reason, &corr.Status, &corr.RequestedAt)
+    if err != nil {
+      return nil, err
+    }
+    if before != nil {
+      if err := json.Unmarshal(before, &corr.Before); err != nil {
+        return nil, err
+      }
+    }
+    if err := json.Unmarshal(requested, &corr.Requested); err != nil {
+      return nil, err
+    }
+    corr.StatusName = correctionStatusNameMap[corr.Status]
+    corr.History = []CorrectionEvent{}
+    index[corr.ID] = len(corrections)
+    ids = append(ids, int64(corr.ID))
+    corrections = append(corrections, corr)
+  }
+  if err := rows.Err(); err != nil {
+    return nil, err
+  }
+  if len(ids) == 0 {
+    return corrections, nil
+  }
+
+  // 履歴
+  historyRows, err := db.Query(`
+    SELECT h.shurrq, h.shurst, COALESCE(h.shurcm, ''), h.shurby, COALESCE(e.emplnm, ''),
+      TO_CHAR(h.shurat, 'YYYY-MM-DD HH24:MI:SS')
+    FROM TBL_SHURK h
+    LEFT JOIN TBL_EMPLO e ON e.emplid = h.shurby
+    WHERE h.shurrq = ANY($1)
+    ORDER BY h.shurat, h.shurid
+  `, pq.Array(ids))
+  if err != nil {
+    return nil, err
+  }
+  defer historyRows.Close()
+
+  for historyRows.Next() {
+    var requestID int
+    var event CorrectionEvent
+    err := historyRows.Scan(&requestID, &event.Status, &event.Comment, &event.ActedBy, &event.ActedByName, &event.ActedAt)
+    if err != nil {
+      return nil, err
+    }
+    event.StatusName = correctionStatusNameMap[event.Status]
+    i := index[requestID]
+    corrections[i].History = append(corrections[i].History, event)
+  }
+  return corrections, historyRows.Err()
+}
+
+// 修正申請を1件取得（存在しない場合はsql.ErrNoRows）
+func fetchCorrection(requestID int) (*AttendanceCorrection, error) {
+  corrections, err := queryCorrections("s.shusid = $1", requestID)
+  if err != nil {
+    return nil, err
+  }
+  if len(corrections) == 0 {
+    return nil, sql.ErrNoRows
+  }
+  return &corrections[0], nil
+}
+
+// 修正申請が申請中でなくなっていた（同時に処理された）
+var errCorrectionNotPending = &conflictError{errCodeCorrectionNotPending, "この申請は他の操作で既に処理されています"}
+
+// 修正申請の状態を更新し、履歴を記録する
+// 申請中でなくなっていた場合（同時に処理された場合）はerrCorrectionNotPendingを返す
+func updateCorrectionStatus(tx *sql.Tx, requestID int, status int, comment string, operatorID int) error {
+  result, err := tx.Exec(`
+    UPDATE TBL_SHUSE
+    SET shusst = $2
+    WHERE shusid = $1 AND shusst = $3
+  `, requestID, status, correctionStatusPending)
+  if err != nil {
+    return err
+  }
+  count, err := result.RowsAffected()
+  if err != nil {
+    return err
+  }
+  if count == 0 {
+    return errCorrectionNotPending
+  }
+  return insertCorrectionEvent(tx, requestID, status, comment, operatorID)
+}
+
+// 修正申請の履歴を記録する（TBL_SHURK）
+func insertCorrectionEvent(tx *sql.Tx, requestID int, status int, comment string, operatorID int) error {
+  _, err := tx.Exec(`
+    INSERT INTO TBL_SHURK (shurrq, shurst, shurcm, shurby, shurat)
+    VALUES ($1, $2, $3, $4, NOW())
+  `, requestID, status, sql.NullString{String: comment, Valid: comment != ""}, operatorID)
+  return err
+}
+
+// 修正申請の状態を取得し、申請中でない場合は409を返す
+// 呼び出し元はnilが返った場合にそのままreturnすること
+func loadPendingCorrection(c *gin.Context) *AttendanceCorrection {
+  requestID, err := strconv.Atoi(c.Param("requestId"))
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な申請ID"})
+    return nil
+  }
+
+  corr, err := fetchCorrection(requestID)
+  if err != nil {
+    handleDatabaseError(c, err, "修正申請の取得に失敗しました")
+    return nil
+  }
+  if corr.Status != correctionStatusPending {
+    c.JSON(http.StatusConflict, gin.H{
+      "error": fmt.Sprintf("この申請は%sのため処理できません", corr.StatusName),
+      "code":  errCodeCorrectionNotPending,
+    })
+    return nil
+  }
+  return corr
+}
+
+// 承認・却下・取下げのコメントを読み込む（ボディは省略可）
+func bindCorrectionDecision(c *gin.Context) (CorrectionDecision, bool) {
+  var req CorrectionDecision
+  if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト"})
+    return req, false
+  }
+  return req, true
+}
+
+// 勤怠の修正申請（本人のみ、過去の日が対象）
+func submitCorrection(c *gin.Context) {
+  var req CorrectionRequest
+  if err := c.ShouldBindJSON(&req); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト（修正理由は必須です）"})
+    return
+  }
+
+  att := req.Attendance
+  if att.EmployeeID <= 0 || att.Date == "" {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "従業員IDと日付は必須です"})
+    return
+  }
+  if strings.TrimSpace(req.Reason) == "" {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "修正理由を入力してください"})
+    return
+  }
+  if att.EmployeeID != currentEmployeeID(c) {
+    c.JSON(http.StatusForbidden, gin.H{"error": "修正申請は本人のみ行えます"})
+    return
+  }
+  if att.LeaveType > 0 {
+    c.JSON(http.StatusConflict, gin.H{"error": "休暇は休暇申請から申請してください", "code": errCodeLeaveRequestRequired})
+    return
+  }
+
+  // 入力内容のチェック（承認時にも改めてチェックする）
+  if !checkAttendanceInput(c, &att) {
+    return
+  }
+  if att.Date >= time.Now().Format("2006-01-02") {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "当日以降の勤怠は勤怠入力から登録してください"})
+    return
+  }
+
+  // 同じ日の申請中の修正申請は1件まで
+  var pending bool
+  err := db.QueryRow(`
+    SELECT EXISTS (
+      SELECT 1 FROM TBL_SHUSE
+      WHERE shusem = $1 AND shusdt = $2 AND shusst = $3
+    )
+  `, att.EmployeeID, att.Date, correctionStatusPending).Scan(&pending)
+  if err != nil {
+    handleDatabaseError(c, err, "修正申請の確認に失敗しました")
+    return
+  }
+  if pending {
+    c.JSON(http.StatusConflict, gin.H{"error": "この日の修正申請は既に申請中です", "code": errCodeCorrectionPending})
+    return
+  }
+
+  before, err := fetchStoredAttendance(att.EmployeeID, att.Date)
+  if err != nil {
+    handleDatabaseError(c, err, "勤怠データの取得に失敗しました")
+    return
+  }
+  beforeJSON, err := json.Marshal(before)
+  if err != nil {
+    handleDatabaseError(c, err, "修正申請の登録に失敗しました")
+    return
+  }
+  requestedJSON, err := json.Marshal(storedAttendance(&att))
+  if err != nil {
+    handleDatabaseError(c, err, "修正申請の登録に失敗しました")
+    return
+  }
+
+  var requestID int
+  executeWithTransaction(c, func(tx *sql.Tx) error {
+    err := tx.QueryRow(`
+      INSERT INTO TBL_SHUSE (shusem, shusdt, shusbf, shusjs, shusry, shusst, shusat)
+      VALUES ($1, $2, $3, $4, $5, $6, NOW())
+      RETURNING shusid
+    `, att.EmployeeID, att.Date, sql.NullString{String: string(beforeJSON), Valid: before != nil},
+      string(requestedJSON), req.Reason, correctionStatusPending).Scan(&requestID)
+    if err != nil {
+      return err
+    }
+    if err := insertCorrectionEvent(tx, requestID, correctionStatusPending, req.Reason, att.EmployeeID); err != nil {
+      return err
+    }
+
+    log.Printf("勤怠修正申請: 社員%d %s (申請ID %d)", att.EmployeeID, att.Date, requestID)
+    return nil
+  }, "修正申請を登録しました")
+}
+
+// 社員の修正申請一覧取得（statusで絞り込み可）
+func getCorrections(c *gin.Context) {
+  id, err := strconv.Atoi(c.Param("id"))
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なID"})
+    return
+  }
+
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, id) {
+    return
+  }
+
+  condition := "s.shusem = $1"
+  args := []interface{}{id}
+  if status := c.Query("status"); status != "" {
+    st, err := strconv.Atoi(status)
+    if err != nil || correctionStatusNameMap[st] == "" {
+      c.JSON(http.StatusBadRequest, gin.H{"error": "無効な状態"})
+      return
+    }
+    condition += " AND s.shusst = $2"
+    args = append(args, st)
+  }
+
+  corrections, err := queryCorrections(condition, args...)
+  if err != nil {
+    handleDatabaseError(c, err, "修正申請の取得に失敗しました")
+    return
+  }
+  c.JSON(http.StatusOK, corrections)
+}
+
+// 承認待ちの修正申請一覧取得
+// 上司は直属の部下の申請、人事は全社員の申請
+func getTeamCorrections(c *gin.Context) {
+  status := correctionStatusPending
+  if s := c.Query("status"); s != "" {
+    st, err := strconv.Atoi(s)
+    if err != nil || correctionStatusNameMap[st] == "" {
+      c.JSON(http.StatusBadRequest, gin.H{"error": "無効な状態"})
+      return
+    }
+    status = st
+  }
+
+  var corrections []AttendanceCorrection
+  var err error
+  if currentRole(c) == roleHR {
+    corrections, err = queryCorrections("s.shusst = $1", status)
+  } else {
+    corrections, err = queryCorrections(`s.shusst = $1 AND s.shusem IN (
+      SELECT joshid FROM TBL_JOSHI WHERE joshji = $2 AND joshsd <= CURRENT_DATE AND (joshed IS NULL OR joshed > CURRENT_DATE)
+    )`, status, currentEmployeeID(c))
+  }
+  if err != nil {
+    handleDatabaseError(c, err, "修正申請の取得に失敗しました")
+    return
+  }
+  c.JSON(http.StatusOK, corrections)
+}
+
+// 修正申請の承認・却下（直属の上司または人事、本人は不可）
+// 承認した場合のみ勤怠に反映し、反映時にも締め状態・出退勤時刻をチェックする
+func decideCorrection(approve bool) gin.HandlerFunc {
+  return func(c *gin.Context) {
+    req, ok := bindCorrectionDecision(c)
+    if !ok {
+      return
+    }
+    corr := loadPendingCorrection(c)
+    if corr == nil {
+      return
+    }
+
+    approverID := currentEmployeeID(c)
+    if corr.EmployeeID == approverID {
+      c.JSON(http.StatusForbidden, gin.H{"error": "自分の修正申請は承認・却下できません"})
+      return
+    }
+    if currentRole(c) != roleHR {
+      isReport, err := isDirectReport(approverID, corr.EmployeeID)
+      if err != nil {
+        handleDatabaseError(c, err, "上司・部下関係の確認に失敗しました")
+        return
+      }
+      if !isReport {
+        c.JSON(http.StatusForbidden, gin.H{"error": "直属の部下の修正申請のみ承認・却下できます"})
+        return
+      }
+    }
+
+    if !approve {
+      executeWithTransaction(c, func(tx *sql.Tx) error {
+        return updateCorrectionStatus(tx, corr.ID, correctionStatusRejected, req.Comment, approverID)
+      }, "修正申請を却下しました")
+      return
+    }
+
+    att := *corr.Requested
+    if !checkAttendanceInput(c, &att) {
+      return
+    }
+    before, err := fetchStoredAttendance(att.EmployeeID, att.Date)
+    if err != nil {
+      handleDatabaseError(c, err, "勤怠データの取得に失敗しました")
+      return
+    }
+
+    executeWithTransaction(c, func(tx *sql.Tx) error {
+      if err := updateCorrectionStatus(tx, corr.ID, correctionStatusApproved, req.Comment, approverID); err != nil {
+        return err
+      }
+      return saveAttendance(tx, &att, before, approverID)
+    }, "修正申請を承認し、勤怠に反映しました")
+  }
+}
+
+// 修正申請の取下げ（申請者本人のみ）
+func cancelCorrection(c *gin.Context) {
+  req, ok := bindCorrectionDecision(c)
+  if !ok {
+    return
+  }
+  corr := loadPendingCorrection(c)
+  if corr == nil {
+    return
+  }
+  if corr.EmployeeID != currentEmployeeID(c) {
+    c.JSON(http.StatusForbidden, gin.H{"error": "修正申請の取下げは本人のみ行えます"})
+    return
+  }
+
+  executeWithTransaction(c, func(tx *sql.Tx) error {
+    return updateCorrectionStatus(tx, corr.ID, correctionStatusCancelled, req.Comment, corr.EmployeeID)
+  }, "修正申請を取り下げました")
+}
+
+// 年次有給休暇の休暇タイプ（TBL_KYUKAの初期データ、時季指定で登録する）
+const leaveTypeAnnual = 1
+
+// 休暇タイプのエラーコード
+const errCodeLeaveTypeCapExceeded = "LEAVE_TYPE_CAP_EXCEEDED" // 年度の上限日数を超える
+
+// 休暇タイプを取得（conditionはTBL_KYUKAに対する条件、ID順）
+func queryLeaveTypes(condition string, args ...interface{}) ([]LeaveType, error) {
+  rows, err := db.Query(`
+    SELECT kyukid, kyuknm, kyukyk, kyukht, kyuksh, kyuksm, kyukjg, kyukhs
+    FROM TBL_KYUKA
+    WHERE `+condition+`
+    ORDER BY kyukid
+  `, args...)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  types := []LeaveType{}
+  for rows.Next() {
+    var t LeaveType
+    var capDays sql.NullFloat64
+    err := rows.Scan(&t.ID, &t.Name, &t.Paid, &t.Statutory, &t.ConsumesAnnual, &t.NeedsCertificate, &capDays, &t.Retired)
+    if err != nil {
+      return nil, err
+    }
+    if capDays.Valid {
+      t.YearlyCapDays = &capDays.Float64
+    }
+    types = append(types, t)
+  }
+  return types, rows.Err()
+}
+
+// 休暇タイプを1件取得（存在しない場合はsql.ErrNoRows）
+func fetchLeaveType(id int) (*LeaveType, error) {
+  types, err := queryLeaveTypes("kyukid = $1", id)
+  if err != nil {
+    return nil, err
+  }
+  if len(types) == 0 {
+    return nil, sql.ErrNoRows
+  }
+  return &types[0], nil
+}
+
+// 休暇タイプがマスタに登録されていて廃止されていないかをチェックし、不正な場合は400を返す
+// 呼び出し元はnilが返った場合にそのままreturnすること
+func checkLeaveType(c *gin.Context, id int) *LeaveType {
+  leaveType, err := fetchLeaveType(id)
+  if err == sql.ErrNoRows {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な休暇タイプ"})
+    return nil
+  }
+  if err != nil {
+    handleDatabaseError(c, err, "休暇タイプの取得に失敗しました")
+    return nil
+  }
+  if leaveType.Retired {
+    c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%sは廃止された休暇タイプです", leaveType.Name)})
+    return nil
+  }
+  return leaveType
+}
+
+// 休暇タイプの年度（4月始まり）の初日
+func leaveYearStart(date time.Time) time.Time {
+  year := date.Year()
+  if date.Month() < time.April {
+    year--
+  }
+  return time.Date(year, time.April, 1, 0, 0, 0, 0, time.Local)
+}
+
+// 休暇タイプの年度の上限日数をチェックし、超える場合は409を返す（同じ日に登録済みの休暇は置き換える）
+// 呼び出し元はfalseが返った場合にそのままreturnすること
+func checkLeaveTypeCap(c *gin.Context, att *Attendance, leaveType *LeaveType) bool {
+  if leaveType.YearlyCapDays == nil {
+    return true
+  }
+
+  date, err := time.ParseInLocation("2006-01-02", att.Date, time.Local)
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な日付形式", "code": errCodeInvalidDate})
+    return false
+  }
+  history, err := fetchWorkSchedules(att.EmployeeID)
+  if err != nil {
+    handleDatabaseError(c, err, "勤務形態の取得に失敗しました")
+    return false
+  }
+  yearStart := leaveYearStart(date)
+  usage, err := fetchLeaveUsage(att.EmployeeID, "l.leretp = $2 AND l.leredt >= $3 AND l.leredt < $4 AND l.leredt <> $5",
+    leaveType.ID, yearStart.Format("2006-01-02"), yearStart.AddDate(1, 0, 0).Format("2006-01-02"), att.Date)
+  if err != nil {
+    handleDatabaseError(c, err, "休暇データの取得に失敗しました")
+    return false
+  }
+
+  days := newLeaveUsage(date, att.LeaveUnit, att.LeaveHours, workScheduleOn(history, date)).days
+  for _, u := range usage {
+    days += u.days
+  }
+  if days > *leaveType.YearlyCapDays+1e-9 {
+    c.JSON(http.StatusConflict, gin.H{
+      "error": fmt.Sprintf("%sは%d年度に%g日までです", leaveType.Name, yearStart.Year(), *leaveType.YearlyCapDays),
+      "code":  errCodeLeaveTypeCapExceeded,
+    })
+    return false
+  }
+  return true
+}
+
+// 休暇タイプ一覧取得（廃止した休暇タイプを含む）
+func getLeaveTypes(c *gin.Context) {
+  types, err := queryLeaveTypes("TRUE")
+  if err != nil {
+    handleDatabaseError(c, err, "休暇タイプの取得に失敗しました")
+    return
+  }
+  c.JSON(http.StatusOK, types)
+}
+
+// 休暇タイプの登録・更新（人事のみ）
+// idを省略した場合は新しい休暇タイプとして登録する。登録済みの休暇があるため削除はせず廃止（retired）にする
+func saveLeaveType(c *gin.Context) {
+  var req LeaveType
+  if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト（名称は必須です）"})
+    return
+  }
+  if req.ConsumesAnnual && !req.Paid {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "年次有給休暇の残日数から消化する休暇は有給にしてください"})
+    return
+  }
+  if req.YearlyCapDays != nil && *req.YearlyCapDays <= 0 {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "上限日数は0より大きい日数を指定してください"})
+    return
+  }
+  capDays := sql.NullFloat64{}
+  if req.YearlyCapDays != nil {
+    capDays = sql.NullFloat64{Float64: *req.YearlyCapDays, Valid: true}
+  }
+
+  executeWithTransaction(c, func(tx *sql.Tx) error {
+    if req.ID == 0 {
+      return tx.QueryRow(`
+        INSERT INTO TBL_KYUKA (kyukid, kyuknm, kyukyk, kyukht, kyuksh, kyuksm, kyukjg, kyukhs)
+        SELECT COALESCE(MAX(kyukid), 0) + 1, $1, $2, $3, $4, $5, $6, $7 FROM TBL_KYUKA
+        RETURNING kyukid
+      `, req.Name, req.Paid, req.Statutory, req.ConsumesAnnual, req.NeedsCertificate, capDays, req.Retired).Scan(&req.ID)
+    }
+
+    result, err := tx.Exec(`
+      UPDATE TBL_KYUKA
+      SET kyuknm = $2, kyukyk = $3, kyukht = $4, kyuksh = $5, kyuksm = $6, kyukjg = $7, kyukhs = $8
+      WHERE kyukid = $1
+    `, req.ID, req.Name, req.Paid, req.Statutory, req.ConsumesAnnual, req.NeedsCertificate, capDays, req.Retired)
+    if err != nil {
+      return err
+    }
+    count, err := result.RowsAffected()
+    if err != nil {
+      return err
+    }
+    if count == 0 {
+      return sql.ErrNoRows
+    }
+    return nil
+  }, "休暇タイプを登録しました")
+}
+
+// 休暇の単位（TBL_LEAVE.lerekb）
+const (
+  leaveUnitFullDay   = 1 // 全日
+  leaveUnitMorning   = 2 // 午前半休
+  leaveUnitAfternoon = 3 // 午後半休
+  leaveUnitHourly    = 4 // 時間単位
+)
+
+// 休暇の単位名
+var leaveUnitNameMap = map[int]string{
+  leaveUnitFullDay:   "全日",
+  leaveUnitMorning:   "午前半休",
+  leaveUnitAfternoon: "午後半休",
+  leaveUnitHourly:    "時間単位",
+}
+
+// 時間単位の年次有給休暇の上限（1年の付与期間あたり5日分）
+const hourlyLeaveDaysPerYear = 5
+
+// 勤続年数に応じた年次有給休暇の付与日数（6か月、1年6か月、2年6か月、…、6年6か月以上）
+var annualLeaveGrantDays = []float64{10, 11, 12, 14, 16, 18, 20}
+
+// 年次有給休暇の有効期間（付与日から2年、翌年度への繰越は1回まで）
+const annualLeaveValidYears = 2
+
+// 年次有給休暇のエラーコード
+const (
+  errCodeHireDateNotSet      = "HIRE_DATE_NOT_SET"          // 入社日が未登録
+  errCodeInsufficientBalance = "INSUFFICIENT_LEAVE_BALANCE" // 残日数が不足
+  errCodeHourlyLeaveLimit    = "HOURLY_LEAVE_LIMIT"         // 時間単位の休暇が年5日分を超える
+)
+
+// 入社日が登録されていない
+var errHireDateNotSet = errors.New("入社日が登録されていません")
+
+// 年次有給休暇の付与（TBL_FUYOK）
+type LeaveGrant struct {
+  GrantDate  string  `json:"grantDate"`           // 付与日
+  ExpiryDate string  `json:"expiryDate"`          // 失効日（この日から使用できない）
+  Days       float64 `json:"days"`                // 付与日数
+  Used       float64 `json:"used"`                // 使用日数（登録済みの休暇から古い付与の順に充当）
+  Remaining  float64 `json:"remaining"`           // 残日数
+  Expired    bool    `json:"expired"`             // 基準日に失効済み
+  Projected  bool    `json:"projected,omitempty"` // 基準日以降の付与予定（未登録）
+
+  grantDate  time.Time
+  expiryDate time.Time
+}
+
+// 年次有給休暇の残日数
+type LeaveBalance struct {
+  EmployeeID    int          `json:"employeeId"`
+  HireDate      string       `json:"hireDate"`
+  AsOf          string       `json:"asOf"`                  // 基準日
+  Granted       float64      `json:"granted"`               // 基準日に有効な付与日数の合計
+  Used          float64      `json:"used"`                  // 有効な付与から使用した日数
+  Remaining     float64      `json:"remaining"`             // 残日数
+  Unallocated   float64      `json:"unallocated,omitempty"` // 付与を超えて登録されている日数
+  NextGrantDate string       `json:"nextGrantDate"`
+  NextGrantDays float64      `json:"nextGrantDays"`
+  Grants        []LeaveGrant `json:"grants"`
+}
+
+// 入社日を取得（未登録の場合はerrHireDateNotSet）
+func fetchHireDate(employeeID int) (time.Time, error) {
+  var hireDate sql.NullTime
+  err := db.QueryRow("SELECT emplhd FROM TBL_EMPLO WHERE emplid = $1", employeeID).Scan(&hireDate)
+  if err != nil {
+    return time.Time{}, err
+  }
+  if !hireDate.Valid {
+    return time.Time{}, errHireDateNotSet
+  }
+  d := hireDate.Time
+  return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Local), nil
+}
+
+// 月数を加算する（加算先の月に同じ日がない場合は月末にする）
+func addMonthsClamped(date time.Time, months int) time.Time {
+  first := time.Date(date.Year(), date.Month()+time.Month(months), 1, 0, 0, 0, 0, date.Location())
+  lastDay := first.AddDate(0, 1, -1).Day()
+  return first.AddDate(0, 0, minInt(date.Day(), lastDay)-1)
+}
+
+// 入社日から年次有給休暇の付与日と付与日数を計算する（until以前の付与）
+// 付与日・失効日は入社日と同じ日で、その月にない日（8月31日入社の2月など）は月末にする
+func annualLeaveSchedule(hireDate time.Time, until time.Time) []LeaveGrant {
+  var grants []LeaveGrant
+  for i := 0; ; i++ {
+    date := addMonthsClamped(hireDate, 6+12*i)
+    if date.After(until) {
+      return grants
+    }
+    expiry := addMonthsClamped(hireDate, 6+12*(i+annualLeaveValidYears))
+    grants = append(grants, LeaveGrant{
+      GrantDate:  date.Format("2006-01-02"),
+      ExpiryDate: expiry.Format("2006-01-02"),
+      Days:       annualLeaveGrantDays[minInt(i, len(annualLeaveGrantDays)-1)],
+      grantDate:  date,
+      expiryDate: expiry,
+    })
+  }
+}
+
+// 付与日が来た年次有給休暇の付与をTBL_FUYOKに登録する（登録済みの付与は変更しない）
+func registerAnnualLeaveGrants(employeeID int, hireDate time.Time, today time.Time) error {
+  for _, g := range annualLeaveSchedule(hireDate, today) {
+    _, err := db.Exec(`
+      INSERT INTO TBL_FUYOK (fuyoid, fuyodt, fuyody, fuyoex, fuyoat)
+      VALUES ($1, $2, $3, $4, NOW())
+      ON CONFLICT (fuyoid, fuyodt) DO NOTHING
+    `, employeeID, g.GrantDate, g.Days, g.ExpiryDate)
+    if err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// 入社日が登録されている全社員について、付与日が来た年次有給休暇の付与を登録する（スケジューラから実行）
+func runDueAnnualLeaveGrants() {
+  now := time.Now()
+  today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+  type employeeHireDate struct {
+    id       int
+    hireDate time.Time
+  }
+  rows, err := db.Query("SELECT emplid, emplhd FROM TBL_EMPLO WHERE emplhd IS NOT NULL ORDER BY emplid")
+  if err != nil {
+    log.Printf("年次有給休暇の付与対象の取得エラー: %v", err)
+    return
+  }
+  var employees []employeeHireDate
+  for rows.Next() {
+    var e employeeHireDate
+    if err := rows.Scan(&e.id, &e.hireDate); err != nil {
+      rows.Close()
+      log.Printf("年次有給休暇の付与対象の取得エラー: %v", err)
+      return
+    }
+    d := e.hireDate
+    e.hireDate = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Local)
+    employees = append(employees, e)
+  }
+  rows.Close()
+  if err := rows.Err(); err != nil {
+    log.Printf("年次有給休暇の付与対象の取得エラー: %v", err)
+    return
+  }
+
+  for _, e := range employees {
+    if err := registerAnnualLeaveGrants(e.id, e.hireDate, today); err != nil {
+      log.Printf("年次有給休暇の付与の登録エラー [%d]: %v", e.id, err)
+    }
+  }
+}
+
+// 年次有給休暇の付与を取得する（データベースへの書き込みは行わない）
+// TBL_FUYOKに登録済みの付与に、付与日が来ていてまだ登録されていない付与（スケジューラの実行前）と
+// untilまでの当日より後の付与予定を加える
+func annualLeaveGrants(employeeID int, hireDate time.Time, until time.Time) ([]LeaveGrant, error) {
+  now := time.Now()
+  today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+  rows, err := db.Query(`
+    SELECT fuyodt, fuyody, fuyoex
+    FROM TBL_FUYOK
+    WHERE fuyoid = $1
+    ORDER BY fuyodt
+  `, employeeID)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var grants []LeaveGrant
+  registered := map[string]bool{}
+  for rows.Next() {
+    var g LeaveGrant
+    if err := rows.Scan(&g.grantDate, &g.Days, &g.expiryDate); err != nil {
+      return nil, err
+    }
+    g.grantDate = time.Date(g.grantDate.Year(), g.grantDate.Month(), g.grantDate.Day(), 0, 0, 0, 0, time.Local)
+    g.expiryDate = time.Date(g.expiryDate.Year(), g.expiryDate.Month(), g.expiryDate.Day(), 0, 0, 0, 0, time.Local)
+    g.GrantDate = g.grantDate.Format("2006-01-02")
+    g.ExpiryDate = g.expiryDate.Format("2006-01-02")
+    registered[g.GrantDate] = true
+    grants = append(grants, g)
+  }
+  if err := rows.Err(); err != nil {
+    return nil, err
+  }
+
+  // 未登録の付与と付与予定
+  for _, g := range annualLeaveSchedule(hireDate, until) {
+    if registered[g.GrantDate] {
+      continue
+    }
+    g.Projected = g.grantDate.After(today)
+    grants = append(grants, g)
+  }
+  sort.SliceStable(grants, func(i, j int) bool { return grants[i].grantDate.Before(grants[j].grantDate) })
+  return grants, nil
+}
+
+// 1日分の休暇の時間数（所定労働時間の1時間未満を切り上げ、時間単位の休暇の換算に使用）
+func leaveDayHours(schedule *WorkSchedule) int {
+  return maxInt(1, (schedule.dailyMinutes()+59)/60)
+}
+
+// 年次有給休暇の使用（1日分）
+type leaveUsage struct {
+  date     time.Time
+  days     float64 // 使用日数（半休は0.5日、時間単位は1日の時間数で換算）
+  hours    int     // 時間単位の休暇の時間数
+  dayHours int     // その日の1日分の時間数
+}
+
+// 休暇の単位から使用日数を計算する
+func newLeaveUsage(date time.Time, unit int, hours int, schedule *WorkSchedule) leaveUsage {
+  usage := leaveUsage{date: date, days: 1, dayHours: leaveDayHours(schedule)}
+  switch unit {
+  case leaveUnitMorning, leaveUnitAfternoon:
+    usage.days = 0.5
+  case leaveUnitHourly:
+    usage.hours = hours
+    usage.days = float64(hours) / float64(usage.dayHours)
+  }
+  return usage
+}
+
+// 登録されている休暇（conditionはTBL_LEAVE lに対する条件、$2以降をargsで指定、日付の昇順）
+func fetchLeaveUsage(employeeID int, condition string, args ...interface{}) ([]leaveUsage, error) {
+  history, err := fetchWorkSchedules(employeeID)
+  if err != nil {
+    return nil, err
+  }
+
+  rows, err := db.Query(`
+    SELECT l.leredt, l.lerekb, l.lerehr
+    FROM TBL_LEAVE l
+    WHERE l.lereid = $1 AND `+condition+`
+    ORDER BY l.leredt
+  `, append([]interface{}{employeeID}, args...)...)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var usage []leaveUsage
+  for rows.Next() {
+    var d time.Time
+    var unit, hours int
+    if err := rows.Scan(&d, &unit, &hours); err != nil {
+      return nil, err
+    }
+    date := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Local)
+    usage = append(usage, newLeaveUsage(date, unit, hours, workScheduleOn(history, date)))
+  }
+  return usage, rows.Err()
+}
+
+// 年次有給休暇の残日数から消化する休暇（日付の昇順）
+func fetchAnnualLeaveUsage(employeeID int) ([]leaveUsage, error) {
+  return fetchLeaveUsage(employeeID, "l.leretp IN (SELECT kyukid FROM TBL_KYUKA WHERE kyuksh)")
+}
+
+// 休暇を付与に充当する（その日に有効な付与のうち、付与日の古い順）
+// 充当できなかった日数を返す
+func allocateAnnualLeave(grants []LeaveGrant, usage []leaveUsage) float64 {
+  for i := range grants {
+    grants[i].Used = 0
+  }
+
+  unallocated := 0.0
+  for _, u := range usage {
+    d := u.date
+    need := u.days
+    for i := range grants {
+      g := &grants[i]
+      if d.Before(g.grantDate) || !d.Before(g.expiryDate) || g.Days <= g.Used {
+        continue
+      }
+      use := math.Min(need, g.Days-g.Used)
+      g.Used += use
+      need -= use
+      if need <= 0 {
+        break
+      }
+    }
+    unallocated += need
+  }
+
+  for i := range grants {
+    grants[i].Remaining = grants[i].Days - grants[i].Used
+  }
+  return unallocated
+}
+
+// 入社日・年次有給休暇の付与・登録済みの休暇を取得する
+// 付与はuntilまたは最後の休暇の日のうち遅い方までを対象にする
+func loadAnnualLeave(employeeID int, until time.Time) (time.Time, []LeaveGrant, []leaveUsage, error) {
+  hireDate, err := fetchHireDate(employeeID)
+  if err != nil {
+    return time.Time{}, nil, nil, err
+  }
+  usage, err := fetchAnnualLeaveUsage(employeeID)
+  if err != nil {
+    return time.Time{}, nil, nil, err
+  }
+  if len(usage) > 0 && usage[len(usage)-1].date.After(until) {
+    until = usage[len(usage)-1].date
+  }
+  grants, err := annualLeaveGrants(employeeID, hireDate, until)
+  if err != nil {
+    return time.Time{}, nil, nil, err
+  }
+  return hireDate, grants, usage, nil
+}
+
+// 基準日の年次有給休暇の残日数を集計する（基準日より後に登録済みの休暇も充当済みとして数える）
+func summarizeAnnualLeave(employeeID int, hireDate time.Time, grants []LeaveGrant, usage []leaveUsage, asOf time.Time) *LeaveBalance {
+  balance := &LeaveBalance{
+    EmployeeID:  employeeID,
+    HireDate:    hireDate.Format("2006-01-02"),
+    AsOf:        asOf.Format("2006-01-02"),
+    Unallocated: allocateAnnualLeave(grants, usage),
+    Grants:      []LeaveGrant{},
+  }
+  for _, g := range grants {
+    if g.grantDate.After(asOf) {
+      continue
+    }
+    g.Expired = !asOf.Before(g.expiryDate)
+    if !g.Expired {
+      balance.Granted += g.Days
+      balance.Used += g.Used
+      balance.Remaining += g.Remaining
+    }
+    balance.Grants = append(balance.Grants, g)
+  }
+
+  // 次回の付与
+  for _, g := range annualLeaveSchedule(hireDate, asOf.AddDate(1, 0, 0)) {
+    if g.grantDate.After(asOf) {
+      balance.NextGrantDate = g.GrantDate
+      balance.NextGrantDays = g.Days
+      break
+    }
+  }
+  return balance
+}
+
+// 年次有給休暇を登録できるか（残日数・時間単位の上限）をチェックし、不足する場合は409を返す
+// 複数の休暇を指定した場合はすべて登録した場合の残日数をチェックする（同じ日に登録済みの休暇は置き換える）
+// 呼び出し元はfalseが返った場合にそのままreturnすること
+func checkAnnualLeaveBalance(c *gin.Context, employeeID int, leaves ...Attendance) bool {
+  if len(leaves) == 0 {
+    return true
+  }
+  history, err := fetchWorkSchedules(employeeID)
+  if err != nil {
+    handleDatabaseError(c, err, "勤務形態の取得に失敗しました")
+    return false
+  }
+
+  targets := make([]leaveUsage, 0, len(leaves))
+  for _, leave := range leaves {
+    date, err := time.ParseInLocation("2006-01-02", leave.Date, time.Local)
+    if err != nil {
+      c.JSON(http.StatusBadRequest, gin.H{"error": "無効な日付形式", "code": errCodeInvalidDate})
+      return false
+    }
+    targets = append(targets, newLeaveUsage(date, leave.LeaveUnit, leave.LeaveHours, workScheduleOn(history, date)))
+  }
+  sort.Slice(targets, func(i, j int) bool { return targets[i].date.Before(targets[j].date) })
+
+  hireDate, grants, registered, err := loadAnnualLeave(employeeID, targets[len(targets)-1].date)
+  if err == errHireDateNotSet {
+    c.JSON(http.StatusConflict, gin.H{"error": "入社日が登録されていないため年次有給休暇を登録できません", "code": errCodeHireDateNotSet})
+    return false
+  }
+  if err != nil {
+    handleDatabaseError(c, err, "年次有給休暇の残日数の取得に失敗しました")
+    return false
+  }
+
+  // 登録後の休暇（同じ日に登録済みの休暇は置き換える）
+  withTargets := append([]leaveUsage{}, targets...)
+  for _, u := range registered {
+    replaced := false
+    for _, target := range targets {
+      if u.date.Equal(target.date) {
+        replaced = true
+        break
+      }
+    }
+    if !replaced {
+      withTargets = append(withTargets, u)
+    }
+  }
+  sort.SliceStable(withTargets, func(i, j int) bool { return withTargets[i].date.Before(withTargets[j].date) })
+
+  // 時間単位の休暇は付与日から1年間で5日分まで
+  for _, target := range targets {
+    if target.hours == 0 {
+      continue
+    }
+    var grant *LeaveGrant
+    for i := len(grants) - 1; i >= 0; i-- {
+      if !grants[i].grantDate.After(target.date) {
+        grant = &grants[i]
+        break
+      }
+    }
+    if grant == nil {
+      continue // 付与前の日は残日数のチェックで不足になる
+    }
+    periodEnd := grant.grantDate.AddDate(1, 0, 0)
+    hours := 0
+    for _, u := range withTargets {
+      if !u.date.Before(grant.grantDate) && u.date.Before(periodEnd) {
+        hours += u.hours
+      }
+    }
+    if limit := hourlyLeaveDaysPerYear * target.dayHours; hours > limit {
+      c.JSON(http.StatusConflict, gin.H{
+        "error": fmt.Sprintf("時間単位の年次有給休暇は%s付与の期間で%d時間（%d日分）までです", grant.GrantDate, limit, hourlyLeaveDaysPerYear),
+        "code":  errCodeHourlyLeaveLimit,
+      })
+      return false
+    }
+  }
+
+  // 登録した場合に、登録した日または以降の休暇が付与を超えないか
+  balance := summarizeAnnualLeave(employeeID, hireDate, grants, registered, targets[0].date)
+  if allocateAnnualLeave(grants, withTargets) > balance.Unallocated+1e-9 {
+    c.JSON(http.StatusConflict, gin.H{
+      "error": fmt.Sprintf("年次有給休暇の残日数が不足しています（%sの残日数: %g日）", balance.AsOf, balance.Remaining),
+      "code":  errCodeInsufficientBalance,
+    })
+    return false
+  }
+  return true
+}
+
+// 年次有給休暇の残日数取得（dateで基準日を指定可、省略時は当日）
+func getLeaveBalance(c *gin.Context) {
+  id, err := strconv.Atoi(c.Param("id"))
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なID"})
+    return
+  }
+
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, id) {
+    return
+  }
+
+  date := c.DefaultQuery("date", time.Now().Format("2006-01-02"))
+  asOf, err := time.ParseInLocation("2006-01-02", date, time.Local)
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な日付形式", "code": errCodeInvalidDate})
+    return
+  }
+
+  hireDate, grants, usage, err := loadAnnualLeave(id, asOf)
+  if err == errHireDateNotSet {
+    c.JSON(http.StatusNotFound, gin.H{"error": "入社日が登録されていません", "code": errCodeHireDateNotSet})
+    return
+  }
+  if err != nil {
+    handleDatabaseError(c, err, "年次有給休暇の残日数の取得に失敗しました")
+    return
+  }
+  c.JSON(http.StatusOK, summarizeAnnualLeave(id, hireDate, grants, usage, asOf))
+}
+
+// 年5日の取得義務（付与日数が10日以上の付与について、付与日から1年以内に5日取得させる）
+const (
+  annualLeaveObligationGrantDays = 10 // 対象となる付与日数
+  annualLeaveObligationDays      = 5  // 取得させる日数
+)
+
+// 年5日の取得義務の状況
+type LeaveComplianceEntry struct {
+  Employee    Employee `json:"employee"`
+  GrantDate   string   `json:"grantDate"`   // 付与日（期間の初日）
+  PeriodEnd   string   `json:"periodEnd"`   // 期間の末日
+  GrantDays   float64  `json:"grantDays"`   // 付与日数
+  Taken       float64  `json:"taken"`       // 期間内の年次有給休暇（登録済みの予定・時季指定を含む）
+  TakenToDate float64  `json:"takenToDate"` // うち基準日までに取得した日数
+  Planned     float64  `json:"planned"`     // うち時季指定した日数
+  Shortfall   float64  `json:"shortfall"`   // 5日に不足する日数
+  DaysLeft    int      `json:"daysLeft"`    // 期間の末日までの日数
+  AtRisk      bool     `json:"atRisk"`      // 5日に達していない
+}
+
+// 年5日の取得義務のレポート
+type LeaveComplianceReport struct {
+  AsOf            string                 `json:"asOf"`
+  Entries         []LeaveComplianceEntry `json:"entries"`
+  MissingHireDate []Employee             `json:"missingHireDate"` // 入社日が未登録で判定できない社員
+}
+
+// 時季指定の登録リクエスト
+type PlannedLeaveRequest struct {
+  EmployeeID int      `json:"employeeId" binding:"required"`
+  Dates      []string `json:"dates" binding:"required"` // YYYY-MM-DD形式
+}
+
+// 指定日を含む取得義務の期間の付与（対象外の場合はnil）
+func obligationGrant(grants []LeaveGrant, date time.Time) *LeaveGrant {
+  for i := len(grants) - 1; i >= 0; i-- {
+    g := &grants[i]
+    if g.grantDate.After(date) || g.Days < annualLeaveObligationGrantDays {
+      continue
+    }
+    if date.Before(g.grantDate.AddDate(1, 0, 0)) {
+      return g
+    }
+    return nil
+  }
+  return nil
+}
+
+// 取得義務の期間の状況を集計する
+func leaveCompliance(employee Employee, grant *LeaveGrant, asOf time.Time) (*LeaveComplianceEntry, error) {
+  periodEnd := grant.grantDate.AddDate(1, 0, 0)
+  entry := &LeaveComplianceEntry{
+    Employee:  employee,
+    GrantDate: grant.GrantDate,
+    PeriodEnd: periodEnd.AddDate(0, 0, -1).Format("2006-01-02"),
+    GrantDays: grant.Days,
+    DaysLeft:  int(periodEnd.Sub(asOf).Hours()/24) - 1,
+  }
+
+  // 半休は0.5日として数え、時間単位の休暇は取得義務の日数に含めない
+  err := db.QueryRow(`
+    SELECT
+      COALESCE(SUM(CASE WHEN lerekb = $5 THEN 1 WHEN lerekb = $6 THEN 0 ELSE 0.5 END), 0),
+      COALESCE(SUM(CASE WHEN lerekb = $5 THEN 1 WHEN lerekb = $6 THEN 0 ELSE 0.5 END) FILTER (WHERE leredt <= $4), 0),
+      COALESCE(SUM(CASE WHEN lerekb = $5 THEN 1 WHEN lerekb = $6 THEN 0 ELSE 0.5 END) FILTER (WHERE leresh), 0)
+    FROM TBL_LEAVE
+    WHERE lereid = $1 AND leretp IN (SELECT kyukid FROM TBL_KYUKA WHERE kyuksh) AND leredt >= $2 AND leredt < $3
+  `, employee.ID, grant.GrantDate, periodEnd.Format("2006-01-02"), asOf.Format("2006-01-02"),
+    leaveUnitFullDay, leaveUnitHourly).
+    Scan(&entry.Taken, &entry.TakenToDate, &entry.Planned)
+  if err != nil {
+    return nil, err
+  }
+
+  entry.Shortfall = math.Max(0, annualLeaveObligationDays-entry.Taken)
+  entry.AtRisk = entry.Shortfall > 0
+  return entry, nil
+}
+
+// 年5日の取得義務のレポート（人事のみ）
+// 基準日（date、省略時は当日）を含む期間で5日に達していない社員を期間の末日が近い順に返す（all=trueで全社員）
+func getLeaveCompliance(c *gin.Context) {
+  date := c.DefaultQuery("date", time.Now().Format("2006-01-02"))
+  asOf, err := time.ParseInLocation("2006-01-02", date, time.Local)
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な日付形式", "code": errCodeInvalidDate})
+    return
+  }
+  all := c.Query("all") == "true"
+
+  rows, err := db.Query("SELECT emplid, emplnm, emplrl, emplhd FROM TBL_EMPLO ORDER BY emplid")
+  if err != nil {
+    handleDatabaseError(c, err, "社員情報の取得に失敗しました")
+    return
+  }
+  type employeeRow struct {
+    employee Employee
+    hireDate sql.NullTime
+  }
+  var employees []employeeRow
+  for rows.Next() {
+    var r employeeRow
+    if err := rows.Scan(&r.employee.ID, &r.employee.Name, &r.employee.Role, &r.hireDate); err != nil {
+      rows.Close()
+      handleDatabaseError(c, err, "社員情報の取得に失敗しました")
+      return
+    }
+    r.employee.IsAdmin = isAdminRole(r.employee.Role)
+    employees = append(employees, r)
+  }
+  rows.Close()
+  if err := rows.Err(); err != nil {
+    handleDatabaseError(c, err, "社員情報の取得に失敗しました")
+    return
+  }
+
+  report := LeaveComplianceReport{
+    AsOf:            asOf.Format("2006-01-02"),
+    Entries:         []LeaveComplianceEntry{},
+    MissingHireDate: []Employee{},
+  }
+  for _, r := range employees {
+    if !r.hireDate.Valid {
+      report.MissingHireDate = append(report.MissingHireDate, r.employee)
+      continue
+    }
+    d := r.hireDate.Time
+    hireDate := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Local)
+
+    grants, err := annualLeaveGrants(r.employee.ID, hireDate, asOf)
+    if err != nil {
+      handleDatabaseError(c, err, "年次有給休暇の付与の取得に失敗しました")
+      return
+    }
+    grant := obligationGrant(grants, asOf)
+    if grant == nil {
+      continue
+    }
+    entry, err := leaveCompliance(r.employee, grant, asOf)
+    if err != nil {
+      handleDatabaseError(c, err, "年次有給休暇の取得状況の集計に失敗しました")
+      return
+    }
+    if entry.AtRisk || all {
+      report.Entries = append(report.Entries, *entry)
+    }
+  }
+  sort.SliceStable(report.Entries, func(i, j int) bool {
+    return report.Entries[i].PeriodEnd < report.Entries[j].PeriodEnd
+  })
+
+  c.JSON(http.StatusOK, report)
+}
+
+// 時季指定の登録（人事のみ）
+// 取得義務の期間で5日に不足する日数まで、所定の勤務日に年次有給休暇を登録する
+func createPlannedLeave(c *gin.Context) {
+  var req PlannedLeaveRequest
+  if err := c.ShouldBindJSON(&req); err != nil || len(req.Dates) == 0 {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト（社員IDと日付は必須です）"})
+    return
+  }
+
+  hireDate, err := fetchHireDate(req.EmployeeID)
+  if err == errHireDateNotSet {
+    c.JSON(http.StatusConflict, gin.H{"error": "入社日が登録されていないため時季指定できません", "code": errCodeHireDateNotSet})
+    return
+  }
+  if err != nil {
+    handleDatabaseError(c, err, "社員情報の取得に失敗しました")
+    return
+  }
+  history, err := fetchWorkSchedules(req.EmployeeID)
+  if err != nil {
+    handleDatabaseError(c, err, "勤務形態の取得に失敗しました")
+    return
+  }
+
+  now := time.Now()
+  today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+  seen := map[string]bool{}
+  var targets []time.Time
+  for _, date := range req.Dates {
+    // 入力期間（締め状態）のチェック
+    if !checkAttendanceWritable(c, req.EmployeeID, date) {
+      return
+    }
+    target, _ := time.ParseInLocation("2006-01-02", date, time.Local)
+    if seen[date] {
+      c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%sが重複しています", date)})
+      return
+    }
+    seen[date] = true
+    if target.Before(today) {
+      c.JSON(http.StatusBadRequest, gin.H{"error": "時季指定は当日以降の日を指定してください"})
+      return
+    }
+    if workScheduleOn(history, target).isRestDay(target.Weekday()) {
+      c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%sは所定の休日です", date)})
+      return
+    }
+    targets = append(targets, target)
+  }
+  sort.Slice(targets, func(i, j int) bool { return targets[i].Before(targets[j]) })
+
+  // 勤怠・休暇が登録済みの日は指定できない
+  var registered bool
+  err = db.QueryRow(`
+    SELECT EXISTS (SELECT 1 FROM TBL_ATTEN WHERE atteid = $1 AND attedt = ANY($2::date[]))
+      OR EXISTS (SELECT 1 FROM TBL_LEAVE WHERE lereid = $1 AND leredt = ANY($2::date[]))
+  `, req.EmployeeID, pq.Array(req.Dates)).Scan(&registered)
+  if err != nil {
+    handleDatabaseError(c, err, "勤怠データの確認に失敗しました")
+    return
+  }
+  if registered {
+    c.JSON(http.StatusConflict, gin.H{"error": "勤怠または休暇が登録されている日は時季指定できません"})
+    return
+  }
+
+  // 取得義務の期間毎に、5日に不足する日数までしか指定できない
+  grants, err := annualLeaveGrants(req.EmployeeID, hireDate, targets[len(targets)-1])
+  if err != nil {
+    handleDatabaseError(c, err, "年次有給休暇の付与の取得に失敗しました")
+    return
+  }
+  counts := map[string]float64{}
+  for _, target := range targets {
+    grant := obligationGrant(grants, target)
+    if grant == nil {
+      c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%sは年5日の取得義務の対象期間外です", target.Format("2006-01-02"))})
+      return
+    }
+    entry, err := leaveCompliance(Employee{ID: req.EmployeeID}, grant, today)
+    if err != nil {
+      handleDatabaseError(c, err, "年次有給休暇の取得状況の集計に失敗しました")
+      return
+    }
+    // 時季指定は全日なので、半日・時間単位の不足は1日に切り上げる
+    counts[grant.GrantDate]++
+    if limit := math.Ceil(entry.Shortfall); counts[grant.GrantDate] > limit {
+      c.JSON(http.StatusBadRequest, gin.H{
+        "error": fmt.Sprintf("%s付与の期間で時季指定できるのは5日に不足する%g日（%g日）までです", grant.GrantDate, entry.Shortfall, limit),
+      })
+      return
+    }
+  }
+
+  // 年次有給休暇の残日数のチェック（時季指定は全日の休暇）
+  leaves := make([]Attendance, 0, len(req.Dates))
+  for _, date := range req.Dates {
+    leaves = append(leaves, Attendance{
+      EmployeeID:   req.EmployeeID,
+      Date:         date,
+      LeaveType:    leaveTypeAnnual,
+      LeaveUnit:    leaveUnitFullDay,
+      PlannedLeave: true,
+    })
+  }
+  if !checkAnnualLeaveBalance(c, req.EmployeeID, leaves...) {
+    return
+  }
+
+  operatorID := currentEmployeeID(c)
+  executeWithTransaction(c, func(tx *sql.Tx) error {
+    for i := range leaves {
+      if err := saveAttendance(tx, &leaves[i], nil, operatorID); err != nil {
+        return err
+      }
+    }
+
+    log.Printf("時季指定: 社員%d %v (登録者%d)", req.EmployeeID, req.Dates, operatorID)
+    return nil
+  }, "年次有給休暇を時季指定しました")
+}
+
+// 休暇申請の状態（TBL_KYUSE.kyusst）
+const (
+  leaveRequestStatusPending   = 1 // 申請中
+  leaveRequestStatusApproved  = 2 // 承認
+  leaveRequestStatusRejected  = 3 // 却下
+  leaveRequestStatusCancelled = 4 // 取消
+)
+
+// 休暇申請の状態名
+var leaveRequestStatusNameMap = map[int]string{
+  leaveRequestStatusPending:   "申請中",
+  leaveRequestStatusApproved:  "承認",
+  leaveRequestStatusRejected:  "却下",
+  leaveRequestStatusCancelled: "取消",
+}
+
+// 休暇申請のエラーコード
+const (
+  errCodeLeaveRequestRequired      = "LEAVE_REQUEST_REQUIRED"       // 休暇は休暇申請が必要
+  errCodeLeaveRequestExists        = "LEAVE_REQUEST_EXISTS"         // 同じ日の申請が申請中・承認済み
+  errCodeLeaveRequestNotPending    = "LEAVE_REQUEST_NOT_PENDING"    // 申請中ではない（処理済み）
+  errCodeLeaveRequestNotCancelable = "LEAVE_REQUEST_NOT_CANCELABLE" // 取り消せない状態・日付
+)
+
+// 休暇申請
+type LeaveRequest struct {
+  ID               int               `json:"id"`
+  EmployeeID       int               `json:"employeeId"`
+  EmployeeName     string            `json:"employeeName"`
+  Date             string            `json:"date"`
+  LeaveType        int               `json:"leaveType"`
+  LeaveName        string            `json:"leaveName"`                  // 休暇タイプの名称
+  NeedsCertificate bool              `json:"needsCertificate,omitempty"` // 証明書類の提出が必要な休暇タイプ
+  LeaveUnit        int               `json:"leaveUnit"`
+  LeaveHours       int               `json:"leaveHours,omitempty"`
+  Reason           string            `json:"reason,omitempty"`
+  Status           int               `json:"status"`
+  StatusName       string            `json:"statusName"`
+  RequestedAt      string            `json:"requestedAt"`
+  History          []CorrectionEvent `json:"history"` // 申請・承認・却下・取消の履歴（形式は修正申請と共通）
+}
+
+// 休暇申請の登録リクエスト
+type LeaveApplication struct {
+  Leave  Attendance `json:"leave"` // 申請する休暇（employeeId, date, leaveType, leaveUnit, leaveHours）
+  Reason string     `json:"reason,omitempty"`
+}
+
+// 申請した休暇の内容
+func (r *LeaveRequest) attendance() Attendance {
+  return Attendance{
+    EmployeeID: r.EmployeeID,
+    Date:       r.Date,
+    LeaveType:  r.LeaveType,
+    LeaveUnit:  r.LeaveUnit,
+    LeaveHours: r.LeaveHours,
+  }
+}
+
+// 休暇申請を取得（conditionはTBL_KYUSE kに対する条件、新しい順）
+func queryLeaveRequests(condition string, args ...interface{}) ([]LeaveRequest, error) {
+  rows, err := db.Query(`
+    SELECT k.kyusid, k.kyusem, e.emplnm, TO_CHAR(k.kyusdt, 'YYYY-MM-DD'), k.kyustp, t.kyuknm, t.kyuksm,
+      k.kyuskb, k.kyushr, COALESCE(k.kyusry, ''), k.kyusst, TO_CHAR(k.kyusat, 'YYYY-MM-DD HH24:MI:SS')
+    FROM TBL_KYUSE k
+    JOIN TBL_EMPLO e ON e.emplid = k.kyusem
+    JOIN TBL_KYUKA t ON t.kyukid = k.kyustp
+    WHERE `+condition+`
+    ORDER BY k.kyusat DESC, k.kyusid DESC
+  `, args...)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  requests := []LeaveRequest{}
+  index := map[int]int{}
+  ids := []int64{}
+  for rows.Next() {
+    var req LeaveRequest
+    err := rows.Scan(&req.ID, &req.EmployeeID, &req.EmployeeName, &req.Date, &req.LeaveType, &req.LeaveName,
+      &req.NeedsCertificate, &req.LeaveUnit, &req.LeaveHours, &req.Reason, &req.Status, &req.RequestedAt)
+    if err != nil {
+      return nil, err
+    }
+    req.StatusName = leaveRequestStatusNameMap[req.Status]
+    req.History = []CorrectionEvent{}
+    index[req.ID] = len(requests)
+    ids = append(ids, int64(req.ID))
+    requests = append(requests, req)
+  }
+  if err := rows.Err(); err != nil {
+    return nil, err
+  }
+  if len(ids) == 0 {
+    return requests, nil
+  }
+
+  // 履歴
+  historyRows, err := db.Query(`
+    SELECT h.kyurrq, h.kyurst, COALESCE(h.kyurcm, ''), h.kyurby, COALESCE(e.emplnm, ''),
+      TO_CHAR(h.kyurat, 'YYYY-MM-DD HH24:MI:SS')
+    FROM TBL_KYURK h
+    LEFT JOIN TBL_EMPLO e ON e.emplid = h.kyurby
+    WHERE h.kyurrq = ANY($1)
+    ORDER BY h.kyurat, h.kyurid
+  `, pq.Array(ids))
+  if err != nil {
+    return nil, err
+  }
+  defer historyRows.Close()
+
+  for historyRows.Next() {
+    var requestID int
+    var event CorrectionEvent
+    err := historyRows.Scan(&requestID, &event.Status, &event.Comment, &event.ActedBy, &event.ActedByName, &event.ActedAt)
+    if err != nil {
+      return nil, err
+    }
+    event.StatusName = leaveRequestStatusNameMap[event.Status]
+    i := index[requestID]
+    requests[i].History = append(requests[i].History, event)
+  }
+  return requests, historyRows.Err()
+}
+
+// 休暇申請を1件取得（存在しない場合はsql.ErrNoRows）
+func fetchLeaveRequest(requestID int) (*LeaveRequest, error) {
+  requests, err := queryLeaveRequests("k.kyusid = $1", requestID)
+  if err != nil {
+    return nil, err
+  }
+  if len(requests) == 0 {
+    return nil, sql.ErrNoRows
+  }
+  return &requests[0], nil
+}
+
+// 休暇申請がfromの状態でなくなっていた（同時に処理された）
+var errLeaveRequestNotPending = &conflictError{errCodeLeaveRequestNotPending, "この申請は他の操作で既に処理されています"}
+
+// 休暇申請の状態をfromからstatusに更新し、履歴を記録する
+// fromの状態でなくなっていた場合（同時に処理された場合）はerrLeaveRequestNotPendingを返す
+func updateLeaveRequestStatus(tx *sql.Tx, requestID int, from int, status int, comment string, operatorID int) error {
+  result, err := tx.Exec(`
+    UPDATE TBL_KYUSE
+    SET kyusst = $2
+    WHERE kyusid = $1 AND kyusst = $3
+  `, requestID, status, from)
+  if err != nil {
+    return err
+  }
+  count, err := result.RowsAffected()
+  if err != nil {
+    return err
+  }
+  if count == 0 {
+    return errLeaveRequestNotPending
+  }
+  return insertLeaveRequestEvent(tx, requestID, status, comment, operatorID)
+}
+
+// 休暇申請の履歴を記録する（TBL_KYURK）
+func insertLeaveRequestEvent(tx *sql.Tx, requestID int, status int, comment string, operatorID int) error {
+  _, err := tx.Exec(`
+    INSERT INTO TBL_KYURK (kyurrq, kyurst, kyurcm, kyurby, kyurat)
+    VALUES ($1, $2, $3, $4, NOW())
+  `, requestID, status, sql.NullString{String: comment, Valid: comment != ""}, operatorID)
+  return err
+}
+
+// 承認済みの休暇申請を取消にする（休暇削除・勤怠入力で休暇がなくなった場合）
+func cancelApprovedLeaveRequests(tx *sql.Tx, employeeID int, date string, comment string, operatorID int) error {
+  rows, err := tx.Query(`
+    UPDATE TBL_KYUSE
+    SET kyusst = $4
+    WHERE kyusem = $1 AND kyusdt = $2 AND kyusst = $3
+    RETURNING kyusid
+  `, employeeID, date, leaveRequestStatusApproved, leaveRequestStatusCancelled)
+  if err != nil {
+    return err
+  }
+  var ids []int
+  for rows.Next() {
+    var id int
+    if err := rows.Scan(&id); err != nil {
+      rows.Close()
+      return err
+    }
+    ids = append(ids, id)
+  }
+  rows.Close()
+  if err := rows.Err(); err != nil {
+    return err
+  }
+
+  for _, id := range ids {
+    if err := insertLeaveRequestEvent(tx, id, leaveRequestStatusCancelled, comment, operatorID); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// 休暇申請を取得する（存在しない場合は404）
+// 呼び出し元はnilが返った場合にそのままreturnすること
+func loadLeaveRequest(c *gin.Context) *LeaveRequest {
+  requestID, err := strconv.Atoi(c.Param("requestId"))
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な申請ID"})
+    return nil
+  }
+
+  req, err := fetchLeaveRequest(requestID)
+  if err != nil {
+    handleDatabaseError(c, err, "休暇申請の取得に失敗しました")
+    return nil
+  }
+  return req
+}
+
+// 休暇申請（本人のみ）
+// 直属の上司（または人事）が承認するまで勤怠・年次有給休暇の残日数には反映しない
+func submitLeaveRequest(c *gin.Context) {
+  var req LeaveApplication
+  if err := c.ShouldBindJSON(&req); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト"})
+    return
+  }
+
+  leave := req.Leave
+  if leave.EmployeeID <= 0 || leave.Date == "" || leave.LeaveType <= 0 {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "従業員ID、日付、休暇タイプは必須です"})
+    return
+  }
+  if leave.EmployeeID != currentEmployeeID(c) {
+    c.JSON(http.StatusForbidden, gin.H{"error": "休暇申請は本人のみ行えます"})
+    return
+  }
+
+  // 入力内容のチェック（承認時にも改めてチェックする）
+  if !checkAttendanceInput(c, &leave) {
+    return
+  }
+
+  // 同じ日の申請中・承認済みの休暇申請は1件まで
+  var exists bool
+  err := db.QueryRow(`
+    SELECT EXISTS (
+      SELECT 1 FROM TBL_KYUSE
+      WHERE kyusem = $1 AND kyusdt = $2 AND kyusst IN ($3, $4)
+    )
+  `, leave.EmployeeID, leave.Date, leaveRequestStatusPending, leaveRequestStatusApproved).Scan(&exists)
+  if err != nil {
+    handleDatabaseError(c, err, "休暇申請の確認に失敗しました")
+    return
+  }
+  if exists {
+    c.JSON(http.StatusConflict, gin.H{"error": "この日の休暇申請は既に申請中または承認済みです", "code": errCodeLeaveRequestExists})
+    return
+  }
+
+  // 承認者（直属の上司、未登録の場合は人事が承認する）
+  managerID, err := currentManagerID(leave.EmployeeID)
+  if err != nil && err != sql.ErrNoRows {
+    handleDatabaseError(c, err, "上司情報の取得に失敗しました")
+    return
+  }
+
+  var requestID int
+  executeWithTransaction(c, func(tx *sql.Tx) error {
+    err := tx.QueryRow(`
+      INSERT INTO TBL_KYUSE (kyusem, kyusdt, kyustp, kyuskb, kyushr, kyusry, kyusst, kyusat)
+      VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+      RETURNING kyusid
+    `, leave.EmployeeID, leave.Date, leave.LeaveType, leave.LeaveUnit, leave.LeaveHours,
+      sql.NullString{String: req.Reason, Valid: req.Reason != ""}, leaveRequestStatusPending).Scan(&requestID)
+    if err != nil {
+      return err
+    }
+    if err := insertLeaveRequestEvent(tx, requestID, leaveRequestStatusPending, req.Reason, leave.EmployeeID); err != nil {
+      return err
+    }
+
+    log.Printf("休暇申請: 社員%d %s (申請ID %d, 承認者: 上司%d)", leave.EmployeeID, leave.Date, requestID, managerID)
+    return nil
+  }, "休暇申請を登録しました")
+}
+
+// 社員の休暇申請一覧取得（statusで絞り込み可）
+func getLeaveRequests(c *gin.Context) {
+  id, err := strconv.Atoi(c.Param("id"))
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なID"})
+    return
+  }
+
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, id) {
+    return
+  }
+
+  condition := "k.kyusem = $1"
+  args := []interface{}{id}
+  if status := c.Query("status"); status != "" {
+    st, err := strconv.Atoi(status)
+    if err != nil || leaveRequestStatusNameMap[st] == "" {
+      c.JSON(http.StatusBadRequest, gin.H{"error": "無効な状態"})
+      return
+    }
+    condition += " AND k.kyusst = $2"
+    args = append(args, st)
+  }
+
+  requests, err := queryLeaveRequests(condition, args...)
+  if err != nil {
+    handleDatabaseError(c, err, "休暇申請の取得に失敗しました")
+    return
+  }
+  c.JSON(http.StatusOK, requests)
+}
+
+// 承認待ちの休暇申請一覧取得（上司への通知）
+// 上司は直属の部下の申請、人事は全社員の申請
+func getTeamLeaveRequests(c *gin.Context) {
+  status := leaveRequestStatusPending
+  if s := c.Query("status"); s != "" {
+    st, err := strconv.Atoi(s)
+    if err != nil || leaveRequestStatusNameMap[st] == "" {
+      c.JSON(http.StatusBadRequest, gin.H{"error": "無効な状態"})
+      return
+    }
+    status = st
+  }
+
+  var requests []LeaveRequest
+  var err error
+  if currentRole(c) == roleHR {
+    requests, err = queryLeaveRequests("k.kyusst = $1", status)
+  } else {
+    requests, err = queryLeaveRequests(`k.kyusst = $1 AND k.kyusem IN (
+      SELECT joshid FROM TBL_JOSHI WHERE joshji = $2 AND joshsd <= CURRENT_DATE AND (joshed IS NULL OR joshed > CURRENT_DATE)
+    )`, status, currentEmployeeID(c))
+  }
+  if err != nil {
+    handleDatabaseError(c, err, "休暇申請の取得に失敗しました")
+    return
+  }
+  c.JSON(http.StatusOK, requests)
+}
+
+// 休暇申請の承認・却下（直属の上司または人事、本人は不可）
+// 承認した場合のみ休暇を登録し、登録時にも締め状態・年次有給休暇の残日数をチェックする
+func decideLeaveRequest(approve bool) gin.HandlerFunc {
+  return func(c *gin.Context) {
+    decision, ok := bindCorrectionDecision(c)
+    if !ok {
+      return
+    }
+    req := loadLeaveRequest(c)
+    if req == nil {
+      return
+    }
+    if req.Status != leaveRequestStatusPending {
+      c.JSON(http.StatusConflict, gin.H{
+        "error": fmt.Sprintf("この申請は%sのため処理できません", req.StatusName),
+        "code":  errCodeLeaveRequestNotPending,
+      })
+      return
+    }
+
+    approverID := currentEmployeeID(c)
+    if req.EmployeeID == approverID {
+      c.JSON(http.StatusForbidden, gin.H{"error": "自分の休暇申請は承認・却下できません"})
+      return
+    }
+    if currentRole(c) != roleHR {
+      isReport, err := isDirectReport(approverID, req.EmployeeID)
+      if err != nil {
+        handleDatabaseError(c, err, "上司・部下関係の確認に失敗しました")
+        return
+      }
+      if !isReport {
+        c.JSON(http.StatusForbidden, gin.H{"error": "直属の部下の休暇申請のみ承認・却下できます"})
+        return
+      }
+    }
+
+    if !approve {
+      executeWithTransaction(c, func(tx *sql.Tx) error {
+        return updateLeaveRequestStatus(tx, req.ID, leaveRequestStatusPending, leaveRequestStatusRejected, decision.Comment, approverID)
+      }, "休暇申請を却下しました")
+      return
+    }
+
+    leave := req.attendance()
+    if !checkAttendanceInput(c, &leave) {
+      return
+    }
+    before, err := fetchStoredAttendance(leave.EmployeeID, leave.Date)
+    if err != nil {
+      handleDatabaseError(c, err, "勤怠データの取得に失敗しました")
+      return
+    }
+
+    executeWithTransaction(c, func(tx *sql.Tx) error {
+      err := updateLeaveRequestStatus(tx, req.ID, leaveRequestStatusPending, leaveRequestStatusApproved, decision.Comment, approverID)
+      if err != nil {
+        return err
+      }
+      return saveAttendance(tx, &leave, before, approverID)
+    }, "休暇申請を承認し、休暇を登録しました")
+  }
+}
+
+// 休暇申請の取消（申請者本人のみ）
+// 申請中の申請はそのまま取り消し、承認済みの申請は当日以降の休暇に限り登録した休暇を削除する
+func cancelLeaveRequest(c *gin.Context) {
+  decision, ok := bindCorrectionDecision(c)
+  if !ok {
+    return
+  }
+  req := loadLeaveRequest(c)
+  if req == nil {
+    return
+  }
+  if req.EmployeeID != currentEmployeeID(c) {
+    c.JSON(http.StatusForbidden, gin.H{"error": "休暇申請の取消は本人のみ行えます"})
+    return
+  }
+
+  if req.Status == leaveRequestStatusPending {
+    executeWithTransaction(c, func(tx *sql.Tx) error {
+      return updateLeaveRequestStatus(tx, req.ID, leaveRequestStatusPending, leaveRequestStatusCancelled, decision.Comment, req.EmployeeID)
+    }, "休暇申請を取り消しました")
+    return
+  }
+  if req.Status != leaveRequestStatusApproved {
+    c.JSON(http.StatusConflict, gin.H{
+      "error": fmt.Sprintf("この申請は%sのため取り消せません", req.StatusName),
+      "code":  errCodeLeaveRequestNotCancelable,
+    })
+    return
+  }
+
+  // 承認済みの休暇の取消
+  if req.Date < time.Now().Format("2006-01-02") {
+    c.JSON(http.StatusConflict, gin.H{"error": "過去の日の休暇は取り消せません（修正申請から変更してください）", "code": errCodeLeaveRequestNotCancelable})
+    return
+  }
+  if !checkAttendanceWritable(c, req.EmployeeID, req.Date) {
+    return
+  }
+  before, err := fetchStoredAttendance(req.EmployeeID, req.Date)
+  if err != nil {
+    handleDatabaseError(c, err, "休暇データの取得に失敗しました")
+    return
+  }
+  if before != nil && before.PlannedLeave {
+    c.JSON(http.StatusForbidden, gin.H{"error": "時季指定された年次有給休暇は人事のみ削除できます"})
+    return
+  }
+
+  executeWithTransaction(c, func(tx *sql.Tx) error {
+    err := updateLeaveRequestStatus(tx, req.ID, leaveRequestStatusApproved, leaveRequestStatusCancelled, decision.Comment, req.EmployeeID)
+    if err != nil {
+      return err
+    }
+    if before == nil || before.LeaveType == 0 {
+      return nil // 休暇は既に削除済み
+    }
+    return removeLeave(tx, before, req.EmployeeID)
+  }, "休暇申請を取り消しました")
+}
@@ -0,0 +1,593 @@
+package main
+
+import (
+  "fmt"
+  "net/http"
+  "reflect"
+  "testing"
+  "time"
+
+  "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetWorkSchedule(t *testing.T) {
+  testEmployeeAccessCases(t, selfAccessCases, http.MethodGet, employeePath("/api/schedule/%d"), nil, func(mock sqlmock.Sqlmock, tc accessCase) {
+    mock.ExpectQuery("FROM TBL_KINMU").
+      WithArgs(tc.targetID).
+      WillReturnRows(sqlmock.NewRows([]string{"kinmfm", "kinmst", "kinmet", "kinmbs", "kinmbe", "kinmkd", "kinmhd"}).
+        AddRow(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), "10:00:00", "19:00:00", "13:00:00", "14:00:00", "06", 0))
+  }, func(t *testing.T, tc accessCase, body []byte) {
+    var res struct {
+      Current *WorkSchedule  `json:"current"`
+      History []WorkSchedule `json:"history"`
+    }
+    decodeBody(t, body, &res)
+    want := WorkSchedule{
+      EffectiveFrom: "2025-04-01", StartTime: "10:00", EndTime: "19:00",
+      BreakStart: "13:00", BreakEnd: "14:00", RestDays: []int{0, 6}, LegalHoliday: 0,
+    }
+    if res.Current == nil || !reflect.DeepEqual(*res.Current, want) {
+      t.Errorf("current=%+v、%+vを期待", res.Current, want)
+    }
+    if len(res.History) != 1 {
+      t.Errorf("history=%+v、1件を期待", res.History)
+    }
+  })
+}
+
+func TestGetAttendanceHistory(t *testing.T) {
+  testEmployeeAccessCases(t, selfAccessCases, http.MethodGet, employeePath("/api/attendance/%d/2025-04-01/history"), nil, func(mock sqlmock.Sqlmock, tc accessCase) {
+    mock.ExpectQuery("FROM TBL_HENKO").
+      WithArgs(tc.targetID, "2025-04-01").
+      WillReturnRows(sqlmock.NewRows([]string{"henkid", "henkbf", "henkaf", "henkby", "emplnm", "henkat"}).
+        AddRow(1, nil, []byte(`{"employeeId":10001,"date":"2025-04-01","startTime":"09:00"}`), tc.targetID, "佐藤 太郎",
+          time.Date(2025, 4, 1, 9, 0, 5, 0, time.UTC)).
+        AddRow(2, []byte(`{"employeeId":10001,"date":"2025-04-01","startTime":"09:00"}`),
+          []byte(`{"employeeId":10001,"date":"2025-04-01","startTime":"09:00","endTime":"18:00"}`), testHRID, "斎藤 次郎",
+          time.Date(2025, 4, 2, 10, 0, 0, 0, time.UTC)))
+  }, func(t *testing.T, tc accessCase, body []byte) {
+    var history []AttendanceChange
+    decodeBody(t, body, &history)
+    if len(history) != 2 {
+      t.Fatalf("変更履歴%+v、2件を期待", history)
+    }
+    first, second := history[0], history[1]
+    if first.Before != nil || first.After == nil || first.After.StartTime != "09:00" || first.ChangedAt != "2025-04-01 09:00:05" {
+      t.Errorf("1件目%+v、出勤の登録を期待", first)
+    }
+    if second.Before == nil || second.After == nil || second.After.EndTime != "18:00" || second.ChangedByName != "斎藤 次郎" {
+      t.Errorf("2件目%+v、人事による退勤の登録を期待", second)
+    }
+  })
+}
+
+func TestGetCorrections(t *testing.T) {
+  testEmployeeAccessCases(t, selfAccessCases, http.MethodGet, employeePath("/api/corrections/%d"), nil, func(mock sqlmock.Sqlmock, tc accessCase) {
+    mock.ExpectQuery("FROM TBL_SHUSE").
+      WithArgs(tc.targetID).
+      WillReturnRows(sqlmock.NewRows(correctionColumns).
+        AddRow(7, tc.targetID, "佐藤 太郎", "2025-04-01", nil,
+          []byte(`{"employeeId":10001,"date":"2025-04-01","startTime":"09:00","endTime":"18:00"}`),
+          "打刻漏れ", correctionStatusApproved, "2025-04-02 09:00:00"))
+    mock.ExpectQuery("FROM TBL_SHURK").
+      WillReturnRows(sqlmock.NewRows([]string{"shurrq", "shurst", "shurcm", "shurby", "emplnm", "shurat"}).
+        AddRow(7, correctionStatusPending, "", tc.targetID, "佐藤 太郎", "2025-04-02 09:00:00").
+        AddRow(7, correctionStatusApproved, "確認しました", testManagerID, "山田 花子", "2025-04-02 10:00:00"))
+  }, func(t *testing.T, tc accessCase, body []byte) {
+    var corrections []AttendanceCorrection
+    decodeBody(t, body, &corrections)
+    if len(corrections) != 1 {
+      t.Fatalf("修正申請%+v、1件を期待", corrections)
+    }
+    corr := corrections[0]
+    if corr.ID != 7 || corr.Before != nil || corr.Requested == nil || corr.Requested.EndTime != "18:00" || corr.StatusName != "承認" {
+      t.Errorf("修正申請%+v、承認済みの申請を期待", corr)
+    }
+    if len(corr.History) != 2 || corr.History[1].ActedBy != testManagerID || corr.History[1].StatusName != "承認" {
+      t.Errorf("履歴%+v、申請・上司の承認を期待", corr.History)
+    }
+  })
+}
+
+// 修正申請（TBL_SHUSE）の列
+var correctionColumns = []string{"shusid", "shusem", "emplnm", "shusdt", "shusbf", "shusjs", "shusry", "shusst", "shusat"}
+
+// 読み込み後に他の上司・人事が処理した申請は404ではなく409で返す
+func TestDecideCorrectionConcurrentlyDecided(t *testing.T) {
+  router := setupRouter()
+  mock := setupMockDB(t)
+  expectRole(mock, testManagerID, roleManager)
+  mock.ExpectQuery("FROM TBL_SHUSE").
+    WithArgs(7).
+    WillReturnRows(sqlmock.NewRows(correctionColumns).
+      AddRow(7, testGeneralID, "佐藤 太郎", "2025-04-01", nil,
+        []byte(`{"employeeId":10001,"date":"2025-04-01","startTime":"09:00","endTime":"18:00"}`),
+        "打刻漏れ", correctionStatusPending, "2025-04-02 09:00:00"))
+  mock.ExpectQuery("FROM TBL_SHURK").
+    WillReturnRows(sqlmock.NewRows([]string{"shurrq", "shurst", "shurcm", "shurby", "emplnm", "shurat"}).
+      AddRow(7, correctionStatusPending, "", testGeneralID, "佐藤 太郎", "2025-04-02 09:00:00"))
+  expectDirectReport(mock, testManagerID, testGeneralID, true)
+  mock.ExpectBegin()
+  mock.ExpectExec("UPDATE TBL_SHUSE").
+    WithArgs(7, correctionStatusRejected, correctionStatusPending).
+    WillReturnResult(sqlmock.NewResult(0, 0))
+  mock.ExpectRollback()
+
+  w := performRequest(t, router, http.MethodPost, "/api/team/corrections/7/reject", "", testManagerID, roleManager)
+  if w.Code != http.StatusConflict {
+    t.Fatalf("ステータス%d、409を期待: %s", w.Code, w.Body.String())
+  }
+  var res struct {
+    Code string `json:"code"`
+  }
+  decodeBody(t, w.Body.Bytes(), &res)
+  if res.Code != errCodeCorrectionNotPending {
+    t.Errorf("code=%s、%sを期待", res.Code, errCodeCorrectionNotPending)
+  }
+  if err := mock.ExpectationsWereMet(); err != nil {
+    t.Error(err)
+  }
+}
+
+// 社員の取得に失敗した実行も終了日時とエラー内容を記録する（実行中のまま残さない）
+func TestRunMonthlyCloseRecordsFailure(t *testing.T) {
+  mock := setupMockDB(t)
+  mock.ExpectQuery("INSERT INTO TBL_CLRUN").
+    WithArgs("202504", closeTriggerAuto).
+    WillReturnRows(sqlmock.NewRows([]string{"clruid"}).AddRow(5))
+  mock.ExpectQuery("FROM TBL_EMPLO").
+    WithArgs("202504").
+    WillReturnError(fmt.Errorf("接続が切断されました"))
+  mock.ExpectExec("UPDATE TBL_CLRUN").
+    WithArgs(5, 0, 0, 0, "社員の取得に失敗しました: 接続が切断されました").
+    WillReturnResult(sqlmock.NewResult(0, 1))
+
+  if _, err := runMonthlyClose("202504", closeTriggerAuto, 0); err == nil {
+    t.Error("社員の取得に失敗した締めがエラーになりません")
+  }
+  if err := mock.ExpectationsWereMet(); err != nil {
+    t.Error(err)
+  }
+}
+
+// 残日数の参照は付与を登録せず、ジョブで未登録の付与も付与済みとして数える
+func TestGetLeaveBalance(t *testing.T) {
+  date := func(s string) time.Time {
+    d, _ := time.Parse("2006-01-02", s)
+    return d
+  }
+  testEmployeeAccessCases(t, selfAccessCases, http.MethodGet, employeePath("/api/leave/%d/balance?date=2025-04-01"), nil, func(mock sqlmock.Sqlmock, tc accessCase) {
+    mock.ExpectQuery("SELECT emplhd FROM TBL_EMPLO").
+      WithArgs(tc.targetID).
+      WillReturnRows(sqlmock.NewRows([]string{"emplhd"}).AddRow(date("2023-08-31")))
+    expectNoRows(mock, "FROM TBL_KINMU")
+    expectNoRows(mock, "FROM TBL_LEAVE")
+    mock.ExpectQuery("FROM TBL_FUYOK").
+      WithArgs(tc.targetID).
+      WillReturnRows(sqlmock.NewRows([]string{"fuyodt", "fuyody", "fuyoex"}).
+        AddRow(date("2024-02-29"), 10, date("2026-02-28")))
+  }, func(t *testing.T, tc accessCase, body []byte) {
+    var balance LeaveBalance
+    decodeBody(t, body, &balance)
+    if len(balance.Grants) != 2 || balance.Grants[1].GrantDate != "2025-02-28" || balance.Grants[1].ExpiryDate != "2027-02-28" {
+      t.Errorf("付与%+v、2024-02-29（登録済み）・2025-02-28（未登録）を期待", balance.Grants)
+    }
+    if balance.Granted != 21 || balance.Remaining != 21 {
+      t.Errorf("granted=%v remaining=%v、21日を期待", balance.Granted, balance.Remaining)
+    }
+    if balance.NextGrantDate != "2026-02-28" || balance.NextGrantDays != 12 {
+      t.Errorf("次回付与%s・%v日、2026-02-28の12日を期待", balance.NextGrantDate, balance.NextGrantDays)
+    }
+  })
+}
+
+func TestAnnualLeaveSchedule(t *testing.T) {
+  tests := []struct {
+    name     string
+    hireDate string
+    until    string
+    want     []string // 付与日/失効日/付与日数
+  }{
+    {"6か月後の同じ日", "2024-04-01", "2026-10-01", []string{"2024-10-01/2026-10-01/10", "2025-10-01/2027-10-01/11", "2026-10-01/2028-10-01/12"}},
+    {"付与日の前日まで", "2024-04-01", "2024-09-30", nil},
+    {"8月31日入社は2月末日", "2024-08-31", "2028-03-01", []string{"2025-02-28/2027-02-28/10", "2026-02-28/2028-02-29/11", "2027-02-28/2029-02-28/12", "2028-02-29/2030-02-28/14"}},
+    {"8月30日入社のうるう年", "2023-08-30", "2024-03-01", []string{"2024-02-29/2026-02-28/10"}},
+    {"12月31日入社は6月30日", "2024-12-31", "2025-07-01", []string{"2025-06-30/2027-06-30/10"}},
+    {"6年6か月以上は20日", "2015-04-01", "2024-10-01", []string{
+      "2015-10-01/2017-10-01/10", "2016-10-01/2018-10-01/11", "2017-10-01/2019-10-01/12", "2018-10-01/2020-10-01/14",
+      "2019-10-01/2021-10-01/16", "2020-10-01/2022-10-01/18", "2021-10-01/2023-10-01/20", "2022-10-01/2024-10-01/20",
+      "2023-10-01/2025-10-01/20", "2024-10-01/2026-10-01/20",
+    }},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      hireDate, _ := time.ParseInLocation("2006-01-02", tt.hireDate, time.Local)
+      until, _ := time.ParseInLocation("2006-01-02", tt.until, time.Local)
+      var got []string
+      for _, g := range annualLeaveSchedule(hireDate, until) {
+        got = append(got, fmt.Sprintf("%s/%s/%v", g.GrantDate, g.ExpiryDate, g.Days))
+      }
+      if !reflect.DeepEqual(got, tt.want) {
+        t.Errorf("annualLeaveSchedule(%s, %s) = %v、%vを期待", tt.hireDate, tt.until, got, tt.want)
+      }
+    })
+  }
+}
+
+// 付与日が来た付与だけを登録する
+func TestRegisterAnnualLeaveGrants(t *testing.T) {
+  mock := setupMockDB(t)
+  mock.ExpectExec("INSERT INTO TBL_FUYOK").
+    WithArgs(testGeneralID, "2025-02-28", float64(10), "2027-02-28").
+    WillReturnResult(sqlmock.NewResult(0, 1))
+  mock.ExpectExec("INSERT INTO TBL_FUYOK").
+    WithArgs(testGeneralID, "2026-02-28", float64(11), "2028-02-29").
+    WillReturnResult(sqlmock.NewResult(0, 0))
+
+  hireDate := time.Date(2024, 8, 31, 0, 0, 0, 0, time.Local)
+  today := time.Date(2027, 2, 27, 0, 0, 0, 0, time.Local)
+  if err := registerAnnualLeaveGrants(testGeneralID, hireDate, today); err != nil {
+    t.Fatal(err)
+  }
+  if err := mock.ExpectationsWereMet(); err != nil {
+    t.Error(err)
+  }
+}
+
+// 休暇申請（TBL_KYUSE）の列
+var leaveRequestColumns = []string{"kyusid", "kyusem", "emplnm", "kyusdt", "kyustp", "kyuknm", "kyuksm", "kyuskb", "kyushr", "kyusry", "kyusst", "kyusat"}
+
+func TestGetLeaveRequests(t *testing.T) {
+  testEmployeeAccessCases(t, selfAccessCases, http.MethodGet, employeePath("/api/leave-requests/%d"), nil, func(mock sqlmock.Sqlmock, tc accessCase) {
+    mock.ExpectQuery("FROM TBL_KYUSE").
+      WithArgs(tc.targetID).
+      WillReturnRows(sqlmock.NewRows(leaveRequestColumns).
+        AddRow(3, tc.targetID, "佐藤 太郎", "2025-04-10", 1, "年次有給休暇", false, leaveUnitMorning, 0, "通院", leaveRequestStatusPending, "2025-04-01 12:00:00"))
+    mock.ExpectQuery("FROM TBL_KYURK").
+      WillReturnRows(sqlmock.NewRows([]string{"kyurrq", "kyurst", "kyurcm", "kyurby", "emplnm", "kyurat"}).
+        AddRow(3, leaveRequestStatusPending, "", tc.targetID, "佐藤 太郎", "2025-04-01 12:00:00"))
+  }, func(t *testing.T, tc accessCase, body []byte) {
+    var requests []LeaveRequest
+    decodeBody(t, body, &requests)
+    if len(requests) != 1 {
+      t.Fatalf("休暇申請%+v、1件を期待", requests)
+    }
+    req := requests[0]
+    if req.ID != 3 || req.LeaveName != "年次有給休暇" || req.LeaveUnit != leaveUnitMorning || req.StatusName != "申請中" || len(req.History) != 1 {
+      t.Errorf("休暇申請%+v、申請中の午前半休を期待", req)
+    }
+  })
+}
+
+// 読み込み後に他の上司・人事が処理した申請は404ではなく409で返す
+func TestDecideLeaveRequestConcurrentlyDecided(t *testing.T) {
+  router := setupRouter()
+  mock := setupMockDB(t)
+  expectRole(mock, testManagerID, roleManager)
+  mock.ExpectQuery("FROM TBL_KYUSE").
+    WithArgs(3).
+    WillReturnRows(sqlmock.NewRows(leaveRequestColumns).
+      AddRow(3, testGeneralID, "佐藤 太郎", "2025-04-10", 1, "年次有給休暇", false, leaveUnitFullDay, 0, "通院", leaveRequestStatusPending, "2025-04-01 12:00:00"))
+  mock.ExpectQuery("FROM TBL_KYURK").
+    WillReturnRows(sqlmock.NewRows([]string{"kyurrq", "kyurst", "kyurcm", "kyurby", "emplnm", "kyurat"}).
+      AddRow(3, leaveRequestStatusPending, "", testGeneralID, "佐藤 太郎", "2025-04-01 12:00:00"))
+  expectDirectReport(mock, testManagerID, testGeneralID, true)
+  mock.ExpectBegin()
+  mock.ExpectExec("UPDATE TBL_KYUSE").
+    WithArgs(3, leaveRequestStatusRejected, leaveRequestStatusPending).
+    WillReturnResult(sqlmock.NewResult(0, 0))
+  mock.ExpectRollback()
+
+  w := performRequest(t, router, http.MethodPost, "/api/team/leave-requests/3/reject", "", testManagerID, roleManager)
+  if w.Code != http.StatusConflict {
+    t.Fatalf("ステータス%d、409を期待: %s", w.Code, w.Body.String())
+  }
+  var res struct {
+    Code string `json:"code"`
+  }
+  decodeBody(t, w.Body.Bytes(), &res)
+  if res.Code != errCodeLeaveRequestNotPending {
+    t.Errorf("code=%s、%sを期待", res.Code, errCodeLeaveRequestNotPending)
+  }
+  if err := mock.ExpectationsWereMet(); err != nil {
+    t.Error(err)
+  }
+}
+
+// テスト用の1日分の労働（区間は日付の0時からの分で指定し、翌日にまたがる場合は24時以降の値にする）
+func testWorkDay(date string, schedule *WorkSchedule, intervals ...[2]int) workDay {
+  d, _ := time.ParseInLocation("2006-01-02", date, time.Local)
+  day := workDay{Date: d, Schedule: schedule}
+  for _, iv := range intervals {
+    day.Intervals = append(day.Intervals, timeRange{
+      Start: d.Add(time.Duration(iv[0]) * time.Minute),
+      End:   d.Add(time.Duration(iv[1]) * time.Minute),
+    })
+  }
+  return day
+}
+
+// 2025年4月の平日（月初から順にn日）に同じ区間で労働した日
+func testWeekdays(n int, intervals ...[2]int) []workDay {
+  var days []workDay
+  for d := time.Date(2025, 4, 1, 0, 0, 0, 0, time.Local); len(days) < n; d = d.AddDate(0, 0, 1) {
+    if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+      days = append(days, testWorkDay(d.Format("2006-01-02"), &defaultWorkSchedule, intervals...))
+    }
+  }
+  return days
+}
+
+func TestTallyWorkTime(t *testing.T) {
+  const h = 60
+  standard := [][2]int{{9 * h, 12 * h}, {13 * h, 18 * h}} // 所定どおり9:00〜18:00（休憩12:00〜13:00）
+  short := &WorkSchedule{StartTime: "10:00", EndTime: "17:00", BreakStart: "12:00", BreakEnd: "13:00", RestDays: []int{0, 6}}
+  april := time.Date(2025, 4, 1, 0, 0, 0, 0, time.Local) // 火曜日（週は3月30日の日曜日から）
+  may := time.Date(2025, 5, 1, 0, 0, 0, 0, time.Local)   // 木曜日（週は4月27日の日曜日から）
+
+  tests := []struct {
+    name       string
+    monthStart time.Time
+    days       []workDay
+    want       WorkTimeSummary
+  }{
+    {
+      name: "所定労働時間どおり", monthStart: april,
+      days: []workDay{testWorkDay("2025-04-01", &defaultWorkSchedule, standard...)},
+      want: WorkTimeSummary{WorkedMinutes: 8 * h},
+    },
+    {
+      name: "1日8時間を超える労働は時間外", monthStart: april,
+      days: []workDay{testWorkDay("2025-04-01", &defaultWorkSchedule, [2]int{9 * h, 12 * h}, [2]int{13 * h, 20 * h})},
+      want: WorkTimeSummary{WorkedMinutes: 10 * h, OvertimeMinutes: 2 * h},
+    },
+    {
+      name: "所定時間外・法定時間内は割増なしの区分", monthStart: april,
+      days: []workDay{testWorkDay("2025-04-01", short, standard...)},
+      want: WorkTimeSummary{WorkedMinutes: 8 * h, NonStatutoryMinutes: 2 * h},
+    },
+    {
+      name: "週40時間を超える所定休日の労働は時間外", monthStart: april,
+      days: []workDay{
+        testWorkDay("2025-04-07", &defaultWorkSchedule, standard...),
+        testWorkDay("2025-04-08", &defaultWorkSchedule, standard...),
+        testWorkDay("2025-04-09", &defaultWorkSchedule, standard...),
+        testWorkDay("2025-04-10", &defaultWorkSchedule, standard...),
+        testWorkDay("2025-04-11", &defaultWorkSchedule, standard...),
+        testWorkDay("2025-04-12", &defaultWorkSchedule, [2]int{9 * h, 13 * h}),
+      },
+      want: WorkTimeSummary{WorkedMinutes: 44 * h, OvertimeMinutes: 4 * h},
+    },
+    {
+      name: "月初を含む週は前月の労働も週40時間に含める", monthStart: april,
+      days: []workDay{
+        testWorkDay("2025-03-30", &defaultWorkSchedule, standard...), // 前月の法定休日は週40時間に含めない
+        testWorkDay("2025-03-31", &defaultWorkSchedule, standard...),
+        testWorkDay("2025-04-01", &defaultWorkSchedule, standard...),
+        testWorkDay("2025-04-02", &defaultWorkSchedule, standard...),
+        testWorkDay("2025-04-03", &defaultWorkSchedule, standard...),
+        testWorkDay("2025-04-04", &defaultWorkSchedule, standard...),
+        testWorkDay("2025-04-05", &defaultWorkSchedule, [2]int{9 * h, 13 * h}),
+      },
+      want: WorkTimeSummary{WorkedMinutes: 36 * h, OvertimeMinutes: 4 * h},
+    },
+    {
+      name: "前月の1日8時間超の労働は週40時間に含めない", monthStart: may,
+      days: []workDay{
+        testWorkDay("2025-04-28", &defaultWorkSchedule, [2]int{9 * h, 12 * h}, [2]int{13 * h, 20 * h}),
+        testWorkDay("2025-04-29", &defaultWorkSchedule, [2]int{9 * h, 12 * h}, [2]int{13 * h, 20 * h}),
+        testWorkDay("2025-04-30", &defaultWorkSchedule, [2]int{9 * h, 12 * h}, [2]int{13 * h, 20 * h}),
+        testWorkDay("2025-05-01", &defaultWorkSchedule, standard...),
+        testWorkDay("2025-05-02", &defaultWorkSchedule, standard...),
+        testWorkDay("2025-05-03", &defaultWorkSchedule, [2]int{9 * h, 11 * h}),
+      },
+      want: WorkTimeSummary{WorkedMinutes: 18 * h, OvertimeMinutes: 2 * h},
+    },
+    {
+      name: "月60時間までの時間外", monthStart: april,
+      days: testWeekdays(15, [2]int{9 * h, 12 * h}, [2]int{13 * h, 22 * h}),
+      want: WorkTimeSummary{WorkedMinutes: 180 * h, OvertimeMinutes: 60 * h},
+    },
+    {
+      name: "月60時間を超える時間外", monthStart: april,
+      days: testWeekdays(16, [2]int{9 * h, 12 * h}, [2]int{13 * h, 22 * h}),
+      want: WorkTimeSummary{WorkedMinutes: 192 * h, OvertimeMinutes: 60 * h, LongOvertimeMinutes: 4 * h},
+    },
+    {
+      name: "深夜労働は22:00から翌5:00まで", monthStart: april,
+      days: []workDay{testWorkDay("2025-04-01", &defaultWorkSchedule, [2]int{18 * h, 27 * h})},
+      want: WorkTimeSummary{WorkedMinutes: 9 * h, NonStatutoryMinutes: 8 * h, OvertimeMinutes: 1 * h, LateNightMinutes: 5 * h},
+    },
+    {
+      name: "早朝の深夜労働は5:00まで", monthStart: april,
+      days: []workDay{testWorkDay("2025-04-02", &defaultWorkSchedule, [2]int{4 * h, 6 * h})},
+      want: WorkTimeSummary{WorkedMinutes: 2 * h, NonStatutoryMinutes: 2 * h, LateNightMinutes: 1 * h},
+    },
+    {
+      name: "法定休日の労働は時間外・週40時間と別に集計", monthStart: april,
+      days: []workDay{testWorkDay("2025-04-06", &defaultWorkSchedule, [2]int{9 * h, 12 * h}, [2]int{13 * h, 20 * h})},
+      want: WorkTimeSummary{WorkedMinutes: 10 * h, HolidayMinutes: 10 * h},
+    },
+    {
+      name: "法定休日の深夜労働は0:00までが休日労働", monthStart: april,
+      days: []workDay{testWorkDay("2025-04-06", &defaultWorkSchedule, [2]int{20 * h, 26 * h})},
+      want: WorkTimeSummary{WorkedMinutes: 6 * h, HolidayMinutes: 4 * h, NonStatutoryMinutes: 2 * h, LateNightMinutes: 4 * h},
+    },
+    {
+      name: "法定休日の前日からの深夜労働は0:00から休日労働", monthStart: april,
+      days: []workDay{testWorkDay("2025-04-05", &defaultWorkSchedule, [2]int{22 * h, 26 * h})},
+      want: WorkTimeSummary{WorkedMinutes: 4 * h, NonStatutoryMinutes: 2 * h, HolidayMinutes: 2 * h, LateNightMinutes: 4 * h},
+    },
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      if got := tallyWorkTime(tt.days, tt.monthStart); *got != tt.want {
+        t.Errorf("tallyWorkTime = %+v\n%+vを期待", *got, tt.want)
+      }
+    })
+  }
+}
+
+// テスト用の日時（サーバーの時刻と同じ基準）
+func testClock(date string, clock string) time.Time {
+  t, _ := time.ParseInLocation("2006-01-02 15:04", date+" "+clock, time.Local)
+  return t
+}
+
+func TestPunchStateTarget(t *testing.T) {
+  now := testClock("2025-04-02", "10:00")
+  today := testClock("2025-04-02", "00:00")
+  yesterday := testClock("2025-04-01", "00:00")
+  working := &openSegment{Date: today, Seq: 1, Start: testClock("2025-04-02", "09:00")}
+
+  tests := []struct {
+    name     string
+    state    punchState
+    kind     string
+    want     time.Time
+    wantCode string
+  }{
+    {name: "出勤は当日", kind: punchIn, want: today},
+    {name: "退勤は勤務中の日付", state: punchState{Work: working}, kind: punchOut, want: today},
+    {name: "休憩開始は勤務中の日付", state: punchState{Work: working}, kind: punchBreakStart, want: today},
+    {name: "休憩終了は休憩中の日付", state: punchState{Work: working, Break: &openSegment{Date: today, Seq: 2, Start: testClock("2025-04-02", "09:30")}}, kind: punchBreakEnd, want: today},
+    {name: "日付をまたぐ勤務の退勤は前日", state: punchState{Work: &openSegment{Date: yesterday, Seq: 1, Start: testClock("2025-04-01", "22:00")}}, kind: punchOut, want: yesterday},
+    {name: "二重の出勤", state: punchState{Work: working}, kind: punchIn, wantCode: errCodeAlreadyClockedIn},
+    {name: "出勤前の退勤", kind: punchOut, wantCode: errCodeNotClockedIn},
+    {name: "出勤前の休憩開始", kind: punchBreakStart, wantCode: errCodeNotClockedIn},
+    {name: "休憩開始前の休憩終了", state: punchState{Work: working}, kind: punchBreakEnd, wantCode: errCodeNotOnBreak},
+    {name: "休憩中の退勤", state: punchState{Work: working, Break: &openSegment{Date: today, Seq: 2, Start: testClock("2025-04-02", "09:30")}}, kind: punchOut, wantCode: errCodeOnBreak},
+    {name: "二重の休憩開始", state: punchState{Work: working, Break: &openSegment{Date: today, Seq: 2, Start: testClock("2025-04-02", "09:30")}}, kind: punchBreakStart, wantCode: errCodeOnBreak},
+    {name: "出勤と同じ時刻の退勤", state: punchState{Work: &openSegment{Date: today, Seq: 1, Start: now}}, kind: punchOut, wantCode: errCodeDuplicatePunch},
+    {name: "休憩開始と同じ時刻の休憩終了", state: punchState{Work: working, Break: &openSegment{Date: today, Seq: 2, Start: now}}, kind: punchBreakEnd, wantCode: errCodeDuplicatePunch},
+    {name: "前々日の退勤が未打刻", state: punchState{Work: &openSegment{Date: testClock("2025-03-31", "00:00"), Seq: 1, Start: testClock("2025-03-31", "09:00")}}, kind: punchOut, wantCode: errCodeMissingClockOut},
+    {name: "退勤未打刻のままの出勤", state: punchState{Work: &openSegment{Date: testClock("2025-03-31", "00:00"), Seq: 1, Start: testClock("2025-03-31", "09:00")}}, kind: punchIn, wantCode: errCodeMissingClockOut},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      got, err := tt.state.target(tt.kind, now)
+      if tt.wantCode != "" {
+        perr, ok := err.(*punchError)
+        if !ok || perr.code != tt.wantCode {
+          t.Fatalf("target(%s) = %v、%sを期待", tt.kind, err, tt.wantCode)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatal(err)
+      }
+      if !got.Equal(tt.want) {
+        t.Errorf("target(%s) = %s、%sを期待", tt.kind, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+      }
+    })
+  }
+  if _, err := (&punchState{}).target("unknown", now); err == nil {
+    t.Error("不明な打刻の種類がエラーになりません")
+  }
+}
+
+func TestWritePunch(t *testing.T) {
+  yesterday := testClock("2025-04-01", "00:00")
+  working := &openSegment{Date: yesterday, Seq: 1, Start: testClock("2025-04-01", "09:00")}
+  expectPunchIn := func(manual, leave bool) func(sqlmock.Sqlmock) {
+    return func(mock sqlmock.Sqlmock) {
+      mock.ExpectQuery("FROM TBL_LEAVE").
+        WithArgs(testGeneralID, "2025-04-01", leaveUnitFullDay).
+        WillReturnRows(sqlmock.NewRows([]string{"manual", "leave"}).AddRow(manual, leave))
+    }
+  }
+  expectClockIn := func(mock sqlmock.Sqlmock) {
+    mock.ExpectQuery("SELECT attest FROM TBL_ATTEN").
+      WithArgs(testGeneralID, "2025-04-01").
+      WillReturnRows(sqlmock.NewRows([]string{"attest"}).AddRow("09:00:00"))
+  }
+
+  tests := []struct {
+    name     string
+    kind     string
+    state    punchState
+    now      time.Time
+    expect   func(sqlmock.Sqlmock)
+    wantCode string
+  }{
+    {
+      name: "出勤は勤怠と勤務の区間を登録", kind: punchIn, now: testClock("2025-04-01", "09:00"),
+      expect: func(mock sqlmock.Sqlmock) {
+        expectPunchIn(false, false)(mock)
+        mock.ExpectExec("INSERT INTO TBL_ATTEN").
+          WithArgs(testGeneralID, "2025-04-01", "09:00").
+          WillReturnResult(sqlmock.NewResult(0, 1))
+        mock.ExpectExec("INSERT INTO TBL_KUKAN").
+          WithArgs(testGeneralID, "2025-04-01", segmentKindWork, "2025-04-01 09:00:00").
+          WillReturnResult(sqlmock.NewResult(0, 1))
+      },
+    },
+    {name: "手入力の勤怠がある日の出勤", kind: punchIn, now: testClock("2025-04-01", "09:00"), expect: expectPunchIn(true, false), wantCode: errCodeManualEntry},
+    {name: "全日の休暇がある日の出勤", kind: punchIn, now: testClock("2025-04-01", "09:00"), expect: expectPunchIn(false, true), wantCode: errCodeOnLeave},
+    {
+      name: "日付をまたぐ退勤は翌日として記録", kind: punchOut, state: punchState{Work: working}, now: testClock("2025-04-02", "02:00"),
+      expect: func(mock sqlmock.Sqlmock) {
+        expectClockIn(mock)
+        mock.ExpectExec("UPDATE TBL_KUKAN").
+          WithArgs(testGeneralID, "2025-04-01", 1, "2025-04-02 02:00:00").
+          WillReturnResult(sqlmock.NewResult(0, 1))
+        mock.ExpectExec("UPDATE TBL_ATTEN").
+          WithArgs(testGeneralID, "2025-04-01", "02:00", 1).
+          WillReturnResult(sqlmock.NewResult(0, 1))
+      },
+    },
+    {
+      name: "出勤からちょうど24時間の退勤", kind: punchOut, state: punchState{Work: working}, now: testClock("2025-04-02", "09:00"),
+      expect: func(mock sqlmock.Sqlmock) {
+        expectClockIn(mock)
+        mock.ExpectExec("UPDATE TBL_KUKAN").
+          WithArgs(testGeneralID, "2025-04-01", 1, "2025-04-02 09:00:00").
+          WillReturnResult(sqlmock.NewResult(0, 1))
+        mock.ExpectExec("UPDATE TBL_ATTEN").
+          WithArgs(testGeneralID, "2025-04-01", "09:00", 1).
+          WillReturnResult(sqlmock.NewResult(0, 1))
+      },
+    },
+    {name: "出勤から24時間を超える退勤", kind: punchOut, state: punchState{Work: working}, now: testClock("2025-04-02", "09:01"), expect: expectClockIn, wantCode: errCodeMissingClockOut},
+    {
+      name: "休憩開始は休憩の区間を登録", kind: punchBreakStart, state: punchState{Work: working}, now: testClock("2025-04-01", "12:00"),
+      expect: func(mock sqlmock.Sqlmock) {
+        mock.ExpectExec("INSERT INTO TBL_KUKAN").
+          WithArgs(testGeneralID, "2025-04-01", segmentKindBreak, "2025-04-01 12:00:00").
+          WillReturnResult(sqlmock.NewResult(0, 1))
+      },
+    },
+    {
+      name: "休憩終了は休憩の区間を終了", kind: punchBreakEnd, now: testClock("2025-04-01", "13:00"),
+      state: punchState{Work: working, Break: &openSegment{Date: yesterday, Seq: 2, Start: testClock("2025-04-01", "12:00")}},
+      expect: func(mock sqlmock.Sqlmock) {
+        mock.ExpectExec("UPDATE TBL_KUKAN").
+          WithArgs(testGeneralID, "2025-04-01", 2, "2025-04-01 13:00:00").
+          WillReturnResult(sqlmock.NewResult(0, 1))
+      },
+    },
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      mock := setupMockDB(t)
+      mock.ExpectBegin()
+      tt.expect(mock)
+      tx, err := db.Begin()
+      if err != nil {
+        t.Fatal(err)
+      }
+      err = writePunch(tx, testGeneralID, tt.kind, yesterday, tt.now, &tt.state)
+      if tt.wantCode != "" {
+        perr, ok := err.(*punchError)
+        if !ok || perr.code != tt.wantCode {
+          t.Errorf("writePunch(%s) = %v、%sを期待", tt.kind, err, tt.wantCode)
+        }
+      } else if err != nil {
+        t.Error(err)
+      }
+      if err := mock.ExpectationsWereMet(); err != nil {
+        t.Error(err)
+      }
+    })
+  }
+}
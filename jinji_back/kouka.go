@@ -0,0 +1,216 @@
+package main
+
+import (
+  "database/sql"
+  "log"
+  "net/http"
+  "time"
+
+  "github.com/gin-gonic/gin"
+)
+
+// 上司・部下関係の登録リクエスト
+type ReportingLineRequest struct {
+  EmployeeID int    `json:"employeeId" binding:"required"`
+  ManagerID  int    `json:"managerId" binding:"required"`
+  StartDate  string `json:"startDate,omitempty"` // YYYY-MM-DD形式（省略時は当日）
+}
+
+// チームメンバーの人事考課
+type TeamEvaluation struct {
+  Employee   Employee    `json:"employee"`
+  Evaluation *Evaluation `json:"evaluation"` // 未入力の場合はnull
+}
+
+// 社員の当日の直属の上司を取得
+// 上司が登録されていない場合はsql.ErrNoRowsを返す
+func currentManagerID(employeeID int) (int, error) {
+  var managerID int
+  err := db.QueryRow(`
+    SELECT joshji
+    FROM TBL_JOSHI
+    WHERE joshid = $1 AND joshsd <= CURRENT_DATE AND (joshed IS NULL OR joshed > CURRENT_DATE)
+  `, employeeID).Scan(&managerID)
+  return managerID, err
+}
+
+// 上司の当日の直属の部下一覧を取得
+func getTeamMembers(managerID int) ([]Employee, error) {
+  rows, err := db.Query(`
+    SELECT e.emplid, e.emplnm, e.emplrl
+    FROM TBL_JOSHI j
+    JOIN TBL_EMPLO e ON e.emplid = j.joshid
+    WHERE j.joshji = $1 AND j.joshsd <= CURRENT_DATE AND (j.joshed IS NULL OR j.joshed > CURRENT_DATE)
+    ORDER BY e.emplid
+  `, managerID)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  members := []Employee{}
+  for rows.Next() {
+    var member Employee
+    if err := rows.Scan(&member.ID, &member.Name, &member.Role); err != nil {
+      return nil, err
+    }
+    member.IsAdmin = isAdminRole(member.Role)
+    members = append(members, member)
+  }
+  return members, rows.Err()
+}
+
+// 人事考課を1件取得（存在しない場合はsql.ErrNoRows）
+func fetchEvaluation(employeeID int, month string) (*Evaluation, error) {
+  var eval Evaluation
+  var employeeComment, managerComment sql.NullString
+  var skillScore, behaviorScore, attitudeScore sql.NullInt64
+  err := db.QueryRow(`
+    SELECT kokaid, kokamt, kokabk, kokazg, kokake, kokaty, kokajs
+    FROM TBL_KOUKA
+    WHERE kokaid = $1 AND kokamt = $2
+  `, employeeID, month).Scan(
+    &eval.EmployeeID, &eval.Month, &employeeComment,
+    &skillScore, &behaviorScore, &attitudeScore, &managerComment,
+  )
+  if err != nil {
+    return nil, err
+  }
+
+  // Null値の処理
+  eval.EmployeeComment = employeeComment.String
+  eval.ManagerComment = managerComment.String
+  if skillScore.Valid {
+    score := int(skillScore.Int64)
+    eval.SkillScore = &score
+  }
+  if behaviorScore.Valid {
+    score := int(behaviorScore.Int64)
+    eval.BehaviorScore = &score
+  }
+  if attitudeScore.Valid {
+    score := int(attitudeScore.Int64)
+    eval.AttitudeScore = &score
+  }
+  return &eval, nil
+}
+
+// 自分のチーム（直属の部下）一覧取得
+func getTeam(c *gin.Context) {
+  members, err := getTeamMembers(currentEmployeeID(c))
+  if err != nil {
+    handleDatabaseError(c, err, "チーム情報の取得に失敗しました")
+    return
+  }
+
+  c.JSON(http.StatusOK, members)
+}
+
+// チームの人事考課取得（月別）
+func getTeamEvaluations(c *gin.Context) {
+  month := c.DefaultQuery("month", time.Now().Format("200601"))
+  if len(month) != 6 {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な月形式"})
+    return
+  }
+
+  members, err := getTeamMembers(currentEmployeeID(c))
+  if err != nil {
+    handleDatabaseError(c, err, "チーム情報の取得に失敗しました")
+    return
+  }
+
+  evaluations := []TeamEvaluation{}
+  for _, member := range members {
+    eval, err := fetchEvaluation(member.ID, month)
+    if err != nil && err != sql.ErrNoRows {
+      handleDatabaseError(c, err, "人事考課データの取得に失敗しました")
+      return
+    }
+    evaluations = append(evaluations, TeamEvaluation{Employee: member, Evaluation: eval})
+  }
+
+  c.JSON(http.StatusOK, evaluations)
+}
+
+// 上司・部下関係の登録（人事のみ）
+// 現在の関係を終了日で閉じ、新しい関係を開始する
+func setReportingLine(c *gin.Context) {
+  var req ReportingLineRequest
+  if err := c.ShouldBindJSON(&req); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト"})
+    return
+  }
+
+  if req.EmployeeID == req.ManagerID {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "自分自身を上司に設定することはできません"})
+    return
+  }
+
+  startDate := time.Now().Format("2006-01-02")
+  if req.StartDate != "" {
+    if _, err := time.Parse("2006-01-02", req.StartDate); err != nil {
+      c.JSON(http.StatusBadRequest, gin.H{"error": "無効な日付形式"})
+      return
+    }
+    startDate = req.StartDate
+  }
+
+  // 上司のロールを確認
+  var managerRole int
+  err := db.QueryRow("SELECT emplrl FROM TBL_EMPLO WHERE emplid = $1", req.ManagerID).Scan(&managerRole)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      c.JSON(http.StatusBadRequest, gin.H{"error": "上司の社員IDが見つかりません"})
+    } else {
+      handleDatabaseError(c, err, "上司情報の取得に失敗しました")
+    }
+    return
+  }
+  if !isAdminRole(managerRole) {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "上司には上司または人事ロールの社員を指定してください"})
+    return
+  }
+
+  // 開始日は登録済みの最新の関係の開始日以降（終了日が開始日より前にならないように）
+  var latestStart sql.NullTime
+  err = db.QueryRow(`
+    SELECT joshsd
+    FROM TBL_JOSHI
+    WHERE joshid = $1 AND joshed IS NULL
+  `, req.EmployeeID).Scan(&latestStart)
+  if err != nil && err != sql.ErrNoRows {
+    handleDatabaseError(c, err, "上司情報の取得に失敗しました")
+    return
+  }
+  if latestStart.Valid && startDate < latestStart.Time.Format("2006-01-02") {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "開始日は現在の上司の開始日（" + latestStart.Time.Format("2006-01-02") + "）以降を指定してください"})
+    return
+  }
+
+  executeWithTransaction(c, func(tx *sql.Tx) error {
+    // 現在の関係を終了（開始日が先の場合は開始日の前日まで現在の上司が有効）
+    _, err := tx.Exec(`
+      UPDATE TBL_JOSHI
+      SET joshed = $2
+      WHERE joshid = $1 AND joshed IS NULL
+    `, req.EmployeeID, startDate)
+    if err != nil {
+      return err
+    }
+
+    // 新しい関係を登録
+    _, err = tx.Exec(`
+      INSERT INTO TBL_JOSHI (joshid, joshji, joshsd)
+      VALUES ($1, $2, $3)
+      ON CONFLICT (joshid, joshsd) DO UPDATE
+      SET joshji = $2, joshed = NULL
+    `, req.EmployeeID, req.ManagerID, startDate)
+    if err != nil {
+      return err
+    }
+
+    log.Printf("上司設定: 社員%d -> 上司%d (%s, 設定者%d)", req.EmployeeID, req.ManagerID, startDate, currentEmployeeID(c))
+    return nil
+  }, "上司を設定しました")
+}
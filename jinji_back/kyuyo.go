@@ -0,0 +1,2332 @@
+package main
+
+import (
+  "database/sql"
+  "encoding/csv"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "log"
+  "math"
+  "net/http"
+  "strconv"
+  "strings"
+  "time"
+
+  "github.com/gin-gonic/gin"
+  "github.com/lib/pq"
+)
+
+// 給与計算実行リクエスト
+type PayrollRunRequest struct {
+  Month       string `json:"month" binding:"required"` // YYYYMM形式
+  EmployeeIDs []int  `json:"employeeIds,omitempty"`    // 省略時は全社員
+  DryRun      bool   `json:"dryRun"`                   // trueの場合はTBL_SALRYに書き込まない
+}
+
+// 給与計算実行の社員毎の結果
+type PayrollResult struct {
+  EmployeeID int     `json:"employeeId"`
+  Salary     *Salary `json:"salary,omitempty"` // 計算結果（エラー時はnull）
+  Error      string  `json:"error,omitempty"`
+}
+
+// 給与計算実行（TBL_KYRUN）
+type PayrollRun struct {
+  ID         int             `json:"id"`
+  Month      string          `json:"month"`
+  DryRun     bool            `json:"dryRun"`
+  ExecutedBy int             `json:"executedBy"`
+  ExecutedAt string          `json:"executedAt"`
+  Succeeded  int             `json:"succeeded"`
+  Failed     int             `json:"failed"`
+  Results    []PayrollResult `json:"results,omitempty"`
+
+  // 住民税の特別徴収税額の通知が未登録の社員（住民税0円で計算）
+  MissingResidentTax []int `json:"missingResidentTax,omitempty"`
+
+  // 勤怠が締められていない社員（dryRun以外では計算しない）
+  AttendanceNotClosed []int `json:"attendanceNotClosed,omitempty"`
+}
+
+// 控除合計と手取り額を計算
+func (s *Salary) calculateTotals() {
+  s.TotalDeduction = s.HealthInsurance + s.NursingInsurance +
+    s.PensionInsurance + s.EmploymentInsurance +
+    s.IncomeTax + s.ResidentTax
+  s.NetSalary = s.BasicSalary + s.OvertimePay - s.TotalDeduction + s.YearEndAdjustment
+}
+
+// 給与計算関数（勤怠データから給与計算を行い、給与テーブルに登録する）
+func calculateSalary(tx *sql.Tx, employeeID int, yearMonth string) error {
+  salary, err := computeSalary(employeeID, yearMonth)
+  if err != nil {
+    return err
+  }
+  return saveSalary(tx, salary)
+}
+
+// 給与計算（計算のみでDBには書き込まない）
+func computeSalary(employeeID int, yearMonth string) (*Salary, error) {
+  // 生年月日の取得（介護保険の対象判定用）
+  var birthDate sql.NullTime
+  err := db.QueryRow("SELECT emplbd FROM TBL_EMPLO WHERE emplid = $1", employeeID).Scan(&birthDate)
+  if err != nil {
+    return nil, err
+  }
+
+  // 基本情報の取得
+  var basicSalary int
+  err = db.QueryRow(`
+    SELECT srlykh
+    FROM TBL_SALRY
+    WHERE srlyid = $1
+    ORDER BY srlymt DESC
+    LIMIT 1
+  `, employeeID).Scan(&basicSalary)
+
+  if err != nil && err != sql.ErrNoRows {
+    return nil, err
+  }
+  firstSalary := err == sql.ErrNoRows
+
+  // 対象月に有効な料率を取得
+  rates, err := loadPayrollRates(yearMonth)
+  if err != nil {
+    return nil, err
+  }
+
+  // 初回の場合はデフォルト給与を設定
+  if firstSalary {
+    basicSalary = rates.DefaultBasicSalary
+  }
+
+  // 勤怠データから労働時間を集計
+  workTime, err := summarizeWorkTime(employeeID, yearMonth)
+  if err != nil {
+    return nil, err
+  }
+
+  // 残業手当計算（基本給を1か月平均所定労働時間で割った時間単価に割増率をかける）
+  overtimePay := rates.overtimePay(basicSalary, workTime)
+
+  // 標準報酬月額の決定（登録がない場合は基本給を報酬月額とみなす）
+  remuneration, found, err := employeeRemuneration(employeeID, yearMonth)
+  if err != nil {
+    return nil, err
+  }
+  if !found {
+    remuneration = basicSalary
+  }
+  healthGrade, err := lookupRemunerationGrade(gradeKindHealth, yearMonth, remuneration)
+  if err != nil {
+    return nil, err
+  }
+  pensionGrade, err := lookupRemunerationGrade(gradeKindPension, yearMonth, remuneration)
+  if err != nil {
+    return nil, err
+  }
+
+  // 社会保険料計算（健康保険・介護保険・厚生年金は標準報酬月額、雇用保険は基本給から計算）
+  healthInsurance := int(float64(healthGrade.Standard) * rates.HealthInsuranceRate)
+  nursingInsurance := 0
+  if nursingInsuranceApplies(birthDate, yearMonth) {
+    nursingInsurance = int(float64(healthGrade.Standard) * rates.NursingInsuranceRate)
+  }
+  pensionInsurance := int(float64(pensionGrade.Standard) * rates.PensionInsuranceRate)
+  employmentInsurance := int(float64(basicSalary) * rates.EmploymentInsuranceRate)
+
+  // 所得税計算（源泉徴収税額表の月額表、社会保険料等控除後の金額から求める）
+  taxable := basicSalary + overtimePay - healthInsurance - nursingInsurance - pensionInsurance - employmentInsurance
+  incomeTax, err := monthlyWithholdingTax(employeeID, yearMonth, taxable)
+  if err == errWithholdingTableNotFound {
+    // 税額表が未登録の年は従来の累進課税で計算する
+    log.Printf("源泉徴収税額表が未登録のため累進課税で計算します [%d %s]", employeeID, yearMonth)
+    incomeTax = int(float64(basicSalary) * rates.incomeTaxRate(basicSalary*12))
+  } else if err != nil {
+    return nil, err
+  }
+
+  // 住民税（市区町村から通知された特別徴収税額）
+  residentTax, residentTaxFound, err := residentTaxForMonth(employeeID, yearMonth)
+  if err != nil {
+    return nil, err
+  }
+  if !residentTaxFound {
+    log.Printf("住民税の特別徴収税額が未登録です [%d %s]", employeeID, yearMonth)
+  }
+
+  // 年末調整の過不足額（この月に反映する場合のみ）
+  yearEndAdjustment, err := yearEndAdjustmentForMonth(employeeID, yearMonth)
+  if err != nil {
+    return nil, err
+  }
+
+  salary := &Salary{
+    EmployeeID:          employeeID,
+    Month:               yearMonth,
+    BasicSalary:         basicSalary,
+    OvertimePay:         overtimePay,
+    HealthInsurance:     healthInsurance,
+    NursingInsurance:    nursingInsurance,
+    PensionInsurance:    pensionInsurance,
+    EmploymentInsurance: employmentInsurance,
+    IncomeTax:           incomeTax,
+    ResidentTax:         residentTax,
+    YearEndAdjustment:   yearEndAdjustment,
+    ResidentTaxMissing:  !residentTaxFound,
+    WorkTime:            workTime,
+    HealthGrade:         healthGrade.Grade,
+    PensionGrade:        pensionGrade.Grade,
+  }
+  salary.calculateTotals()
+  return salary, nil
+}
+
+// 介護保険料の控除対象月かどうか（40歳以上65歳未満の第2号被保険者）
+// 年齢は誕生日の前日に加算されるため、資格取得は「40歳の誕生日の前日」が属する月から、
+// 資格喪失は「65歳の誕生日の前日」が属する月からとなる（その月は控除しない）
+// 生年月日が未登録の社員は従来どおり控除する
+func nursingInsuranceApplies(birth sql.NullTime, yearMonth string) bool {
+  if !birth.Valid {
+    return true
+  }
+  birthDate := birth.Time
+
+  target, err := time.ParseInLocation("200601", yearMonth, time.Local)
+  if err != nil {
+    return false
+  }
+
+  // 誕生日の前日が属する月の初日
+  monthReached := func(age int) time.Time {
+    day := time.Date(birthDate.Year()+age, birthDate.Month(), birthDate.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, -1)
+    return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.Local)
+  }
+
+  start := monthReached(40)
+  end := monthReached(65)
+  return !target.Before(start) && target.Before(end)
+}
+
+// 給与テーブルに登録
+func saveSalary(tx *sql.Tx, salary *Salary) error {
+  _, err := tx.Exec(`
+    INSERT INTO TBL_SALRY (
+      srlyid, srlymt, srlykh, srlyzg, srlyke, srlyka, srlyko, srlyky, srlysy, srlysz, srlync
+    ) VALUES (
+      $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+    ) ON CONFLICT (srlyid, srlymt) DO UPDATE SET
+      srlykh = $3, srlyzg = $4, srlyke = $5, srlyka = $6,
+      srlyko = $7, srlyky = $8, srlysy = $9, srlysz = $10, srlync = $11
+  `,
+    salary.EmployeeID, salary.Month, salary.BasicSalary, salary.OvertimePay,
+    salary.HealthInsurance, salary.NursingInsurance, salary.PensionInsurance,
+    salary.EmploymentInsurance, salary.IncomeTax, salary.ResidentTax,
+    salary.YearEndAdjustment)
+
+  return err
+}
+
+var errAttendanceNotClosed = errors.New("勤怠が締められていません")
+
+// 給与計算の実行（人事のみ）
+// dryRunの場合は計算結果のみ返し、TBL_SALRYには書き込まない
+// 勤怠が締められていない社員はdryRun以外では計算せず、エラーとして記録する
+// 給与（TBL_SALRY）と実行内容・社員毎の結果（TBL_KYRUN, TBL_KYRES）は1つのトランザクションで保存する
+func createPayrollRun(c *gin.Context) {
+  var req PayrollRunRequest
+  if err := c.ShouldBindJSON(&req); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト"})
+    return
+  }
+
+  if _, err := time.Parse("200601", req.Month); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な月形式"})
+    return
+  }
+
+  // 対象社員の決定（省略時は全社員）
+  employeeIDs := req.EmployeeIDs
+  if len(employeeIDs) == 0 {
+    var err error
+    employeeIDs, err = allEmployeeIDs()
+    if err != nil {
+      handleDatabaseError(c, err, "社員情報の取得に失敗しました")
+      return
+    }
+  }
+
+  run := PayrollRun{
+    Month:      req.Month,
+    DryRun:     req.DryRun,
+    ExecutedBy: currentEmployeeID(c),
+    Results:    []PayrollResult{},
+  }
+
+  for _, employeeID := range employeeIDs {
+    result := PayrollResult{EmployeeID: employeeID}
+
+    // 締められていない月の勤怠は変更される可能性があるため、dryRun以外では計算しない
+    status, err := attendanceCloseStatus(employeeID, req.Month)
+    if err == nil && status != closeStatusClosed {
+      run.AttendanceNotClosed = append(run.AttendanceNotClosed, employeeID)
+      if !req.DryRun {
+        err = errAttendanceNotClosed
+      }
+    }
+
+    var salary *Salary
+    if err == nil {
+      salary, err = computeSalary(employeeID, req.Month)
+    }
+
+    if err != nil {
+      log.Printf("給与計算エラー [%d %s]: %v", employeeID, req.Month, err)
+      result.Error = err.Error()
+      run.Failed++
+    } else {
+      result.Salary = salary
+      run.Succeeded++
+      if salary.ResidentTaxMissing {
+        run.MissingResidentTax = append(run.MissingResidentTax, employeeID)
+      }
+    }
+    run.Results = append(run.Results, result)
+  }
+
+  // 給与と実行記録を保存
+  if err := savePayrollRun(&run); err != nil {
+    log.Printf("給与計算実行記録の保存エラー: %v", err)
+    c.JSON(http.StatusInternalServerError, gin.H{"error": "給与計算実行記録の保存に失敗しました"})
+    return
+  }
+
+  c.JSON(http.StatusOK, run)
+}
+
+// 全社員の社員IDを取得
+func allEmployeeIDs() ([]int, error) {
+  rows, err := db.Query("SELECT emplid FROM TBL_EMPLO ORDER BY emplid")
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var employeeIDs []int
+  for rows.Next() {
+    var id int
+    if err := rows.Scan(&id); err != nil {
+      return nil, err
+    }
+    employeeIDs = append(employeeIDs, id)
+  }
+  return employeeIDs, rows.Err()
+}
+
+// 給与計算実行記録を保存（TBL_KYRUN, TBL_KYRES）
+// dryRun以外では計算できた社員の給与（TBL_SALRY）も同じトランザクションで保存する
+func savePayrollRun(run *PayrollRun) error {
+  tx, err := db.Begin()
+  if err != nil {
+    return err
+  }
+  defer tx.Rollback()
+
+  var executedAt time.Time
+  err = tx.QueryRow(`
+    INSERT INTO TBL_KYRUN (kyrumt, kyrudr, kyruby, kyrudt, kyruok, kyruer)
+    VALUES ($1, $2, $3, NOW(), $4, $5)
+    RETURNING kyruid, kyrudt
+  `, run.Month, run.DryRun, run.ExecutedBy, run.Succeeded, run.Failed).Scan(&run.ID, &executedAt)
+  if err != nil {
+    return err
+  }
+  run.ExecutedAt = executedAt.Format(time.RFC3339)
+
+  for _, result := range run.Results {
+    var salaryJSON sql.NullString
+    if result.Salary != nil {
+      b, err := json.Marshal(result.Salary)
+      if err != nil {
+        return err
+      }
+      salaryJSON = sql.NullString{String: string(b), Valid: true}
+    }
+
+    if result.Salary != nil && !run.DryRun {
+      if err := saveSalary(tx, result.Salary); err != nil {
+        return err
+      }
+    }
+
+    _, err := tx.Exec(`
+      INSERT INTO TBL_KYRES (kyreid, kyreem, kyresl, kyreer)
+      VALUES ($1, $2, $3, $4)
+    `, run.ID, result.EmployeeID, salaryJSON, sql.NullString{String: result.Error, Valid: result.Error != ""})
+    if err != nil {
+      return err
+    }
+  }
+
+  return tx.Commit()
+}
+
+// 給与計算実行履歴の取得（人事のみ）
+func getPayrollRuns(c *gin.Context) {
+  rows, err := db.Query(`
+    SELECT kyruid, kyrumt, kyrudr, kyruby, kyrudt, kyruok, kyruer
+    FROM TBL_KYRUN
+    ORDER BY kyruid DESC
+    LIMIT 50
+  `)
+  if err != nil {
+    handleDatabaseError(c, err, "給与計算履歴の取得に失敗しました")
+    return
+  }
+  defer rows.Close()
+
+  runs := []PayrollRun{}
+  for rows.Next() {
+    var run PayrollRun
+    var executedAt time.Time
+    err := rows.Scan(&run.ID, &run.Month, &run.DryRun, &run.ExecutedBy, &executedAt, &run.Succeeded, &run.Failed)
+    if err != nil {
+      c.JSON(http.StatusInternalServerError, gin.H{"error": "データ読み取りエラー"})
+      return
+    }
+    run.ExecutedAt = executedAt.Format(time.RFC3339)
+    runs = append(runs, run)
+  }
+
+  c.JSON(http.StatusOK, runs)
+}
+
+// 給与計算実行結果の取得（人事のみ）
+func getPayrollRun(c *gin.Context) {
+  runID, err := strconv.Atoi(c.Param("runId"))
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なID"})
+    return
+  }
+
+  var run PayrollRun
+  var executedAt time.Time
+  err = db.QueryRow(`
+    SELECT kyruid, kyrumt, kyrudr, kyruby, kyrudt, kyruok, kyruer
+    FROM TBL_KYRUN
+    WHERE kyruid = $1
+  `, runID).Scan(&run.ID, &run.Month, &run.DryRun, &run.ExecutedBy, &executedAt, &run.Succeeded, &run.Failed)
+  if err != nil {
+    handleDatabaseError(c, err, "給与計算履歴の取得に失敗しました")
+    return
+  }
+  run.ExecutedAt = executedAt.Format(time.RFC3339)
+
+  rows, err := db.Query(`
+    SELECT kyreem, kyresl, kyreer
+    FROM TBL_KYRES
+    WHERE kyreid = $1
+    ORDER BY kyreem
+  `, runID)
+  if err != nil {
+    handleDatabaseError(c, err, "給与計算結果の取得に失敗しました")
+    return
+  }
+  defer rows.Close()
+
+  run.Results = []PayrollResult{}
+  for rows.Next() {
+    var result PayrollResult
+    var salaryJSON, errorMessage sql.NullString
+    if err := rows.Scan(&result.EmployeeID, &salaryJSON, &errorMessage); err != nil {
+      c.JSON(http.StatusInternalServerError, gin.H{"error": "データ読み取りエラー"})
+      return
+    }
+    if salaryJSON.Valid {
+      var salary Salary
+      if err := json.Unmarshal([]byte(salaryJSON.String), &salary); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "データ読み取りエラー"})
+        return
+      }
+      result.Salary = &salary
+      if salary.ResidentTaxMissing {
+        run.MissingResidentTax = append(run.MissingResidentTax, result.EmployeeID)
+      }
+    }
+    result.Error = errorMessage.String
+    run.Results = append(run.Results, result)
+  }
+
+  c.JSON(http.StatusOK, run)
+}
+
+// 所得税の税率区分
+type IncomeTaxBracket struct {
+  UpTo int     `json:"upTo"` // 年収の上限（0は上限なし）
+  Rate float64 `json:"rate"`
+}
+
+// 給与計算の料率（TBL_RATESに適用開始月毎に保存する）
+type PayrollRates struct {
+  HealthInsuranceRate     float64            `json:"healthInsuranceRate"`     // 健康保険料率
+  NursingInsuranceRate    float64            `json:"nursingInsuranceRate"`    // 介護保険料率
+  PensionInsuranceRate    float64            `json:"pensionInsuranceRate"`    // 厚生年金保険料率
+  EmploymentInsuranceRate float64            `json:"employmentInsuranceRate"` // 雇用保険料率
+  IncomeTaxBrackets       []IncomeTaxBracket `json:"incomeTaxBrackets"`       // 所得税の税率区分（年収の昇順）
+  OvertimePremiumRate     float64            `json:"overtimePremiumRate"`     // 時間外労働の割増率
+  LongOvertimePremiumRate float64            `json:"longOvertimePremiumRate"` // 月60時間を超える時間外労働の割増率
+  HolidayPremiumRate      float64            `json:"holidayPremiumRate"`      // 法定休日労働の割増率
+  LateNightPremiumRate    float64            `json:"lateNightPremiumRate"`    // 深夜労働の割増率（時間外・休日労働に加算）
+  DefaultBasicSalary      int                `json:"defaultBasicSalary"`      // 給与データがない社員の基本給
+}
+
+// 料率の版
+type PayrollRateVersion struct {
+  EffectiveFrom string       `json:"effectiveFrom"` // 適用開始月YYYYMM
+  Rates         PayrollRates `json:"rates"`
+  CreatedBy     *int         `json:"createdBy,omitempty"`
+  CreatedAt     string       `json:"createdAt,omitempty"`
+}
+
+// TBL_RATESに登録がない期間に適用する料率
+var defaultPayrollRates = PayrollRates{
+  HealthInsuranceRate:     0.05,   // 健康保険料: 5%
+  NursingInsuranceRate:    0.018,  // 介護保険料: 1.8%
+  PensionInsuranceRate:    0.0915, // 厚生年金: 9.15%
+  EmploymentInsuranceRate: 0.005,  // 雇用保険: 0.5%
+  IncomeTaxBrackets: []IncomeTaxBracket{
+    {UpTo: 1950000, Rate: 0.05},
+    {UpTo: 3300000, Rate: 0.10},
+    {UpTo: 6950000, Rate: 0.20},
+    {UpTo: 9000000, Rate: 0.23},
+    {UpTo: 18000000, Rate: 0.33},
+    {UpTo: 40000000, Rate: 0.40},
+    {UpTo: 0, Rate: 0.45},
+  },
+  OvertimePremiumRate:     0.25,
+  LongOvertimePremiumRate: 0.50,
+  HolidayPremiumRate:      0.35,
+  LateNightPremiumRate:    0.25,
+  DefaultBasicSalary:      250000,
+}
+
+// 年収に対応する所得税率
+func (r *PayrollRates) incomeTaxRate(yearlyIncome int) float64 {
+  for _, bracket := range r.IncomeTaxBrackets {
+    if bracket.UpTo == 0 || yearlyIncome <= bracket.UpTo {
+      return bracket.Rate
+    }
+  }
+  return 0
+}
+
+// 残業手当の計算
+// 所定時間外・法定時間内の労働は割増なし、深夜労働は割増分のみを加算する
+func (r *PayrollRates) overtimePay(basicSalary int, workTime *WorkTimeSummary) int {
+  if workTime.ScheduledHours <= 0 {
+    return 0
+  }
+  perMinute := float64(basicSalary) / workTime.ScheduledHours / 60
+
+  pay := float64(workTime.NonStatutoryMinutes) +
+    float64(workTime.OvertimeMinutes)*(1+r.OvertimePremiumRate) +
+    float64(workTime.LongOvertimeMinutes)*(1+r.LongOvertimePremiumRate) +
+    float64(workTime.HolidayMinutes)*(1+r.HolidayPremiumRate) +
+    float64(workTime.LateNightMinutes)*r.LateNightPremiumRate
+  return int(math.Round(pay * perMinute))
+}
+
+// 料率の検証
+func (r *PayrollRates) validate() error {
+  rates := []float64{
+    r.HealthInsuranceRate, r.NursingInsuranceRate, r.PensionInsuranceRate,
+    r.EmploymentInsuranceRate, r.OvertimePremiumRate, r.LongOvertimePremiumRate,
+    r.HolidayPremiumRate, r.LateNightPremiumRate,
+  }
+  for _, rate := range rates {
+    if rate < 0 || rate >= 1 {
+      return fmt.Errorf("料率は0以上1未満で指定してください")
+    }
+  }
+  if r.DefaultBasicSalary < 0 {
+    return fmt.Errorf("金額は0以上で指定してください")
+  }
+
+  if len(r.IncomeTaxBrackets) == 0 {
+    return fmt.Errorf("所得税の税率区分は必須です")
+  }
+  previous := 0
+  for i, bracket := range r.IncomeTaxBrackets {
+    if bracket.Rate < 0 || bracket.Rate >= 1 {
+      return fmt.Errorf("所得税率は0以上1未満で指定してください")
+    }
+    last := i == len(r.IncomeTaxBrackets)-1
+    if bracket.UpTo == 0 && !last {
+      return fmt.Errorf("上限なしの税率区分は最後に指定してください")
+    }
+    if bracket.UpTo != 0 && bracket.UpTo <= previous {
+      return fmt.Errorf("所得税の税率区分は年収の昇順で指定してください")
+    }
+    previous = bracket.UpTo
+  }
+  if r.IncomeTaxBrackets[len(r.IncomeTaxBrackets)-1].UpTo != 0 {
+    return fmt.Errorf("最後の税率区分は上限なし（upTo: 0）で指定してください")
+  }
+  return nil
+}
+
+// 対象月に有効な料率を取得（適用開始月が対象月以前で最新の版）
+func loadPayrollRates(yearMonth string) (*PayrollRates, error) {
+  var ratesJSON string
+  err := db.QueryRow(`
+    SELECT ratejs
+    FROM TBL_RATES
+    WHERE ratefm <= $1
+    ORDER BY ratefm DESC
+    LIMIT 1
+  `, yearMonth).Scan(&ratesJSON)
+  if err == sql.ErrNoRows {
+    rates := defaultPayrollRates
+    return &rates, nil
+  }
+  if err != nil {
+    return nil, err
+  }
+
+  // 項目追加前に登録された版は、ない項目に初期値を使う
+  rates := defaultPayrollRates
+  rates.IncomeTaxBrackets = nil
+  if err := json.Unmarshal([]byte(ratesJSON), &rates); err != nil {
+    return nil, fmt.Errorf("料率データが不正です [%s]: %w", yearMonth, err)
+  }
+  return &rates, nil
+}
+
+// 料率の版一覧取得（人事のみ）
+func getPayrollRates(c *gin.Context) {
+  rows, err := db.Query(`
+    SELECT ratefm, ratejs, rateby, ratedt
+    FROM TBL_RATES
+    ORDER BY ratefm DESC
+  `)
+  if err != nil {
+    handleDatabaseError(c, err, "料率の取得に失敗しました")
+    return
+  }
+  defer rows.Close()
+
+  versions := []PayrollRateVersion{}
+  for rows.Next() {
+    var version PayrollRateVersion
+    var ratesJSON string
+    var createdBy sql.NullInt64
+    var createdAt time.Time
+    if err := rows.Scan(&version.EffectiveFrom, &ratesJSON, &createdBy, &createdAt); err != nil {
+      c.JSON(http.StatusInternalServerError, gin.H{"error": "データ読み取りエラー"})
+      return
+    }
+    if err := json.Unmarshal([]byte(ratesJSON), &version.Rates); err != nil {
+      c.JSON(http.StatusInternalServerError, gin.H{"error": "データ読み取りエラー"})
+      return
+    }
+    if createdBy.Valid {
+      id := int(createdBy.Int64)
+      version.CreatedBy = &id
+    }
+    version.CreatedAt = createdAt.Format(time.RFC3339)
+    versions = append(versions, version)
+  }
+
+  c.JSON(http.StatusOK, gin.H{
+    "versions": versions,
+    "default":  defaultPayrollRates,
+  })
+}
+
+// 対象月に有効な料率の取得（人事のみ）
+func getPayrollRatesForMonth(c *gin.Context) {
+  month, ok := parseMonthParam(c)
+  if !ok {
+    return
+  }
+
+  rates, err := loadPayrollRates(month)
+  if err != nil {
+    handleDatabaseError(c, err, "料率の取得に失敗しました")
+    return
+  }
+
+  c.JSON(http.StatusOK, rates)
+}
+
+// 料率の登録・予約（人事のみ）
+// 過去の給与計算結果が変わらないよう、適用開始月は当月以降に限る
+func schedulePayrollRates(c *gin.Context) {
+  var version PayrollRateVersion
+  if err := c.ShouldBindJSON(&version); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト"})
+    return
+  }
+
+  if _, err := time.Parse("200601", version.EffectiveFrom); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な月形式"})
+    return
+  }
+  if version.EffectiveFrom < time.Now().Format("200601") {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "適用開始月は当月以降を指定してください"})
+    return
+  }
+  if err := version.Rates.validate(); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    return
+  }
+
+  ratesJSON, err := json.Marshal(version.Rates)
+  if err != nil {
+    c.JSON(http.StatusInternalServerError, gin.H{"error": "料率の変換に失敗しました"})
+    return
+  }
+
+  _, err = db.Exec(`
+    INSERT INTO TBL_RATES (ratefm, ratejs, rateby, ratedt)
+    VALUES ($1, $2, $3, NOW())
+    ON CONFLICT (ratefm) DO UPDATE
+    SET ratejs = $2, rateby = $3, ratedt = NOW()
+  `, version.EffectiveFrom, string(ratesJSON), currentEmployeeID(c))
+  if err != nil {
+    handleDatabaseError(c, err, "料率の登録に失敗しました")
+    return
+  }
+
+  log.Printf("料率登録 [%s] 実行者%d", version.EffectiveFrom, currentEmployeeID(c))
+  c.JSON(http.StatusOK, gin.H{"message": "料率を登録しました"})
+}
+
+// 標準報酬月額の等級表の種類（TBL_TOKYU.tokykb）
+const (
+  gradeKindHealth  = 1 // 健康保険（介護保険を含む）
+  gradeKindPension = 2 // 厚生年金
+)
+
+// 標準報酬月額の決定理由（TBL_HYOJN.hyojrs）
+const (
+  gradeReasonAcquisition = 1 // 資格取得時決定・人事による登録
+  gradeReasonAnnual      = 2 // 定時決定（算定基礎）
+  gradeReasonRevision    = 3 // 随時改定（月額変更）
+)
+
+// 等級表の種類名
+var gradeKindMap = map[string]int{
+  "health":  gradeKindHealth,
+  "pension": gradeKindPension,
+}
+
+// 標準報酬月額の等級
+type RemunerationGrade struct {
+  Grade    int `json:"grade"`
+  Lower    int `json:"lower"`    // 報酬月額の下限（この金額以上）
+  Standard int `json:"standard"` // 標準報酬月額
+}
+
+// 等級表（等級の昇順）
+type GradeTable []RemunerationGrade
+
+// 報酬月額に対応する等級
+func (t GradeTable) lookup(remuneration int) RemunerationGrade {
+  result := t[0]
+  for _, grade := range t {
+    if remuneration >= grade.Lower {
+      result = grade
+    }
+  }
+  return result
+}
+
+// 等級表の検証
+func (t GradeTable) validate() error {
+  if len(t) == 0 {
+    return fmt.Errorf("等級は必須です")
+  }
+  for i, grade := range t {
+    if grade.Grade != i+1 {
+      return fmt.Errorf("等級は1から連番で指定してください")
+    }
+    if grade.Standard <= 0 || grade.Lower < 0 {
+      return fmt.Errorf("金額は0以上で指定してください")
+    }
+    if i > 0 && (grade.Lower <= t[i-1].Lower || grade.Standard <= t[i-1].Standard) {
+      return fmt.Errorf("下限と標準報酬月額は等級の昇順で指定してください")
+    }
+  }
+  if t[0].Lower != 0 {
+    return fmt.Errorf("1等級の下限は0で指定してください")
+  }
+  return nil
+}
+
+// 健康保険の標準報酬月額表（TBL_TOKYUに登録がない期間に使用）
+var defaultHealthGradeTable = GradeTable{
+  {1, 0, 58000}, {2, 63000, 68000}, {3, 73000, 78000}, {4, 83000, 88000},
+  {5, 93000, 98000}, {6, 101000, 104000}, {7, 107000, 110000}, {8, 114000, 118000},
+  {9, 122000, 126000}, {10, 130000, 134000}, {11, 138000, 142000}, {12, 146000, 150000},
+  {13, 155000, 160000}, {14, 165000, 170000}, {15, 175000, 180000}, {16, 185000, 190000},
+  {17, 195000, 200000}, {18, 210000, 220000}, {19, 230000, 240000}, {20, 250000, 260000},
+  {21, 270000, 280000}, {22, 290000, 300000}, {23, 310000, 320000}, {24, 330000, 340000},
+  {25, 350000, 360000}, {26, 370000, 380000}, {27, 395000, 410000}, {28, 425000, 440000},
+  {29, 455000, 470000}, {30, 485000, 500000}, {31, 515000, 530000}, {32, 545000, 560000},
+  {33, 575000, 590000}, {34, 605000, 620000}, {35, 635000, 650000}, {36, 665000, 680000},
+  {37, 695000, 710000}, {38, 730000, 750000}, {39, 770000, 790000}, {40, 810000, 830000},
+  {41, 855000, 880000}, {42, 905000, 930000}, {43, 955000, 980000}, {44, 1005000, 1030000},
+  {45, 1055000, 1090000}, {46, 1115000, 1150000}, {47, 1175000, 1210000}, {48, 1235000, 1270000},
+  {49, 1295000, 1330000}, {50, 1355000, 1390000},
+}
+
+// 厚生年金の標準報酬月額表（健康保険の4〜35等級に相当）
+var defaultPensionGradeTable = GradeTable{
+  {1, 0, 88000}, {2, 93000, 98000}, {3, 101000, 104000}, {4, 107000, 110000},
+  {5, 114000, 118000}, {6, 122000, 126000}, {7, 130000, 134000}, {8, 138000, 142000},
+  {9, 146000, 150000}, {10, 155000, 160000}, {11, 165000, 170000}, {12, 175000, 180000},
+  {13, 185000, 190000}, {14, 195000, 200000}, {15, 210000, 220000}, {16, 230000, 240000},
+  {17, 250000, 260000}, {18, 270000, 280000}, {19, 290000, 300000}, {20, 310000, 320000},
+  {21, 330000, 340000}, {22, 350000, 360000}, {23, 370000, 380000}, {24, 395000, 410000},
+  {25, 425000, 440000}, {26, 455000, 470000}, {27, 485000, 500000}, {28, 515000, 530000},
+  {29, 545000, 560000}, {30, 575000, 590000}, {31, 605000, 620000}, {32, 635000, 650000},
+}
+
+// 対象月に有効な等級表を取得（適用開始月が対象月以前で最新の版）
+func loadGradeTable(kind int, yearMonth string) (GradeTable, error) {
+  rows, err := db.Query(`
+    SELECT tokygr, tokymn, tokyhy
+    FROM TBL_TOKYU
+    WHERE tokykb = $1
+    AND tokyfm = (SELECT MAX(tokyfm) FROM TBL_TOKYU WHERE tokykb = $1 AND tokyfm <= $2)
+    ORDER BY tokygr
+  `, kind, yearMonth)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var table GradeTable
+  for rows.Next() {
+    var grade RemunerationGrade
+    if err := rows.Scan(&grade.Grade, &grade.Lower, &grade.Standard); err != nil {
+      return nil, err
+    }
+    table = append(table, grade)
+  }
+  if err := rows.Err(); err != nil {
+    return nil, err
+  }
+
+  if len(table) == 0 {
+    if kind == gradeKindPension {
+      return defaultPensionGradeTable, nil
+    }
+    return defaultHealthGradeTable, nil
+  }
+  return table, nil
+}
+
+// 報酬月額に対応する等級を対象月の等級表から取得
+func lookupRemunerationGrade(kind int, yearMonth string, remuneration int) (RemunerationGrade, error) {
+  table, err := loadGradeTable(kind, yearMonth)
+  if err != nil {
+    return RemunerationGrade{}, err
+  }
+  return table.lookup(remuneration), nil
+}
+
+// 社員の対象月に有効な報酬月額を取得（登録がない場合はfoundがfalse）
+func employeeRemuneration(employeeID int, yearMonth string) (remuneration int, found bool, err error) {
+  err = db.QueryRow(`
+    SELECT hyojrm
+    FROM TBL_HYOJN
+    WHERE hyojid = $1 AND hyojfm <= $2
+    ORDER BY hyojfm DESC
+    LIMIT 1
+  `, employeeID, yearMonth).Scan(&remuneration)
+  if err == sql.ErrNoRows {
+    return 0, false, nil
+  }
+  if err != nil {
+    return 0, false, err
+  }
+  return remuneration, true, nil
+}
+
+// 社員の報酬月額を登録（同じ適用開始月の登録は上書き）
+func saveEmployeeRemuneration(employeeID int, effectiveFrom string, remuneration int, reason int, operatorID int) error {
+  _, err := db.Exec(`
+    INSERT INTO TBL_HYOJN (hyojid, hyojfm, hyojrm, hyojrs, hyojby, hyojdt)
+    VALUES ($1, $2, $3, $4, $5, NOW())
+    ON CONFLICT (hyojid, hyojfm) DO UPDATE
+    SET hyojrm = $3, hyojrs = $4, hyojby = $5, hyojdt = NOW()
+  `, employeeID, effectiveFrom, remuneration, reason, operatorID)
+  return err
+}
+
+// 指定月の報酬（基本給＋残業手当）の平均を給与データから計算
+// 給与データがない月は除き、1か月もない場合はcountが0
+func averageRemuneration(employeeID int, months []string) (average int, count int, err error) {
+  var total sql.NullInt64
+  err = db.QueryRow(`
+    SELECT SUM(srlykh + srlyzg), COUNT(*)
+    FROM TBL_SALRY
+    WHERE srlyid = $1 AND srlymt = ANY($2)
+  `, employeeID, pq.Array(months)).Scan(&total, &count)
+  if err != nil || count == 0 {
+    return 0, count, err
+  }
+  return int(total.Int64) / count, count, nil
+}
+
+// 月（YYYYMM）に加算
+func addMonths(yearMonth string, months int) string {
+  t, err := time.Parse("200601", yearMonth)
+  if err != nil {
+    return yearMonth
+  }
+  return t.AddDate(0, months, 0).Format("200601")
+}
+
+// 社員の標準報酬月額の等級（履歴の1件分）
+type EmployeeGrade struct {
+  EffectiveFrom string            `json:"effectiveFrom"`
+  Remuneration  int               `json:"remuneration"` // 報酬月額
+  Reason        int               `json:"reason"`       // 1:資格取得・登録, 2:定時決定, 3:随時改定
+  Health        RemunerationGrade `json:"health"`
+  Pension       RemunerationGrade `json:"pension"`
+}
+
+// 報酬月額から健康保険・厚生年金の等級を求める
+func newEmployeeGrade(effectiveFrom string, remuneration int, reason int) (*EmployeeGrade, error) {
+  health, err := lookupRemunerationGrade(gradeKindHealth, effectiveFrom, remuneration)
+  if err != nil {
+    return nil, err
+  }
+  pension, err := lookupRemunerationGrade(gradeKindPension, effectiveFrom, remuneration)
+  if err != nil {
+    return nil, err
+  }
+  return &EmployeeGrade{
+    EffectiveFrom: effectiveFrom,
+    Remuneration:  remuneration,
+    Reason:        reason,
+    Health:        health,
+    Pension:       pension,
+  }, nil
+}
+
+// 等級表の取得（人事のみ）
+func getGradeTables(c *gin.Context) {
+  month, ok := parseMonthParam(c)
+  if !ok {
+    return
+  }
+
+  health, err := loadGradeTable(gradeKindHealth, month)
+  if err != nil {
+    handleDatabaseError(c, err, "等級表の取得に失敗しました")
+    return
+  }
+  pension, err := loadGradeTable(gradeKindPension, month)
+  if err != nil {
+    handleDatabaseError(c, err, "等級表の取得に失敗しました")
+    return
+  }
+
+  c.JSON(http.StatusOK, gin.H{
+    "month":   month,
+    "health":  health,
+    "pension": pension,
+  })
+}
+
+// 等級表の登録（人事のみ）
+func saveGradeTable(c *gin.Context) {
+  var req struct {
+    EffectiveFrom string     `json:"effectiveFrom" binding:"required"` // 適用開始月YYYYMM
+    Kind          string     `json:"kind" binding:"required"`          // health / pension
+    Grades        GradeTable `json:"grades" binding:"required"`
+  }
+  if err := c.ShouldBindJSON(&req); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト"})
+    return
+  }
+
+  kind, ok := gradeKindMap[req.Kind]
+  if !ok {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "kindはhealthまたはpensionを指定してください"})
+    return
+  }
+  if _, err := time.Parse("200601", req.EffectiveFrom); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な月形式"})
+    return
+  }
+  if req.EffectiveFrom < time.Now().Format("200601") {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "適用開始月は当月以降を指定してください"})
+    return
+  }
+  if err := req.Grades.validate(); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    return
+  }
+
+  executeWithTransaction(c, func(tx *sql.Tx) error {
+    _, err := tx.Exec("DELETE FROM TBL_TOKYU WHERE tokyfm = $1 AND tokykb = $2", req.EffectiveFrom, kind)
+    if err != nil {
+      return err
+    }
+    for _, grade := range req.Grades {
+      _, err := tx.Exec(`
+        INSERT INTO TBL_TOKYU (tokyfm, tokykb, tokygr, tokymn, tokyhy)
+        VALUES ($1, $2, $3, $4, $5)
+      `, req.EffectiveFrom, kind, grade.Grade, grade.Lower, grade.Standard)
+      if err != nil {
+        return err
+      }
+    }
+    return nil
+  }, "等級表を登録しました")
+}
+
+// 社員の標準報酬月額の履歴取得（人事のみ）
+func getEmployeeGrades(c *gin.Context) {
+  id, err := strconv.Atoi(c.Param("id"))
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なID"})
+    return
+  }
+
+  rows, err := db.Query(`
+    SELECT hyojfm, hyojrm, hyojrs
+    FROM TBL_HYOJN
+    WHERE hyojid = $1
+    ORDER BY hyojfm DESC
+  `, id)
+  if err != nil {
+    handleDatabaseError(c, err, "標準報酬月額の取得に失敗しました")
+    return
+  }
+
+  type gradeRow struct {
+    effectiveFrom string
+    remuneration  int
+    reason        int
+  }
+  var history []gradeRow
+  for rows.Next() {
+    var r gradeRow
+    if err := rows.Scan(&r.effectiveFrom, &r.remuneration, &r.reason); err != nil {
+      rows.Close()
+      c.JSON(http.StatusInternalServerError, gin.H{"error": "データ読み取りエラー"})
+      return
+    }
+    history = append(history, r)
+  }
+  rows.Close()
+
+  grades := []EmployeeGrade{}
+  for _, r := range history {
+    grade, err := newEmployeeGrade(r.effectiveFrom, r.remuneration, r.reason)
+    if err != nil {
+      handleDatabaseError(c, err, "等級表の取得に失敗しました")
+      return
+    }
+    grades = append(grades, *grade)
+  }
+
+  c.JSON(http.StatusOK, grades)
+}
+
+// 社員の報酬月額の登録（人事のみ、資格取得時決定など）
+func setEmployeeGrade(c *gin.Context) {
+  var req struct {
+    EmployeeID    int    `json:"employeeId" binding:"required"`
+    EffectiveFrom string `json:"effectiveFrom" binding:"required"` // 適用開始月YYYYMM
+    Remuneration  int    `json:"remuneration" binding:"required"`  // 報酬月額
+  }
+  if err := c.ShouldBindJSON(&req); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト"})
+    return
+  }
+  if _, err := time.Parse("200601", req.EffectiveFrom); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な月形式"})
+    return
+  }
+  if req.Remuneration <= 0 {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "報酬月額は1以上で指定してください"})
+    return
+  }
+
+  err := saveEmployeeRemuneration(req.EmployeeID, req.EffectiveFrom, req.Remuneration, gradeReasonAcquisition, currentEmployeeID(c))
+  if err != nil {
+    handleDatabaseError(c, err, "標準報酬月額の登録に失敗しました")
+    return
+  }
+
+  grade, err := newEmployeeGrade(req.EffectiveFrom, req.Remuneration, gradeReasonAcquisition)
+  if err != nil {
+    handleDatabaseError(c, err, "等級表の取得に失敗しました")
+    return
+  }
+  c.JSON(http.StatusOK, grade)
+}
+
+// 定時決定（算定基礎）の実行（人事のみ）
+// 4〜6月の報酬の平均から標準報酬月額を決定し、9月から適用する
+func runAnnualGradeDetermination(c *gin.Context) {
+  var req struct {
+    Year int `json:"year" binding:"required"`
+  }
+  if err := c.ShouldBindJSON(&req); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト"})
+    return
+  }
+
+  months := []string{
+    fmt.Sprintf("%d04", req.Year),
+    fmt.Sprintf("%d05", req.Year),
+    fmt.Sprintf("%d06", req.Year),
+  }
+  effectiveFrom := fmt.Sprintf("%d09", req.Year)
+
+  employeeIDs, err := allEmployeeIDs()
+  if err != nil {
+    handleDatabaseError(c, err, "社員情報の取得に失敗しました")
+    return
+  }
+
+  type annualResult struct {
+    EmployeeID int            `json:"employeeId"`
+    Grade      *EmployeeGrade `json:"grade,omitempty"`
+    Error      string         `json:"error,omitempty"`
+  }
+  results := []annualResult{}
+  for _, employeeID := range employeeIDs {
+    result := annualResult{EmployeeID: employeeID}
+
+    average, count, err := averageRemuneration(employeeID, months)
+    if err == nil && count == 0 {
+      // 4〜6月に給与がない社員は対象外
+      continue
+    }
+    if err == nil {
+      err = saveEmployeeRemuneration(employeeID, effectiveFrom, average, gradeReasonAnnual, currentEmployeeID(c))
+    }
+    if err == nil {
+      result.Grade, err = newEmployeeGrade(effectiveFrom, average, gradeReasonAnnual)
+    }
+    if err != nil {
+      log.Printf("定時決定エラー [%d %d]: %v", employeeID, req.Year, err)
+      result.Error = err.Error()
+    }
+    results = append(results, result)
+  }
+
+  c.JSON(http.StatusOK, gin.H{
+    "effectiveFrom": effectiveFrom,
+    "results":       results,
+  })
+}
+
+// 随時改定（月額変更）の判定・実行（人事のみ）
+// 固定的賃金が変動した月から3か月の報酬の平均が、現在の等級と2等級以上異なる場合に4か月目から改定する
+func runGradeRevision(c *gin.Context) {
+  var req struct {
+    EmployeeID  int    `json:"employeeId" binding:"required"`
+    ChangeMonth string `json:"changeMonth" binding:"required"` // 固定的賃金が変動した月YYYYMM
+  }
+  if err := c.ShouldBindJSON(&req); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト"})
+    return
+  }
+  if _, err := time.Parse("200601", req.ChangeMonth); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な月形式"})
+    return
+  }
+
+  months := []string{req.ChangeMonth, addMonths(req.ChangeMonth, 1), addMonths(req.ChangeMonth, 2)}
+  effectiveFrom := addMonths(req.ChangeMonth, 3)
+
+  average, count, err := averageRemuneration(req.EmployeeID, months)
+  if err != nil {
+    handleDatabaseError(c, err, "給与データの取得に失敗しました")
+    return
+  }
+  if count < len(months) {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "変動月から3か月分の給与データがありません"})
+    return
+  }
+
+  // 現在の報酬月額（登録がなければ変動前月の基本給）
+  current, found, err := employeeRemuneration(req.EmployeeID, months[2])
+  if err == nil && !found {
+    err = db.QueryRow(`
+      SELECT srlykh FROM TBL_SALRY
+      WHERE srlyid = $1 AND srlymt < $2
+      ORDER BY srlymt DESC
+      LIMIT 1
+    `, req.EmployeeID, req.ChangeMonth).Scan(&current)
+  }
+  if err != nil {
+    handleDatabaseError(c, err, "標準報酬月額の取得に失敗しました")
+    return
+  }
+
+  before, err := newEmployeeGrade(months[2], current, gradeReasonAcquisition)
+  if err != nil {
+    handleDatabaseError(c, err, "等級表の取得に失敗しました")
+    return
+  }
+  after, err := newEmployeeGrade(effectiveFrom, average, gradeReasonRevision)
+  if err != nil {
+    handleDatabaseError(c, err, "等級表の取得に失敗しました")
+    return
+  }
+
+  diff := after.Health.Grade - before.Health.Grade
+  if diff < 0 {
+    diff = -diff
+  }
+  if diff < 2 {
+    c.JSON(http.StatusOK, gin.H{"revised": false, "before": before, "after": after})
+    return
+  }
+
+  err = saveEmployeeRemuneration(req.EmployeeID, effectiveFrom, average, gradeReasonRevision, currentEmployeeID(c))
+  if err != nil {
+    handleDatabaseError(c, err, "標準報酬月額の登録に失敗しました")
+    return
+  }
+
+  c.JSON(http.StatusOK, gin.H{"revised": true, "before": before, "after": after})
+}
+
+// 源泉徴収税額表（月額表）の欄（TBL_EMPLO.emplkb）
+const (
+  withholdingColumnKou  = 1 // 甲欄（扶養控除等申告書の提出あり）
+  withholdingColumnOtsu = 2 // 乙欄（扶養控除等申告書の提出なし）
+)
+
+// 欄の名前
+var withholdingColumnMap = map[string]int{
+  "kou":  withholdingColumnKou,
+  "otsu": withholdingColumnOtsu,
+}
+
+// 税額表の甲欄に掲載されている扶養親族等の数（0〜7人）
+const withholdingMaxDependents = 7
+
+// 扶養親族等の数が7人を超える場合に1人につき控除する金額
+const withholdingExtraDependentDeduction = 1610
+
+// 税額表の最初の行未満の金額に対する乙欄の税率
+const withholdingOtsuMinimumRate = 0.03063
+
+// 乙欄で扶養親族等（従たる給与についての扶養控除等申告書に記載）1人につき控除する金額
+const withholdingOtsuDependentDeduction = 1610
+
+// 月額表で税額を計算式で求める金額（740,000円以上）
+const withholdingHighAmount = 740000
+
+// 740,000円の場合の甲欄の税額（扶養親族等の数0〜7人、令和2年分以降の月額表）
+var withholdingHighKouBase = [withholdingMaxDependents + 1]int{73390, 66950, 60500, 54060, 47610, 41170, 34730, 28290}
+
+// 740,000円以上の金額の区分（以上）毎の税額と、区分の金額を超える部分の税率（10万分率）
+// 甲欄の税額は扶養親族等0人の税額で、他の人数は740,000円の場合の税額との差額を加算する
+type withholdingBracket struct {
+  lower int
+  base  int
+  rate  int
+}
+
+var withholdingHighKouBrackets = []withholdingBracket{
+  {740000, 73390, 20420},
+  {780000, 81560, 23483},
+  {950000, 121480, 33693},
+  {1700000, 374180, 40840},
+  {2170000, 571570, 40840},
+  {2210000, 593340, 40840},
+  {2250000, 615120, 40840},
+  {3500000, 1125620, 45945},
+}
+
+var withholdingHighOtsuBrackets = []withholdingBracket{
+  {740000, 259200, 40840},
+  {3500000, 1386400, 45945},
+}
+
+var (
+  errWithholdingTableNotFound = errors.New("源泉徴収税額表が登録されていません")
+  errWithholdingOutOfRange    = errors.New("源泉徴収税額表の範囲外の金額です")
+)
+
+// 源泉徴収税額表の1行
+type WithholdingRow struct {
+  Lower int    `json:"lower"` // 社会保険料等控除後の給与等の金額（以上）
+  Upper int    `json:"upper"` // 社会保険料等控除後の給与等の金額（未満）
+  Kou   [8]int `json:"kou"`   // 甲欄（扶養親族等の数0〜7人）
+  Otsu  int    `json:"otsu"`  // 乙欄
+}
+
+// 源泉徴収税額表（金額の昇順）
+type WithholdingTable []WithholdingRow
+
+// 税額の算出
+func (t WithholdingTable) tax(amount int, column int, dependents int) (int, error) {
+  if len(t) == 0 {
+    return 0, errWithholdingTableNotFound
+  }
+
+  var tax int
+  switch {
+  case amount < t[0].Lower:
+    // 表の最初の行未満（月額88,000円未満）は甲欄0円、乙欄は金額の3.063%
+    if column == withholdingColumnOtsu {
+      tax = int(float64(amount) * withholdingOtsuMinimumRate)
+    }
+  case amount >= withholdingHighAmount:
+    // 740,000円以上は月額表の計算式（区分の金額の場合の税額に、超える金額の税率分を加算）
+    if column == withholdingColumnOtsu {
+      tax = withholdingBracketTax(withholdingHighOtsuBrackets, amount)
+    } else {
+      tax = withholdingHighKouBase[minInt(dependents, withholdingMaxDependents)] +
+        withholdingBracketTax(withholdingHighKouBrackets, amount) - withholdingHighKouBrackets[0].base
+    }
+  default:
+    row := t.find(amount)
+    if row == nil {
+      return 0, errWithholdingOutOfRange
+    }
+    if column == withholdingColumnOtsu {
+      tax = row.Otsu
+    } else {
+      tax = row.Kou[minInt(dependents, withholdingMaxDependents)]
+    }
+  }
+
+  // 甲欄で7人を超える扶養親族等は1人につき1,610円を7人の税額から、乙欄は1人につき1,610円を控除する
+  if column == withholdingColumnOtsu {
+    tax -= dependents * withholdingOtsuDependentDeduction
+  } else if dependents > withholdingMaxDependents {
+    tax -= (dependents - withholdingMaxDependents) * withholdingExtraDependentDeduction
+  }
+  return maxInt(tax, 0), nil
+}
+
+// 金額を含む行を検索（なければnil）
+func (t WithholdingTable) find(amount int) *WithholdingRow {
+  for i := range t {
+    if amount >= t[i].Lower && amount < t[i].Upper {
+      return &t[i]
+    }
+  }
+  return nil
+}
+
+// 金額の区分の税額に、区分の金額を超える部分の税率分（1円未満切捨て）を加算する
+func withholdingBracketTax(brackets []withholdingBracket, amount int) int {
+  b := brackets[0]
+  for _, bracket := range brackets {
+    if amount >= bracket.lower {
+      b = bracket
+    }
+  }
+  return b.base + (amount-b.lower)*b.rate/100000
+}
+
+// CSVから源泉徴収税額表を読み込む
+// 1行が「以上,未満,甲0人,甲1人,…,甲7人,乙」の11列。数字で始まらない行（見出し）は読み飛ばす
+func parseWithholdingCSV(r io.Reader) (WithholdingTable, error) {
+  reader := csv.NewReader(r)
+  reader.FieldsPerRecord = -1
+  reader.TrimLeadingSpace = true
+
+  var table WithholdingTable
+  line := 0
+  for {
+    record, err := reader.Read()
+    if err == io.EOF {
+      break
+    }
+    line++
+    if err != nil {
+      return nil, fmt.Errorf("%d行目: %w", line, err)
+    }
+    if len(record) == 0 || record[0] == "" || record[0][0] < '0' || record[0][0] > '9' {
+      continue
+    }
+    if len(record) != 11 {
+      return nil, fmt.Errorf("%d行目: 列数は11列で指定してください", line)
+    }
+
+    values := make([]int, len(record))
+    for i, field := range record {
+      v, err := strconv.Atoi(strings.ReplaceAll(field, ",", ""))
+      if err != nil || v < 0 {
+        return nil, fmt.Errorf("%d行目: 無効な金額です: %s", line, field)
+      }
+      values[i] = v
+    }
+
+    row := WithholdingRow{Lower: values[0], Upper: values[1], Otsu: values[10]}
+    copy(row.Kou[:], values[2:10])
+    table = append(table, row)
+  }
+
+  if err := table.validate(); err != nil {
+    return nil, err
+  }
+  return table, nil
+}
+
+// 税額表の検証（金額の区分が重複・欠落なく並んでいること）
+func (t WithholdingTable) validate() error {
+  if len(t) == 0 {
+    return fmt.Errorf("税額表の行がありません")
+  }
+  for i, row := range t {
+    if row.Upper <= row.Lower {
+      return fmt.Errorf("%d円以上の行: 未満の金額は以上の金額より大きくしてください", row.Lower)
+    }
+    if i > 0 && row.Lower != t[i-1].Upper {
+      return fmt.Errorf("%d円以上の行: 前の行の未満の金額と一致しません", row.Lower)
+    }
+  }
+  return nil
+}
+
+// 対象年に有効な源泉徴収税額表を取得（対象年以前で最新の年の表）
+func loadWithholdingTable(year string) (WithholdingTable, error) {
+  rows, err := db.Query(`
+    SELECT gensmn, gensmx, gensk0, gensk1, gensk2, gensk3, gensk4, gensk5, gensk6, gensk7, gensot
+    FROM TBL_GENSN
+    WHERE gensyr = (SELECT MAX(gensyr) FROM TBL_GENSN WHERE gensyr <= $1)
+    ORDER BY gensmn
+  `, year)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  var table WithholdingTable
+  for rows.Next() {
+    var row WithholdingRow
+    err := rows.Scan(
+      &row.Lower, &row.Upper,
+      &row.Kou[0], &row.Kou[1], &row.Kou[2], &row.Kou[3],
+      &row.Kou[4], &row.Kou[5], &row.Kou[6], &row.Kou[7],
+      &row.Otsu,
+    )
+    if err != nil {
+      return nil, err
+    }
+    table = append(table, row)
+  }
+  if err := rows.Err(); err != nil {
+    return nil, err
+  }
+  if len(table) == 0 {
+    return nil, errWithholdingTableNotFound
+  }
+  return table, nil
+}
+
+// 源泉徴収税額表を登録（同じ年の表は置き換える）
+func saveWithholdingTable(year string, table WithholdingTable) error {
+  tx, err := db.Begin()
+  if err != nil {
+    return err
+  }
+  defer tx.Rollback()
+
+  if _, err := tx.Exec("DELETE FROM TBL_GENSN WHERE gensyr = $1", year); err != nil {
+    return err
+  }
+  for _, row := range table {
+    _, err := tx.Exec(`
+      INSERT INTO TBL_GENSN (gensyr, gensmn, gensmx, gensk0, gensk1, gensk2, gensk3, gensk4, gensk5, gensk6, gensk7, gensot)
+      VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+    `, year, row.Lower, row.Upper,
+      row.Kou[0], row.Kou[1], row.Kou[2], row.Kou[3],
+      row.Kou[4], row.Kou[5], row.Kou[6], row.Kou[7],
+      row.Otsu)
+    if err != nil {
+      return err
+    }
+  }
+  return tx.Commit()
+}
+
+// CSVファイルから源泉徴収税額表を登録
+func importWithholdingTable(year string, r io.Reader) (int, error) {
+  if _, err := time.Parse("2006", year); err != nil {
+    return 0, fmt.Errorf("無効な年形式: %s", year)
+  }
+  table, err := parseWithholdingCSV(r)
+  if err != nil {
+    return 0, err
+  }
+  if err := saveWithholdingTable(year, table); err != nil {
+    return 0, err
+  }
+  return len(table), nil
+}
+
+// 社員の月額の源泉徴収税額を計算
+func monthlyWithholdingTax(employeeID int, yearMonth string, taxable int) (int, error) {
+  var column, dependents int
+  err := db.QueryRow("SELECT emplkb, emplfy FROM TBL_EMPLO WHERE emplid = $1", employeeID).Scan(&column, &dependents)
+  if err != nil {
+    return 0, err
+  }
+
+  table, err := loadWithholdingTable(yearMonth[:4])
+  if err != nil {
+    return 0, err
+  }
+  return table.tax(taxable, column, dependents)
+}
+
+// 源泉徴収税額表の取得（人事のみ）
+func getWithholdingTable(c *gin.Context) {
+  year := c.Param("year")
+  if _, err := time.Parse("2006", year); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な年形式"})
+    return
+  }
+
+  table, err := loadWithholdingTable(year)
+  if err == errWithholdingTableNotFound {
+    c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+    return
+  }
+  if err != nil {
+    handleDatabaseError(c, err, "源泉徴収税額表の取得に失敗しました")
+    return
+  }
+
+  c.JSON(http.StatusOK, gin.H{"year": year, "rows": table})
+}
+
+// 源泉徴収税額表のCSV登録（人事のみ）
+// リクエストボディにCSVをそのまま送る
+func uploadWithholdingTable(c *gin.Context) {
+  year := c.Param("year")
+  count, err := importWithholdingTable(year, c.Request.Body)
+  if err != nil {
+    log.Printf("源泉徴収税額表登録エラー [%s]: %v", year, err)
+    c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    return
+  }
+
+  log.Printf("源泉徴収税額表登録 [%s] %d行 実行者%d", year, count, currentEmployeeID(c))
+  c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("源泉徴収税額表を登録しました（%d行）", count)})
+}
+
+// 社員の源泉徴収の区分（甲欄・乙欄、扶養親族等の数）の登録（人事のみ）
+func setWithholdingProfile(c *gin.Context) {
+  var req struct {
+    EmployeeID int    `json:"employeeId" binding:"required"`
+    Column     string `json:"column" binding:"required"` // kou / otsu
+    Dependents int    `json:"dependents"`                // 扶養親族等の数
+  }
+  if err := c.ShouldBindJSON(&req); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト"})
+    return
+  }
+
+  column, ok := withholdingColumnMap[req.Column]
+  if !ok {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "columnはkouまたはotsuを指定してください"})
+    return
+  }
+  if req.Dependents < 0 {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "扶養親族等の数は0以上で指定してください"})
+    return
+  }
+
+  result, err := db.Exec(`
+    UPDATE TBL_EMPLO
+    SET emplkb = $2, emplfy = $3
+    WHERE emplid = $1
+  `, req.EmployeeID, column, req.Dependents)
+  if err != nil {
+    handleDatabaseError(c, err, "源泉徴収の区分の登録に失敗しました")
+    return
+  }
+  if n, _ := result.RowsAffected(); n == 0 {
+    c.JSON(http.StatusNotFound, gin.H{"error": "社員が見つかりません"})
+    return
+  }
+
+  c.JSON(http.StatusOK, gin.H{"message": "源泉徴収の区分を登録しました"})
+}
+
+// 年末調整の控除額・税率（令和2年分以降）
+const (
+  lifeInsuranceDeductionCap    = 40000  // 生命保険料控除（区分毎の上限）
+  lifeInsuranceDeductionTotal  = 120000 // 生命保険料控除（合計の上限）
+  earthquakeInsuranceCap       = 50000  // 地震保険料控除の上限
+  generalDependentDeduction    = 380000 // 一般の控除対象扶養親族
+  specificDependentDeduction   = 630000 // 特定扶養親族（19歳以上23歳未満）
+  elderlyDependentDeduction    = 480000 // 老人扶養親族
+  cohabitingParentDeduction    = 580000 // 同居老親等
+  spouseIncomeLimit            = 480000 // 控除対象配偶者の合計所得金額の上限
+  housingLoanCreditRate        = 0.007  // 住宅借入金等特別控除の控除率
+  housingLoanCreditCap         = 210000 // 住宅借入金等特別控除の上限
+  reconstructionSpecialTaxRate = 1.021  // 復興特別所得税を含む税率
+)
+
+// 年末調整の申告内容（社員が申告する控除）
+type YearEndDeclaration struct {
+  LifeInsurancePremium    int  `json:"lifeInsurancePremium"`    // 一般生命保険料
+  MedicalInsurancePremium int  `json:"medicalInsurancePremium"` // 介護医療保険料
+  PensionInsurancePremium int  `json:"pensionInsurancePremium"` // 個人年金保険料
+  EarthquakePremium       int  `json:"earthquakePremium"`       // 地震保険料
+  SocialInsurancePaid     int  `json:"socialInsurancePaid"`     // 本人が直接支払った社会保険料（国民年金など）
+  HasSpouse               bool `json:"hasSpouse"`               // 配偶者（控除対象配偶者または配偶者特別控除の対象）の有無
+  SpouseIncome            int  `json:"spouseIncome"`            // 配偶者の合計所得金額
+  SpouseElderly           bool `json:"spouseElderly"`           // 配偶者が70歳以上
+  GeneralDependents       int  `json:"generalDependents"`       // 一般の控除対象扶養親族の数
+  SpecificDependents      int  `json:"specificDependents"`      // 特定扶養親族の数
+  ElderlyDependents       int  `json:"elderlyDependents"`       // 老人扶養親族の数（同居老親等以外）
+  CohabitingParents       int  `json:"cohabitingParents"`       // 同居老親等の数
+  HousingLoanBalance      int  `json:"housingLoanBalance"`      // 住宅借入金等の年末残高
+}
+
+// 申告内容の検証
+func (d *YearEndDeclaration) validate() error {
+  values := []int{
+    d.LifeInsurancePremium, d.MedicalInsurancePremium, d.PensionInsurancePremium,
+    d.EarthquakePremium, d.SocialInsurancePaid, d.SpouseIncome,
+    d.GeneralDependents, d.SpecificDependents, d.ElderlyDependents, d.CohabitingParents,
+    d.HousingLoanBalance,
+  }
+  for _, v := range values {
+    if v < 0 {
+      return fmt.Errorf("金額・人数は0以上で指定してください")
+    }
+  }
+  return nil
+}
+
+// 年末調整の計算結果
+type YearEndAdjustment struct {
+  EmployeeID          int    `json:"employeeId"`
+  Year                string `json:"year"`
+  ApplyMonth          string `json:"applyMonth"`          // 過不足額を反映する給与の月
+  Income              int    `json:"income"`              // 給与の支払金額
+  EmploymentIncome    int    `json:"employmentIncome"`    // 給与所得控除後の金額
+  SocialInsurance     int    `json:"socialInsurance"`     // 社会保険料控除
+  LifeInsurance       int    `json:"lifeInsurance"`       // 生命保険料控除
+  EarthquakeInsurance int    `json:"earthquakeInsurance"` // 地震保険料控除
+  SpouseDeduction     int    `json:"spouseDeduction"`     // 配偶者控除・配偶者特別控除
+  DependentDeduction  int    `json:"dependentDeduction"`  // 扶養控除
+  BasicDeduction      int    `json:"basicDeduction"`      // 基礎控除
+  TaxableIncome       int    `json:"taxableIncome"`       // 課税給与所得金額（千円未満切り捨て）
+  CalculatedTax       int    `json:"calculatedTax"`       // 算出所得税額
+  HousingLoanCredit   int    `json:"housingLoanCredit"`   // 住宅借入金等特別控除額
+  AnnualTax           int    `json:"annualTax"`           // 年調年税額（復興特別所得税を含む、百円未満切り捨て）
+  Withheld            int    `json:"withheld"`            // 源泉徴収済みの税額
+  Difference          int    `json:"difference"`          // 過不足額（プラスは還付、マイナスは追加徴収）
+}
+
+// 年末調整の実行リクエスト
+type YearEndRunRequest struct {
+  EmployeeIDs []int  `json:"employeeIds"` // 省略時は全社員
+  ApplyMonth  string `json:"applyMonth"`  // 反映する給与の月（12: 当年12月, 01: 翌年1月、省略時は12）
+  DryRun      bool   `json:"dryRun"`
+}
+
+// 年末調整の社員毎の結果
+type YearEndResult struct {
+  EmployeeID int                `json:"employeeId"`
+  Adjustment *YearEndAdjustment `json:"adjustment,omitempty"`
+  Error      string             `json:"error,omitempty"`
+}
+
+// 給与所得控除後の給与等の金額
+// 660万円未満は年末調整用の表にあわせて4,000円単位で計算する
+func employmentIncomeAfterDeduction(income int) int {
+  switch {
+  case income < 551000:
+    return 0
+  case income < 1625000:
+    return income - 550000
+  case income < 6600000:
+    a := income / 4000 * 4000
+    switch {
+    case income < 1800000:
+      return a - (a*40/100 - 100000)
+    case income < 3600000:
+      return a - (a*30/100 + 80000)
+    default:
+      return a - (a*20/100 + 440000)
+    }
+  case income < 8500000:
+    return income - (income*10/100 + 1100000)
+  }
+  return income - 1950000
+}
+
+// 生命保険料控除（新制度、区分毎）
+func lifeInsuranceDeduction(premium int) int {
+  switch {
+  case premium <= 20000:
+    return premium
+  case premium <= 40000:
+    return premium/2 + 10000
+  case premium <= 80000:
+    return premium/4 + 20000
+  }
+  return lifeInsuranceDeductionCap
+}
+
+// 基礎控除（合計所得金額に応じて逓減）
+func basicDeduction(totalIncome int) int {
+  switch {
+  case totalIncome <= 24000000:
+    return 480000
+  case totalIncome <= 24500000:
+    return 320000
+  case totalIncome <= 25000000:
+    return 160000
+  }
+  return 0
+}
+
+// 配偶者特別控除の控除額（配偶者の合計所得金額の上限毎、本人の合計所得金額900万円以下/950万円以下/1,000万円以下）
+var spouseSpecialDeductionBrackets = []struct {
+  upTo    int
+  amounts [3]int
+}{
+  {950000, [3]int{380000, 260000, 130000}},
+  {1000000, [3]int{360000, 240000, 120000}},
+  {1050000, [3]int{310000, 210000, 110000}},
+  {1100000, [3]int{260000, 180000, 90000}},
+  {1150000, [3]int{210000, 140000, 70000}},
+  {1200000, [3]int{160000, 110000, 60000}},
+  {1250000, [3]int{110000, 80000, 40000}},
+  {1300000, [3]int{60000, 40000, 20000}},
+  {1330000, [3]int{30000, 20000, 10000}},
+}
+
+// 配偶者控除・配偶者特別控除（本人の合計所得金額に応じて逓減、1,000万円超は対象外）
+// 配偶者の合計所得金額が48万円超133万円以下の場合は配偶者特別控除、133万円超は対象外
+func spouseDeduction(d *YearEndDeclaration, totalIncome int) int {
+  if !d.HasSpouse {
+    return 0
+  }
+  var column int
+  switch {
+  case totalIncome <= 9000000:
+    column = 0
+  case totalIncome <= 9500000:
+    column = 1
+  case totalIncome <= 10000000:
+    column = 2
+  default:
+    return 0
+  }
+
+  if d.SpouseIncome <= spouseIncomeLimit {
+    amounts := [3]int{380000, 260000, 130000}
+    if d.SpouseElderly {
+      amounts = [3]int{480000, 320000, 160000}
+    }
+    return amounts[column]
+  }
+  for _, b := range spouseSpecialDeductionBrackets {
+    if d.SpouseIncome <= b.upTo {
+      return b.amounts[column]
+    }
+  }
+  return 0
+}
+
+// 算出所得税額（速算表）
+func annualIncomeTax(taxable int) int {
+  brackets := []struct {
+    upTo      int
+    rate      int // %
+    deduction int
+  }{
+    {1950000, 5, 0},
+    {3300000, 10, 97500},
+    {6950000, 20, 427500},
+    {9000000, 23, 636000},
+    {18000000, 33, 1536000},
+    {40000000, 40, 2796000},
+  }
+  for _, b := range brackets {
+    if taxable <= b.upTo {
+      return taxable*b.rate/100 - b.deduction
+    }
+  }
+  return taxable*45/100 - 4796000
+}
+
+// 年末調整の計算
+func computeYearEndAdjustment(employeeID int, year string, applyMonth string, d *YearEndDeclaration) (*YearEndAdjustment, error) {
+  // 1年分の給与・社会保険料・源泉徴収税額を集計
+  adj := &YearEndAdjustment{EmployeeID: employeeID, Year: year, ApplyMonth: applyMonth}
+  var count int
+  err := db.QueryRow(`
+    SELECT COUNT(*),
+      COALESCE(SUM(srlykh + srlyzg), 0),
+      COALESCE(SUM(srlyke + srlyka + srlyko + srlyky), 0),
+      COALESCE(SUM(srlysy), 0)
+    FROM TBL_SALRY
+    WHERE srlyid = $1 AND srlymt BETWEEN $2 AND $3
+  `, employeeID, year+"01", year+"12").Scan(&count, &adj.Income, &adj.SocialInsurance, &adj.Withheld)
+  if err != nil {
+    return nil, err
+  }
+  if count == 0 {
+    return nil, fmt.Errorf("%s年の給与データがありません", year)
+  }
+
+  // 所得控除
+  adj.EmploymentIncome = employmentIncomeAfterDeduction(adj.Income)
+  adj.SocialInsurance += d.SocialInsurancePaid
+  adj.LifeInsurance = lifeInsuranceDeduction(d.LifeInsurancePremium) +
+    lifeInsuranceDeduction(d.MedicalInsurancePremium) +
+    lifeInsuranceDeduction(d.PensionInsurancePremium)
+  if adj.LifeInsurance > lifeInsuranceDeductionTotal {
+    adj.LifeInsurance = lifeInsuranceDeductionTotal
+  }
+  adj.EarthquakeInsurance = d.EarthquakePremium
+  if adj.EarthquakeInsurance > earthquakeInsuranceCap {
+    adj.EarthquakeInsurance = earthquakeInsuranceCap
+  }
+  adj.SpouseDeduction = spouseDeduction(d, adj.EmploymentIncome)
+  adj.DependentDeduction = d.GeneralDependents*generalDependentDeduction +
+    d.SpecificDependents*specificDependentDeduction +
+    d.ElderlyDependents*elderlyDependentDeduction +
+    d.CohabitingParents*cohabitingParentDeduction
+  adj.BasicDeduction = basicDeduction(adj.EmploymentIncome)
+
+  deductions := adj.SocialInsurance + adj.LifeInsurance + adj.EarthquakeInsurance +
+    adj.SpouseDeduction + adj.DependentDeduction + adj.BasicDeduction
+  adj.TaxableIncome = (adj.EmploymentIncome - deductions) / 1000 * 1000
+  if adj.TaxableIncome < 0 {
+    adj.TaxableIncome = 0
+  }
+
+  // 税額控除（住宅借入金等特別控除は算出税額が上限）
+  adj.CalculatedTax = annualIncomeTax(adj.TaxableIncome)
+  adj.HousingLoanCredit = int(float64(d.HousingLoanBalance) * housingLoanCreditRate)
+  if adj.HousingLoanCredit > housingLoanCreditCap {
+    adj.HousingLoanCredit = housingLoanCreditCap
+  }
+  if adj.HousingLoanCredit > adj.CalculatedTax {
+    adj.HousingLoanCredit = adj.CalculatedTax
+  }
+
+  adj.AnnualTax = int(float64(adj.CalculatedTax-adj.HousingLoanCredit)*reconstructionSpecialTaxRate) / 100 * 100
+  adj.Difference = adj.Withheld - adj.AnnualTax
+  return adj, nil
+}
+
+// 年末調整の申告内容を取得（未申告の場合は空の申告）
+func fetchYearEndDeclaration(employeeID int, year string) (*YearEndDeclaration, bool, error) {
+  var raw []byte
+  err := db.QueryRow("SELECT nensjs FROM TBL_NENSH WHERE nensid = $1 AND nensyr = $2", employeeID, year).Scan(&raw)
+  if err == sql.ErrNoRows {
+    return &YearEndDeclaration{}, false, nil
+  }
+  if err != nil {
+    return nil, false, err
+  }
+
+  var d YearEndDeclaration
+  if err := json.Unmarshal(raw, &d); err != nil {
+    return nil, false, err
+  }
+  return &d, true, nil
+}
+
+// 確定済みの年末調整の結果を取得（未実行の場合はnil）
+func fetchYearEndAdjustment(employeeID int, year string) (*YearEndAdjustment, error) {
+  var raw []byte
+  err := db.QueryRow("SELECT nencjs FROM TBL_NENCH WHERE nencid = $1 AND nencyr = $2", employeeID, year).Scan(&raw)
+  if err == sql.ErrNoRows {
+    return nil, nil
+  }
+  if err != nil {
+    return nil, err
+  }
+
+  var adj YearEndAdjustment
+  if err := json.Unmarshal(raw, &adj); err != nil {
+    return nil, err
+  }
+  return &adj, nil
+}
+
+// 給与計算の対象月に反映する年末調整の過不足額
+func yearEndAdjustmentForMonth(employeeID int, yearMonth string) (int, error) {
+  var difference int
+  err := db.QueryRow(`
+    SELECT nencgk
+    FROM TBL_NENCH
+    WHERE nencid = $1 AND nencmt = $2
+  `, employeeID, yearMonth).Scan(&difference)
+  if err == sql.ErrNoRows {
+    return 0, nil
+  }
+  return difference, err
+}
+
+// 年末調整の結果を確定し、反映月の給与データがあれば過不足額を書き込む
+func saveYearEndAdjustment(adj *YearEndAdjustment, operatorID int) error {
+  raw, err := json.Marshal(adj)
+  if err != nil {
+    return err
+  }
+
+  tx, err := db.Begin()
+  if err != nil {
+    return err
+  }
+  defer tx.Rollback()
+
+  // 反映月を変更して再実行した場合に備え、前回の反映先を取り消す
+  _, err = tx.Exec(`
+    UPDATE TBL_SALRY
+    SET srlync = 0
+    WHERE srlyid = $1
+    AND srlymt = (SELECT nencmt FROM TBL_NENCH WHERE nencid = $1 AND nencyr = $2)
+  `, adj.EmployeeID, adj.Year)
+  if err != nil {
+    return err
+  }
+
+  _, err = tx.Exec(`
+    INSERT INTO TBL_NENCH (nencid, nencyr, nencmt, nencgk, nencjs, nencby, nencdt)
+    VALUES ($1, $2, $3, $4, $5, $6, NOW())
+    ON CONFLICT (nencid, nencyr) DO UPDATE
+    SET nencmt = $3, nencgk = $4, nencjs = $5, nencby = $6, nencdt = NOW()
+  `, adj.EmployeeID, adj.Year, adj.ApplyMonth, adj.Difference, raw, operatorID)
+  if err != nil {
+    return err
+  }
+
+  _, err = tx.Exec(`
+    UPDATE TBL_SALRY
+    SET srlync = $3
+    WHERE srlyid = $1 AND srlymt = $2
+  `, adj.EmployeeID, adj.ApplyMonth, adj.Difference)
+  if err != nil {
+    return err
+  }
+
+  return tx.Commit()
+}
+
+// URLパラメータの社員ID・年を取得（不正な場合は400を返してfalse）
+func parseYearEndParams(c *gin.Context) (int, string, bool) {
+  id, err := strconv.Atoi(c.Param("id"))
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なID"})
+    return 0, "", false
+  }
+  year := c.Param("year")
+  if _, err := time.Parse("2006", year); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な年形式"})
+    return 0, "", false
+  }
+  return id, year, true
+}
+
+// 年末調整の申告内容・結果の取得
+func getYearEnd(c *gin.Context) {
+  id, year, ok := parseYearEndParams(c)
+  if !ok {
+    return
+  }
+
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, id) {
+    return
+  }
+
+  declaration, submitted, err := fetchYearEndDeclaration(id, year)
+  if err != nil {
+    handleDatabaseError(c, err, "年末調整の申告内容の取得に失敗しました")
+    return
+  }
+  adjustment, err := fetchYearEndAdjustment(id, year)
+  if err != nil {
+    handleDatabaseError(c, err, "年末調整の結果の取得に失敗しました")
+    return
+  }
+
+  c.JSON(http.StatusOK, gin.H{
+    "employeeId":  id,
+    "year":        year,
+    "submitted":   submitted,
+    "declaration": declaration,
+    "adjustment":  adjustment, // 未実行の場合はnull
+  })
+}
+
+// 年末調整の申告（本人または人事）
+// 年末調整の実行後は変更できない
+func submitYearEndDeclaration(c *gin.Context) {
+  id, year, ok := parseYearEndParams(c)
+  if !ok {
+    return
+  }
+
+  if currentEmployeeID(c) != id && currentRole(c) != roleHR {
+    c.JSON(http.StatusForbidden, gin.H{"error": "年末調整の申告は本人または人事のみ可能です"})
+    return
+  }
+
+  var d YearEndDeclaration
+  if err := c.ShouldBindJSON(&d); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト"})
+    return
+  }
+  if err := d.validate(); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    return
+  }
+
+  adjustment, err := fetchYearEndAdjustment(id, year)
+  if err != nil {
+    handleDatabaseError(c, err, "年末調整の結果の取得に失敗しました")
+    return
+  }
+  if adjustment != nil {
+    c.JSON(http.StatusConflict, gin.H{"error": "年末調整が実行済みのため申告内容を変更できません"})
+    return
+  }
+
+  raw, err := json.Marshal(d)
+  if err != nil {
+    c.JSON(http.StatusInternalServerError, gin.H{"error": "申告内容の変換に失敗しました"})
+    return
+  }
+
+  _, err = db.Exec(`
+    INSERT INTO TBL_NENSH (nensid, nensyr, nensjs, nensdt)
+    VALUES ($1, $2, $3, NOW())
+    ON CONFLICT (nensid, nensyr) DO UPDATE
+    SET nensjs = $3, nensdt = NOW()
+  `, id, year, raw)
+  if err != nil {
+    handleDatabaseError(c, err, "年末調整の申告に失敗しました")
+    return
+  }
+
+  c.JSON(http.StatusOK, gin.H{"message": "年末調整の申告を登録しました"})
+}
+
+// 年末調整の実行（人事のみ）
+// 12月・翌年1月のどちらに反映する場合も12月の給与計算後に実行する。dryRunの場合は結果を保存しない
+func runYearEndAdjustment(c *gin.Context) {
+  year := c.Param("year")
+  if _, err := time.Parse("2006", year); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な年形式"})
+    return
+  }
+
+  var req YearEndRunRequest
+  if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエスト"})
+    return
+  }
+
+  var applyMonth string
+  switch req.ApplyMonth {
+  case "", "12":
+    applyMonth = year + "12"
+  case "01":
+    applyMonth = addMonths(year+"12", 1)
+  default:
+    c.JSON(http.StatusBadRequest, gin.H{"error": "applyMonthは12または01を指定してください"})
+    return
+  }
+
+  employeeIDs := req.EmployeeIDs
+  if len(employeeIDs) == 0 {
+    var err error
+    employeeIDs, err = allEmployeeIDs()
+    if err != nil {
+      handleDatabaseError(c, err, "社員情報の取得に失敗しました")
+      return
+    }
+  }
+
+  results := []YearEndResult{}
+  for _, employeeID := range employeeIDs {
+    result := YearEndResult{EmployeeID: employeeID}
+
+    declaration, _, err := fetchYearEndDeclaration(employeeID, year)
+    if err == nil {
+      // 12月分の給与・源泉徴収税額を含めて精算するため、反映月にかかわらず12月の給与データが必要
+      var exists bool
+      err = db.QueryRow(`
+        SELECT EXISTS (SELECT 1 FROM TBL_SALRY WHERE srlyid = $1 AND srlymt = $2)
+      `, employeeID, year+"12").Scan(&exists)
+      if err == nil && !exists {
+        err = errors.New("12月の給与計算後に実行してください")
+      }
+    }
+    if err == nil {
+      result.Adjustment, err = computeYearEndAdjustment(employeeID, year, applyMonth, declaration)
+    }
+    if err == nil && !req.DryRun {
+      err = saveYearEndAdjustment(result.Adjustment, currentEmployeeID(c))
+    }
+    if err != nil {
+      log.Printf("年末調整エラー [%d %s]: %v", employeeID, year, err)
+      result.Adjustment = nil
+      result.Error = err.Error()
+    }
+    results = append(results, result)
+  }
+
+  c.JSON(http.StatusOK, gin.H{
+    "year":       year,
+    "applyMonth": applyMonth,
+    "dryRun":     req.DryRun,
+    "results":    results,
+  })
+}
+
+// 年度の12か月（6月から翌年5月まで）
+func residentTaxMonths(year string) []string {
+  months := make([]string, 12)
+  for i := range months {
+    months[i] = addMonths(year+"06", i)
+  }
+  return months
+}
+
+// 対象月の住民税の特別徴収税額を取得（通知が未登録の場合はfoundがfalse）
+func residentTaxForMonth(employeeID int, yearMonth string) (amount int, found bool, err error) {
+  err = db.QueryRow(`
+    SELECT jumigk
+    FROM TBL_JUMIN
+    WHERE jumiid = $1 AND jumimt = $2
+  `, employeeID, yearMonth).Scan(&amount)
+  if err == sql.ErrNoRows {
+    return 0, false, nil
+  }
+  if err != nil {
+    return 0, false, err
+  }
+  return amount, true, nil
+}
+
+// 社員1人分の住民税の特別徴収税額（6月から翌年5月までの12か月分）
+type ResidentTaxSchedule struct {
+  EmployeeID int     `json:"employeeId"`
+  Amounts    [12]int `json:"amounts"` // 6月, 7月, …, 翌年5月
+}
+
+// CSVから住民税の特別徴収税額を読み込む
+// 1行が「社員ID,6月,7月,…,翌年5月」の13列。数字で始まらない行（見出し）は読み飛ばす
+func parseResidentTaxCSV(r io.Reader) ([]ResidentTaxSchedule, error) {
+  reader := csv.NewReader(r)
+  reader.FieldsPerRecord = -1
+  reader.TrimLeadingSpace = true
+
+  var schedules []ResidentTaxSchedule
+  seen := map[int]bool{}
+  line := 0
+  for {
+    record, err := reader.Read()
+    if err == io.EOF {
+      break
+    }
+    line++
+    if err != nil {
+      return nil, fmt.Errorf("%d行目: %w", line, err)
+    }
+    if len(record) == 0 || record[0] == "" || record[0][0] < '0' || record[0][0] > '9' {
+      continue
+    }
+    if len(record) != 13 {
+      return nil, fmt.Errorf("%d行目: 列数は13列で指定してください", line)
+    }
+
+    values := make([]int, len(record))
+    for i, field := range record {
+      v, err := strconv.Atoi(strings.ReplaceAll(field, ",", ""))
+      if err != nil || v < 0 {
+        return nil, fmt.Errorf("%d行目: 無効な値です: %s", line, field)
+      }
+      values[i] = v
+    }
+    if seen[values[0]] {
+      return nil, fmt.Errorf("%d行目: 社員ID %d が重複しています", line, values[0])
+    }
+    seen[values[0]] = true
+
+    schedule := ResidentTaxSchedule{EmployeeID: values[0]}
+    copy(schedule.Amounts[:], values[1:])
+    schedules = append(schedules, schedule)
+  }
+
+  if len(schedules) == 0 {
+    return nil, fmt.Errorf("住民税の特別徴収税額の行がありません")
+  }
+  return schedules, nil
+}
+
+// 年度の住民税の特別徴収税額を登録（CSVに含まれる社員の同じ年度の登録は置き換える）
+func saveResidentTaxSchedules(year string, schedules []ResidentTaxSchedule, operatorID int) error {
+  months := residentTaxMonths(year)
+
+  tx, err := db.Begin()
+  if err != nil {
+    return err
+  }
+  defer tx.Rollback()
+
+  for _, schedule := range schedules {
+    _, err := tx.Exec("DELETE FROM TBL_JUMIN WHERE jumiid = $1 AND jumiyr = $2", schedule.EmployeeID, year)
+    if err != nil {
+      return err
+    }
+    for i, amount := range schedule.Amounts {
+      _, err := tx.Exec(`
+        INSERT INTO TBL_JUMIN (jumiid, jumimt, jumiyr, jumigk, jumiby, jumidt)
+        VALUES ($1, $2, $3, $4, $5, NOW())
+      `, schedule.EmployeeID, months[i], year, amount, operatorID)
+      if err != nil {
+        return err
+      }
+    }
+  }
+  return tx.Commit()
+}
+
+// 年度の住民税の特別徴収税額の一覧を取得
+func fetchResidentTaxSchedules(year string) ([]ResidentTaxSchedule, error) {
+  months := residentTaxMonths(year)
+  index := map[string]int{}
+  for i, month := range months {
+    index[month] = i
+  }
+
+  rows, err := db.Query(`
+    SELECT jumiid, jumimt, jumigk
+    FROM TBL_JUMIN
+    WHERE jumiyr = $1
+    ORDER BY jumiid, jumimt
+  `, year)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  schedules := []ResidentTaxSchedule{}
+  for rows.Next() {
+    var employeeID, amount int
+    var month string
+    if err := rows.Scan(&employeeID, &month, &amount); err != nil {
+      return nil, err
+    }
+    if len(schedules) == 0 || schedules[len(schedules)-1].EmployeeID != employeeID {
+      schedules = append(schedules, ResidentTaxSchedule{EmployeeID: employeeID})
+    }
+    schedules[len(schedules)-1].Amounts[index[month]] = amount
+  }
+  return schedules, rows.Err()
+}
+
+// 年度の住民税の特別徴収税額の通知が未登録の社員IDを取得
+func fetchMissingResidentTax(year string) ([]int, error) {
+  rows, err := db.Query(`
+    SELECT e.emplid
+    FROM TBL_EMPLO e
+    WHERE NOT EXISTS (
+      SELECT 1 FROM TBL_JUMIN j WHERE j.jumiid = e.emplid AND j.jumiyr = $1
+    )
+    ORDER BY e.emplid
+  `, year)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+
+  missing := []int{}
+  for rows.Next() {
+    var id int
+    if err := rows.Scan(&id); err != nil {
+      return nil, err
+    }
+    missing = append(missing, id)
+  }
+  return missing, rows.Err()
+}
+
+// 住民税の特別徴収税額の取得（人事のみ）
+func getResidentTax(c *gin.Context) {
+  year := c.Param("year")
+  if _, err := time.Parse("2006", year); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な年形式"})
+    return
+  }
+
+  schedules, err := fetchResidentTaxSchedules(year)
+  if err != nil {
+    handleDatabaseError(c, err, "住民税の特別徴収税額の取得に失敗しました")
+    return
+  }
+  missing, err := fetchMissingResidentTax(year)
+  if err != nil {
+    handleDatabaseError(c, err, "住民税の特別徴収税額の取得に失敗しました")
+    return
+  }
+
+  c.JSON(http.StatusOK, gin.H{
+    "year":      year,
+    "months":    residentTaxMonths(year),
+    "schedules": schedules,
+    "missing":   missing, // 通知が未登録の社員ID
+  })
+}
+
+// 住民税の特別徴収税額のCSV登録（人事のみ）
+// リクエストボディにCSVをそのまま送る。yearは6月から始まる年度
+func importResidentTax(c *gin.Context) {
+  year := c.Param("year")
+  if _, err := time.Parse("2006", year); err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な年形式"})
+    return
+  }
+
+  schedules, err := parseResidentTaxCSV(c.Request.Body)
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    return
+  }
+
+  if err := saveResidentTaxSchedules(year, schedules, currentEmployeeID(c)); err != nil {
+    handleDatabaseError(c, err, "住民税の特別徴収税額の登録に失敗しました")
+    return
+  }
+
+  missing, err := fetchMissingResidentTax(year)
+  if err != nil {
+    handleDatabaseError(c, err, "住民税の特別徴収税額の取得に失敗しました")
+    return
+  }
+
+  log.Printf("住民税登録 [%s] %d名 実行者%d", year, len(schedules), currentEmployeeID(c))
+  c.JSON(http.StatusOK, gin.H{
+    "message": fmt.Sprintf("住民税の特別徴収税額を登録しました（%d名）", len(schedules)),
+    "missing": missing,
+  })
+}
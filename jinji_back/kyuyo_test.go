@@ -0,0 +1,454 @@
+package main
+
+import (
+  "database/sql"
+  "encoding/json"
+  "net/http"
+  "reflect"
+  "strings"
+  "testing"
+  "time"
+
+  "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetYearEnd(t *testing.T) {
+  testEmployeeAccessCases(t, selfAccessCases, http.MethodGet, employeePath("/api/year-end/%d/2025"), nil, func(mock sqlmock.Sqlmock, tc accessCase) {
+    mock.ExpectQuery("FROM TBL_NENSH").
+      WithArgs(tc.targetID, "2025").
+      WillReturnRows(sqlmock.NewRows([]string{"nensjs"}).AddRow([]byte(`{"lifeInsurancePremium":100000,"generalDependents":1}`)))
+    expectNoRows(mock, "FROM TBL_NENCH")
+  }, func(t *testing.T, tc accessCase, body []byte) {
+    var res struct {
+      Submitted   bool               `json:"submitted"`
+      Declaration YearEndDeclaration `json:"declaration"`
+      Adjustment  *YearEndAdjustment `json:"adjustment"`
+    }
+    decodeBody(t, body, &res)
+    want := YearEndDeclaration{LifeInsurancePremium: 100000, GeneralDependents: 1}
+    if !res.Submitted || res.Declaration != want || res.Adjustment != nil {
+      t.Errorf("submitted=%v declaration=%+v adjustment=%+v、申告済み・未実行を期待", res.Submitted, res.Declaration, res.Adjustment)
+    }
+  })
+}
+
+// 勤怠が締められていない社員は計算せず、給与（TBL_SALRY）に書き込まない
+func TestCreatePayrollRunRefusesOpenMonth(t *testing.T) {
+  router := setupRouter()
+  mock := setupMockDB(t)
+  expectRole(mock, testHRID, roleHR)
+  expectNoRows(mock, "FROM TBL_SHIME")
+  mock.ExpectQuery("FROM TBL_GETSU").
+    WithArgs("202504").
+    WillReturnRows(sqlmock.NewRows([]string{"getsst"}).AddRow(closeStatusOpen))
+  mock.ExpectBegin()
+  mock.ExpectQuery("INSERT INTO TBL_KYRUN").
+    WithArgs("202504", false, testHRID, 0, 1).
+    WillReturnRows(sqlmock.NewRows([]string{"kyruid", "kyrudt"}).AddRow(1, time.Now()))
+  mock.ExpectExec("INSERT INTO TBL_KYRES").
+    WithArgs(1, testGeneralID, nil, errAttendanceNotClosed.Error()).
+    WillReturnResult(sqlmock.NewResult(0, 1))
+  mock.ExpectCommit()
+
+  w := performRequest(t, router, http.MethodPost, "/api/payroll/runs", `{"month":"202504","employeeIds":[10001]}`, testHRID, roleHR)
+  if w.Code != http.StatusOK {
+    t.Fatalf("ステータス%d、200を期待: %s", w.Code, w.Body.String())
+  }
+  var run PayrollRun
+  if err := json.Unmarshal(w.Body.Bytes(), &run); err != nil {
+    t.Fatal(err)
+  }
+  if run.Failed != 1 || len(run.AttendanceNotClosed) != 1 || run.AttendanceNotClosed[0] != testGeneralID {
+    t.Errorf("failed=%d attendanceNotClosed=%v、1件・[%d]を期待", run.Failed, run.AttendanceNotClosed, testGeneralID)
+  }
+  if err := mock.ExpectationsWereMet(); err != nil {
+    t.Error(err)
+  }
+}
+
+// 翌年1月に反映する場合も、12月の給与データがなければ年末調整を実行しない
+func TestRunYearEndAdjustmentRequiresDecemberSalary(t *testing.T) {
+  for _, applyMonth := range []string{"12", "01"} {
+    router := setupRouter()
+    mock := setupMockDB(t)
+    expectRole(mock, testHRID, roleHR)
+    expectNoRows(mock, "FROM TBL_NENSH")
+    mock.ExpectQuery("FROM TBL_SALRY").
+      WithArgs(testGeneralID, "202512").
+      WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+    body := `{"employeeIds":[10001],"applyMonth":"` + applyMonth + `"}`
+    w := performRequest(t, router, http.MethodPost, "/api/payroll/year-end/2025", body, testHRID, roleHR)
+    if w.Code != http.StatusOK {
+      t.Fatalf("applyMonth=%s: ステータス%d、200を期待: %s", applyMonth, w.Code, w.Body.String())
+    }
+    var res struct {
+      Results []YearEndResult `json:"results"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+      t.Fatal(err)
+    }
+    if len(res.Results) != 1 || res.Results[0].Adjustment != nil || res.Results[0].Error != "12月の給与計算後に実行してください" {
+      t.Errorf("applyMonth=%s: results=%+v、12月の給与計算前のエラーを期待", applyMonth, res.Results)
+    }
+    if err := mock.ExpectationsWereMet(); err != nil {
+      t.Errorf("applyMonth=%s: %v", applyMonth, err)
+    }
+  }
+}
+
+// 月額表（令和2年分以降）の行の抜粋
+var sampleWithholdingTable = WithholdingTable{
+  {Lower: 88000, Upper: 89000, Kou: [8]int{130, 0, 0, 0, 0, 0, 0, 0}, Otsu: 3200},
+  {Lower: 89000, Upper: 90000, Kou: [8]int{180, 0, 0, 0, 0, 0, 0, 0}, Otsu: 3200},
+  {Lower: 167000, Upper: 169000, Kou: [8]int{3620, 2000, 390, 0, 0, 0, 0, 0}, Otsu: 11400},
+}
+
+func TestWithholdingTableTax(t *testing.T) {
+  tests := []struct {
+    name       string
+    amount     int
+    column     int
+    dependents int
+    want       int
+  }{
+    {"88,000円未満の甲欄", 87999, withholdingColumnKou, 0, 0},
+    {"88,000円未満の乙欄", 87999, withholdingColumnOtsu, 0, 2695},
+    {"88,000円の甲欄", 88000, withholdingColumnKou, 0, 130},
+    {"88,000円の乙欄", 88000, withholdingColumnOtsu, 0, 3200},
+    {"行の上限の直前", 88999, withholdingColumnKou, 0, 130},
+    {"次の行", 89000, withholdingColumnKou, 0, 180},
+    {"甲欄0人", 168000, withholdingColumnKou, 0, 3620},
+    {"甲欄2人", 168000, withholdingColumnKou, 2, 390},
+    {"甲欄7人", 168000, withholdingColumnKou, 7, 0},
+    {"甲欄7人超は0円未満にならない", 168000, withholdingColumnKou, 9, 0},
+    {"乙欄", 168000, withholdingColumnOtsu, 0, 11400},
+    {"乙欄の扶養親族等1人", 168000, withholdingColumnOtsu, 1, 9790},
+    {"88,000円未満の乙欄の扶養親族等", 87999, withholdingColumnOtsu, 2, 0},
+    {"740,000円の甲欄0人", 740000, withholdingColumnKou, 0, 73390},
+    {"740,000円の甲欄7人", 740000, withholdingColumnKou, 7, 28290},
+    {"740,000円の甲欄9人", 740000, withholdingColumnKou, 9, 25070},
+    {"740,000円超の甲欄0人", 760000, withholdingColumnKou, 0, 73390 + 4084},
+    {"780,000円の甲欄0人", 780000, withholdingColumnKou, 0, 81560},
+    {"780,000円超の甲欄1人", 800000, withholdingColumnKou, 1, 66950 + 81560 + 4696 - 73390},
+    {"3,500,000円の甲欄0人", 3500000, withholdingColumnKou, 0, 1125620},
+    {"3,500,000円超の甲欄0人", 4000000, withholdingColumnKou, 0, 1125620 + 229725},
+    {"740,000円の乙欄", 740000, withholdingColumnOtsu, 0, 259200},
+    {"740,000円超の乙欄", 1000000, withholdingColumnOtsu, 0, 259200 + 106184},
+    {"740,000円超の乙欄の扶養親族等1人", 1000000, withholdingColumnOtsu, 1, 259200 + 106184 - 1610},
+    {"3,500,000円超の乙欄", 4000000, withholdingColumnOtsu, 0, 1386400 + 229725},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      got, err := sampleWithholdingTable.tax(tt.amount, tt.column, tt.dependents)
+      if err != nil {
+        t.Fatalf("エラー: %v", err)
+      }
+      if got != tt.want {
+        t.Errorf("tax(%d, %d, %d) = %d、%dを期待", tt.amount, tt.column, tt.dependents, got, tt.want)
+      }
+    })
+  }
+}
+
+func TestWithholdingTableTaxErrors(t *testing.T) {
+  if _, err := (WithholdingTable{}).tax(100000, withholdingColumnKou, 0); err != errWithholdingTableNotFound {
+    t.Errorf("税額表なし: %v、errWithholdingTableNotFoundを期待", err)
+  }
+  if _, err := sampleWithholdingTable.tax(100000, withholdingColumnKou, 0); err != errWithholdingOutOfRange {
+    t.Errorf("表にない金額: %v、errWithholdingOutOfRangeを期待", err)
+  }
+}
+
+func TestParseWithholdingCSV(t *testing.T) {
+  tests := []struct {
+    name    string
+    csv     string
+    want    WithholdingTable
+    wantErr bool
+  }{
+    {
+      name: "見出しと桁区切りを含む",
+      csv: "以上,未満,甲0人,甲1人,甲2人,甲3人,甲4人,甲5人,甲6人,甲7人,乙\n" +
+        "88000,89000,130,0,0,0,0,0,0,0,3200\n" +
+        "\"89,000\",\"90,000\",180,0,0,0,0,0,0,0,\"3,200\"\n",
+      want: sampleWithholdingTable[:2],
+    },
+    {
+      name:    "列数が不足",
+      csv:     "88000,89000,130,0,0,0,0,0,0,3200\n",
+      wantErr: true,
+    },
+    {
+      name:    "負の金額",
+      csv:     "88000,89000,-130,0,0,0,0,0,0,0,3200\n",
+      wantErr: true,
+    },
+    {
+      name: "区分の欠落",
+      csv: "88000,89000,130,0,0,0,0,0,0,0,3200\n" +
+        "90000,91000,180,0,0,0,0,0,0,0,3200\n",
+      wantErr: true,
+    },
+    {
+      name:    "未満が以上以下",
+      csv:     "88000,88000,130,0,0,0,0,0,0,0,3200\n",
+      wantErr: true,
+    },
+    {
+      name:    "行なし",
+      csv:     "以上,未満,甲0人,甲1人,甲2人,甲3人,甲4人,甲5人,甲6人,甲7人,乙\n",
+      wantErr: true,
+    },
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      got, err := parseWithholdingCSV(strings.NewReader(tt.csv))
+      if tt.wantErr {
+        if err == nil {
+          t.Errorf("エラーを期待: %v", got)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("エラー: %v", err)
+      }
+      if !reflect.DeepEqual(got, tt.want) {
+        t.Errorf("%v、%vを期待", got, tt.want)
+      }
+    })
+  }
+}
+
+func TestNursingInsuranceApplies(t *testing.T) {
+  birth := func(date string) sql.NullTime {
+    d, err := time.ParseInLocation("2006-01-02", date, time.Local)
+    if err != nil {
+      t.Fatal(err)
+    }
+    return sql.NullTime{Time: d, Valid: true}
+  }
+
+  tests := []struct {
+    name      string
+    birthDate sql.NullTime
+    month     string
+    want      bool
+  }{
+    {"40歳の誕生日の前月", birth("1985-06-15"), "202505", false},
+    {"40歳になる月", birth("1985-06-15"), "202506", true},
+    {"1日生まれは前日が属する前月から", birth("1985-06-01"), "202505", true},
+    {"1日生まれの前々月", birth("1985-06-01"), "202504", false},
+    {"1月1日生まれは前年12月から", birth("1985-01-01"), "202412", true},
+    {"65歳の誕生日の前月", birth("1960-06-15"), "202505", true},
+    {"65歳になる月", birth("1960-06-15"), "202506", false},
+    {"1日生まれは65歳の前月から対象外", birth("1960-06-01"), "202505", false},
+    {"1日生まれの65歳の前々月", birth("1960-06-01"), "202504", true},
+    {"2月29日生まれの40歳（うるう年）", birth("1984-02-29"), "202402", true},
+    {"2月29日生まれの40歳の前月", birth("1984-02-29"), "202401", false},
+    {"2月29日生まれの65歳（平年は2月28日に加算）", birth("1984-02-29"), "204902", false},
+    {"2月29日生まれの65歳の前月", birth("1984-02-29"), "204901", true},
+    {"40歳未満", birth("1995-06-12"), "202504", false},
+    {"生年月日が未登録", sql.NullTime{}, "202504", true},
+    {"無効な月", birth("1985-06-15"), "2025-06", false},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      if got := nursingInsuranceApplies(tt.birthDate, tt.month); got != tt.want {
+        t.Errorf("nursingInsuranceApplies(%v, %s) = %v、%vを期待", tt.birthDate.Time.Format("2006-01-02"), tt.month, got, tt.want)
+      }
+    })
+  }
+}
+
+func TestEmploymentIncomeAfterDeduction(t *testing.T) {
+  tests := []struct {
+    name   string
+    income int
+    want   int
+  }{
+    {"0円", 0, 0},
+    {"551,000円未満は0円", 550999, 0},
+    {"551,000円", 551000, 1000},
+    {"1,625,000円未満は55万円を控除", 1624999, 1074999},
+    {"1,625,000円から4,000円単位で40%-10万円を控除", 1625000, 1074400},
+    {"1,700,000円", 1700000, 1120000},
+    {"4,000円未満の端数は切り捨て", 1703999, 1120000},
+    {"1,800,000円から30%+8万円を控除", 1800000, 1180000},
+    {"3,000,000円", 3000000, 2020000},
+    {"3,600,000円から20%+44万円を控除", 3600000, 2440000},
+    {"5,000,000円", 5000000, 3560000},
+    {"6,600,000円から10%+110万円を控除（端数処理なし）", 6600000, 4840000},
+    {"7,000,001円", 7000001, 5200001},
+    {"8,500,000円から195万円を控除", 8500000, 6550000},
+    {"12,000,000円", 12000000, 10050000},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      if got := employmentIncomeAfterDeduction(tt.income); got != tt.want {
+        t.Errorf("employmentIncomeAfterDeduction(%d) = %d、%dを期待", tt.income, got, tt.want)
+      }
+    })
+  }
+}
+
+func TestAnnualIncomeTax(t *testing.T) {
+  tests := []struct {
+    name    string
+    taxable int
+    want    int
+  }{
+    {"0円", 0, 0},
+    {"1,950,000円以下は5%", 1950000, 97500},
+    {"1,951,000円から10%", 1951000, 97600},
+    {"3,300,000円以下は10%", 3300000, 232500},
+    {"6,950,000円以下は20%", 6950000, 962500},
+    {"9,000,000円以下は23%", 9000000, 1434000},
+    {"18,000,000円以下は33%", 18000000, 4404000},
+    {"40,000,000円以下は40%", 40000000, 13204000},
+    {"40,000,000円超は45%", 50000000, 17704000},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      if got := annualIncomeTax(tt.taxable); got != tt.want {
+        t.Errorf("annualIncomeTax(%d) = %d、%dを期待", tt.taxable, got, tt.want)
+      }
+    })
+  }
+}
+
+func TestSpouseDeduction(t *testing.T) {
+  tests := []struct {
+    name        string
+    declaration YearEndDeclaration
+    totalIncome int
+    want        int
+  }{
+    {"配偶者なし", YearEndDeclaration{}, 5000000, 0},
+    {"配偶者控除", YearEndDeclaration{HasSpouse: true, SpouseIncome: 480000}, 9000000, 380000},
+    {"老人控除対象配偶者", YearEndDeclaration{HasSpouse: true, SpouseElderly: true}, 9000000, 480000},
+    {"本人950万円以下", YearEndDeclaration{HasSpouse: true}, 9500000, 260000},
+    {"本人1,000万円以下の老人控除対象配偶者", YearEndDeclaration{HasSpouse: true, SpouseElderly: true}, 10000000, 160000},
+    {"本人1,000万円超は対象外", YearEndDeclaration{HasSpouse: true}, 10000001, 0},
+    {"配偶者特別控除（95万円以下）", YearEndDeclaration{HasSpouse: true, SpouseIncome: 480001}, 9000000, 380000},
+    {"配偶者特別控除は老人の加算なし", YearEndDeclaration{HasSpouse: true, SpouseIncome: 950000, SpouseElderly: true}, 9000000, 380000},
+    {"配偶者特別控除（100万円以下）", YearEndDeclaration{HasSpouse: true, SpouseIncome: 1000000}, 9000000, 360000},
+    {"配偶者特別控除（120万円以下、本人950万円以下）", YearEndDeclaration{HasSpouse: true, SpouseIncome: 1200000}, 9500000, 110000},
+    {"配偶者特別控除（133万円以下、本人1,000万円以下）", YearEndDeclaration{HasSpouse: true, SpouseIncome: 1330000}, 10000000, 10000},
+    {"配偶者特別控除（本人1,000万円超）", YearEndDeclaration{HasSpouse: true, SpouseIncome: 1000000}, 10000001, 0},
+    {"配偶者の所得133万円超は対象外", YearEndDeclaration{HasSpouse: true, SpouseIncome: 1330001}, 5000000, 0},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      if got := spouseDeduction(&tt.declaration, tt.totalIncome); got != tt.want {
+        t.Errorf("spouseDeduction(%+v, %d) = %d、%dを期待", tt.declaration, tt.totalIncome, got, tt.want)
+      }
+    })
+  }
+}
+
+func TestComputeYearEndAdjustment(t *testing.T) {
+  tests := []struct {
+    name        string
+    count       int
+    income      int
+    social      int
+    withheld    int
+    declaration YearEndDeclaration
+    want        YearEndAdjustment
+    wantErr     bool
+  }{
+    {
+      name: "申告なし", count: 12, income: 5000000, social: 720000, withheld: 120000,
+      want: YearEndAdjustment{
+        Income: 5000000, EmploymentIncome: 3560000, SocialInsurance: 720000, BasicDeduction: 480000,
+        TaxableIncome: 2360000, CalculatedTax: 138500, AnnualTax: 141400, Withheld: 120000, Difference: -21400,
+      },
+    },
+    {
+      name: "保険料・配偶者特別控除・扶養親族", count: 12, income: 5000000, social: 720000, withheld: 120000,
+      declaration: YearEndDeclaration{
+        LifeInsurancePremium: 100000, MedicalInsurancePremium: 30000, EarthquakePremium: 60000,
+        HasSpouse: true, SpouseIncome: 1000000, GeneralDependents: 1,
+      },
+      want: YearEndAdjustment{
+        Income: 5000000, EmploymentIncome: 3560000, SocialInsurance: 720000, LifeInsurance: 65000,
+        EarthquakeInsurance: 50000, SpouseDeduction: 360000, DependentDeduction: 380000, BasicDeduction: 480000,
+        TaxableIncome: 1505000, CalculatedTax: 75250, AnnualTax: 76800, Withheld: 120000, Difference: 43200,
+      },
+    },
+    {
+      name: "住宅借入金等特別控除は算出税額が上限", count: 12, income: 5000000, social: 720000, withheld: 120000,
+      declaration: YearEndDeclaration{SocialInsurancePaid: 100000, HousingLoanBalance: 30000000},
+      want: YearEndAdjustment{
+        Income: 5000000, EmploymentIncome: 3560000, SocialInsurance: 820000, BasicDeduction: 480000,
+        TaxableIncome: 2260000, CalculatedTax: 128500, HousingLoanCredit: 128500, AnnualTax: 0, Withheld: 120000, Difference: 120000,
+      },
+    },
+    {
+      name: "控除が所得を超える場合は課税所得0円", count: 3, income: 600000, social: 90000, withheld: 3000,
+      want: YearEndAdjustment{
+        Income: 600000, EmploymentIncome: 50000, SocialInsurance: 90000, BasicDeduction: 480000,
+        Withheld: 3000, Difference: 3000,
+      },
+    },
+    {name: "給与データなし", wantErr: true},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      mock := setupMockDB(t)
+      mock.ExpectQuery("FROM TBL_SALRY").
+        WithArgs(testGeneralID, "202501", "202512").
+        WillReturnRows(sqlmock.NewRows([]string{"count", "income", "social", "withheld"}).
+          AddRow(tt.count, tt.income, tt.social, tt.withheld))
+
+      got, err := computeYearEndAdjustment(testGeneralID, "2025", "202512", &tt.declaration)
+      if tt.wantErr {
+        if err == nil {
+          t.Errorf("エラーを期待: %+v", got)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatal(err)
+      }
+      want := tt.want
+      want.EmployeeID, want.Year, want.ApplyMonth = testGeneralID, "2025", "202512"
+      if *got != want {
+        t.Errorf("computeYearEndAdjustment = %+v\n%+vを期待", *got, want)
+      }
+      if err := mock.ExpectationsWereMet(); err != nil {
+        t.Error(err)
+      }
+    })
+  }
+}
+
+func TestOvertimePay(t *testing.T) {
+  // 基本給320,000円・月平均所定160時間（1時間2,000円）、割増率は時間外25%・60時間超50%・休日35%・深夜25%
+  tests := []struct {
+    name     string
+    workTime WorkTimeSummary
+    want     int
+  }{
+    {"所定労働時間の登録なし", WorkTimeSummary{OvertimeMinutes: 60}, 0},
+    {"時間外なし", WorkTimeSummary{ScheduledHours: 160, WorkedMinutes: 9600}, 0},
+    {"所定時間外・法定時間内は割増なし", WorkTimeSummary{ScheduledHours: 160, NonStatutoryMinutes: 60}, 2000},
+    {"時間外は25%", WorkTimeSummary{ScheduledHours: 160, OvertimeMinutes: 60}, 2500},
+    {"月60時間超は50%", WorkTimeSummary{ScheduledHours: 160, LongOvertimeMinutes: 60}, 3000},
+    {"月60時間までと超過分", WorkTimeSummary{ScheduledHours: 160, OvertimeMinutes: 3600, LongOvertimeMinutes: 600}, 180000},
+    {"法定休日は35%", WorkTimeSummary{ScheduledHours: 160, HolidayMinutes: 60}, 2700},
+    {"深夜労働は割増分のみ", WorkTimeSummary{ScheduledHours: 160, LateNightMinutes: 60}, 500},
+    {"深夜の時間外は25%+25%", WorkTimeSummary{ScheduledHours: 160, OvertimeMinutes: 60, LateNightMinutes: 60}, 3000},
+    {"深夜の60時間超の時間外は50%+25%", WorkTimeSummary{ScheduledHours: 160, LongOvertimeMinutes: 60, LateNightMinutes: 60}, 3500},
+    {"法定休日の深夜労働は35%+25%", WorkTimeSummary{ScheduledHours: 160, HolidayMinutes: 60, LateNightMinutes: 60}, 3200},
+    {"1円未満は四捨五入", WorkTimeSummary{ScheduledHours: 163, OvertimeMinutes: 1}, 41},
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      if got := defaultPayrollRates.overtimePay(320000, &tt.workTime); got != tt.want {
+        t.Errorf("overtimePay(%+v) = %d、%dを期待", tt.workTime, got, tt.want)
+      }
+    })
+  }
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+  "crypto/hmac"
+  "crypto/sha256"
+  "database/sql"
+  "encoding/base64"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "log"
+  "net/http"
+  "os"
+  "strconv"
+  "strings"
+  "time"
+
+  "github.com/gin-gonic/gin"
+  "golang.org/x/crypto/bcrypt"
+)
+
+// トークン関連のエラー
+var (
+  errTokenInvalid = errors.New("無効なトークンです")
+  errTokenExpired = errors.New("トークンの有効期限が切れています")
+)
+
+// トークン署名設定
+var (
+  tokenSecret []byte
+  tokenTTL    = 8 * time.Hour // デフォルトの有効期間
+)
+
+// トークンのクレーム（JWTのペイロード）
+type TokenClaims struct {
+  Subject  string `json:"sub"`  // 社員ID
+  Role     int    `json:"role"` // 社員のロール（TBL_EMPLO.emplrl）
+  IssuedAt int64  `json:"iat"`
+  Expires  int64  `json:"exp"`
+}
+
+// JWTヘッダー（HS256固定）
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// 環境変数からトークン設定を読み込む
+// TOKEN_SECRET: 署名鍵（必須）
+// TOKEN_TTL: 有効期間（time.ParseDuration形式、例: 8h）
+func loadTokenConfig() error {
+  secret := os.Getenv("TOKEN_SECRET")
+  if secret == "" {
+    return errors.New("環境変数 TOKEN_SECRET が設定されていません")
+  }
+  tokenSecret = []byte(secret)
+
+  if ttl := os.Getenv("TOKEN_TTL"); ttl != "" {
+    d, err := time.ParseDuration(ttl)
+    if err != nil || d <= 0 {
+      return fmt.Errorf("環境変数 TOKEN_TTL が不正です: %s", ttl)
+    }
+    tokenTTL = d
+  }
+  return nil
+}
+
+// 署名の生成
+func signToken(signingInput string) string {
+  mac := hmac.New(sha256.New, tokenSecret)
+  mac.Write([]byte(signingInput))
+  return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// トークン発行
+func issueToken(employeeID int, role int) (string, error) {
+  now := time.Now()
+  claims := TokenClaims{
+    Subject:  strconv.Itoa(employeeID),
+    Role:     role,
+    IssuedAt: now.Unix(),
+    Expires:  now.Add(tokenTTL).Unix(),
+  }
+
+  payload, err := json.Marshal(claims)
+  if err != nil {
+    return "", err
+  }
+
+  signingInput := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+  return signingInput + "." + signToken(signingInput), nil
+}
+
+// トークン検証
+// 署名が一致しない・形式が不正な場合はerrTokenInvalid、期限切れの場合はerrTokenExpiredを返す
+func parseToken(token string) (*TokenClaims, error) {
+  parts := strings.Split(token, ".")
+  if len(parts) != 3 || parts[0] != tokenHeader {
+    return nil, errTokenInvalid
+  }
+
+  // 署名検証（タイミング攻撃対策のためhmac.Equalで比較）
+  expected := signToken(parts[0] + "." + parts[1])
+  if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+    return nil, errTokenInvalid
+  }
+
+  payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+  if err != nil {
+    return nil, errTokenInvalid
+  }
+
+  var claims TokenClaims
+  if err := json.Unmarshal(payload, &claims); err != nil {
+    return nil, errTokenInvalid
+  }
+
+  if time.Now().Unix() >= claims.Expires {
+    return nil, errTokenExpired
+  }
+
+  return &claims, nil
+}
+
+// クレームから社員IDを取得
+func (tc *TokenClaims) EmployeeID() (int, error) {
+  id, err := strconv.Atoi(tc.Subject)
+  if err != nil || id <= 0 {
+    return 0, errTokenInvalid
+  }
+  return id, nil
+}
+
+// パスワード設定
+// PASSWORD_ALLOW_PLAINTEXT=false にすると平文で保存されたパスワードでのログインを拒否する
+var allowPlaintextPassword = true
+
+// 環境変数からパスワード設定を読み込む
+func loadPasswordConfig() error {
+  if v := os.Getenv("PASSWORD_ALLOW_PLAINTEXT"); v != "" {
+    allowed, err := strconv.ParseBool(v)
+    if err != nil {
+      return fmt.Errorf("環境変数 PASSWORD_ALLOW_PLAINTEXT が不正です: %s", v)
+    }
+    allowPlaintextPassword = allowed
+  }
+  return nil
+}
+
+// 保存値がbcryptハッシュかどうか
+func isPasswordHash(stored string) bool {
+  return strings.HasPrefix(stored, "$2a$") ||
+    strings.HasPrefix(stored, "$2b$") ||
+    strings.HasPrefix(stored, "$2y$")
+}
+
+// パスワードのハッシュ化
+func hashPassword(password string) (string, error) {
+  hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+  if err != nil {
+    return "", err
+  }
+  return string(hash), nil
+}
+
+// パスワード照合
+// 平文で保存されている行に一致した場合はneedsRehashをtrueで返す
+func verifyPassword(stored string, password string) (matched bool, needsRehash bool) {
+  if isPasswordHash(stored) {
+    return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil, false
+  }
+
+  // 平文の行（移行前のデータ）
+  if !allowPlaintextPassword {
+    return false, false
+  }
+  if !hmac.Equal([]byte(stored), []byte(password)) {
+    return false, false
+  }
+  return true, true
+}
+
+// 平文パスワードをハッシュに置き換える
+func rehashPassword(employeeID int, password string) error {
+  hash, err := hashPassword(password)
+  if err != nil {
+    return err
+  }
+
+  // 他の処理で既に移行済みの場合は上書きしない
+  _, err = db.Exec(`
+    UPDATE TBL_EMPLO
+    SET emplps = $2
+    WHERE emplid = $1 AND emplps = $3
+  `, employeeID, hash, password)
+  return err
+}
+
+// 平文で保存されているパスワードを一括でハッシュ化する
+func hashAllPasswords() (int, error) {
+  rows, err := db.Query("SELECT emplid, emplps FROM TBL_EMPLO")
+  if err != nil {
+    return 0, err
+  }
+
+  type plainRow struct {
+    id       int
+    password string
+  }
+  var targets []plainRow
+  for rows.Next() {
+    var r plainRow
+    if err := rows.Scan(&r.id, &r.password); err != nil {
+      rows.Close()
+      return 0, err
+    }
+    if !isPasswordHash(r.password) {
+      targets = append(targets, r)
+    }
+  }
+  rows.Close()
+  if err := rows.Err(); err != nil {
+    return 0, err
+  }
+
+  count := 0
+  for _, r := range targets {
+    if err := rehashPassword(r.id, r.password); err != nil {
+      return count, fmt.Errorf("社員ID %d: %w", r.id, err)
+    }
+    count++
+  }
+  return count, nil
+}
+
+// 社員のロール（TBL_EMPLO.emplrl）
+const (
+  roleGeneral = 1 // 一般
+  roleManager = 2 // 上司
+  roleHR      = 3 // 人事
+)
+
+// ロール名（エラーメッセージ用）
+var roleNameMap = map[int]string{
+  roleGeneral: "一般",
+  roleManager: "上司",
+  roleHR:      "人事",
+}
+
+// 上司・人事は管理者画面を利用できる
+func isAdminRole(role int) bool {
+  return role == roleManager || role == roleHR
+}
+
+// ロール読み込みミドルウェア
+// トークンのroleは発行時点の値なので、リクエスト毎にTBL_EMPLO.emplrlから最新のロールを取得する
+func loadRole() gin.HandlerFunc {
+  return func(c *gin.Context) {
+    employeeID := currentEmployeeID(c)
+
+    var role int
+    err := db.QueryRow("SELECT emplrl FROM TBL_EMPLO WHERE emplid = $1", employeeID).Scan(&role)
+    if err != nil {
+      if err == sql.ErrNoRows {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "ユーザーが見つかりません"})
+      } else {
+        log.Printf("ロール取得エラー [%d]: %v", employeeID, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "ロールの取得に失敗しました"})
+      }
+      c.Abort()
+      return
+    }
+
+    c.Set("role", role)
+    c.Next()
+  }
+}
+
+// ルート単位の権限チェックミドルウェア
+// 指定したロールのいずれかを持つ社員のみ通過させる
+func requireRole(roles ...int) gin.HandlerFunc {
+  return func(c *gin.Context) {
+    role := currentRole(c)
+    for _, r := range roles {
+      if role == r {
+        c.Next()
+        return
+      }
+    }
+
+    names := make([]string, 0, len(roles))
+    for _, r := range roles {
+      names = append(names, roleNameMap[r])
+    }
+    c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("この操作には%s権限が必要です", strings.Join(names, "・"))})
+    c.Abort()
+  }
+}
+
+// 認証済み社員IDをコンテキストから取得
+func currentEmployeeID(c *gin.Context) int {
+  return c.GetInt("employeeID")
+}
+
+// 認証済み社員のロールをコンテキストから取得
+func currentRole(c *gin.Context) int {
+  return c.GetInt("role")
+}
+
+// 上司と部下の関係を判定する
+// TBL_JOSHIの当日に有効な上司・部下関係（開始日以降、終了日より前）を参照する
+func isDirectReport(managerID int, employeeID int) (bool, error) {
+  var exists bool
+  err := db.QueryRow(`
+    SELECT EXISTS (
+      SELECT 1
+      FROM TBL_JOSHI
+      WHERE joshid = $1 AND joshji = $2
+        AND joshsd <= CURRENT_DATE AND (joshed IS NULL OR joshed > CURRENT_DATE)
+    )
+  `, employeeID, managerID).Scan(&exists)
+  if err != nil {
+    return false, err
+  }
+  return exists, nil
+}
+
+// 対象社員のデータにアクセスできるか判定する
+// 本人は常に可、人事は全社員可、上司は直属の部下のみ可
+func canAccessEmployee(c *gin.Context, targetID int) (bool, error) {
+  userID := currentEmployeeID(c)
+  if userID == targetID {
+    return true, nil
+  }
+
+  switch currentRole(c) {
+  case roleHR:
+    return true, nil
+  case roleManager:
+    return isDirectReport(userID, targetID)
+  }
+  return false, nil
+}
+
+// 対象社員へのアクセス権をチェックし、権限がない場合は403を返す
+// 呼び出し元はfalseが返った場合にそのままreturnすること
+func authorizeEmployeeAccess(c *gin.Context, targetID int) bool {
+  allowed, err := canAccessEmployee(c, targetID)
+  if err != nil {
+    log.Printf("アクセス権判定エラー [%d -> %d]: %v", currentEmployeeID(c), targetID, err)
+    c.JSON(http.StatusInternalServerError, gin.H{"error": "アクセス権の確認に失敗しました"})
+    return false
+  }
+  if !allowed {
+    c.JSON(http.StatusForbidden, gin.H{"error": "他の社員の情報にアクセスする権限がありません"})
+    return false
+  }
+  return true
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+  "net/http"
+  "testing"
+)
+
+// 上司・人事のみのルートは、権限のないロールをハンドラに到達する前に403で拒否する
+func TestAdminRoutesRequireRole(t *testing.T) {
+  hrOnly := []struct {
+    method string
+    path   string
+  }{
+    {http.MethodPut, "/api/employee/10001/role"},
+    {http.MethodGet, "/api/leave/compliance"},
+    {http.MethodPost, "/api/leave/planned"},
+    {http.MethodPost, "/api/leave"},
+    {http.MethodDelete, "/api/leave/10001/2025-04-01"},
+    {http.MethodPost, "/api/leave-types"},
+    {http.MethodPost, "/api/hierarchy"},
+    {http.MethodPost, "/api/schedule"},
+    {http.MethodPost, "/api/payroll/runs"},
+    {http.MethodGet, "/api/payroll/runs"},
+    {http.MethodGet, "/api/payroll/runs/1"},
+    {http.MethodGet, "/api/payroll/rates"},
+    {http.MethodPost, "/api/payroll/rates"},
+    {http.MethodPost, "/api/payroll/grade-tables"},
+    {http.MethodPost, "/api/payroll/grades"},
+    {http.MethodPost, "/api/payroll/grades/annual"},
+    {http.MethodPost, "/api/payroll/tax-tables/2025"},
+    {http.MethodPost, "/api/payroll/withholding"},
+    {http.MethodPost, "/api/payroll/year-end/2025"},
+    {http.MethodPost, "/api/payroll/resident-tax/2025"},
+    {http.MethodGet, "/api/closing/202504"},
+    {http.MethodPost, "/api/closing/202504/close"},
+    {http.MethodPost, "/api/closing/202504/reopen"},
+  }
+  managerOrHR := []struct {
+    method string
+    path   string
+  }{
+    {http.MethodGet, "/api/team"},
+    {http.MethodGet, "/api/team/attendance?month=202504"},
+    {http.MethodGet, "/api/team/evaluations?month=202504"},
+    {http.MethodGet, "/api/team/corrections"},
+    {http.MethodPost, "/api/team/corrections/1/approve"},
+    {http.MethodPost, "/api/team/leave-requests/1/reject"},
+  }
+
+  router := setupRouter()
+  for _, r := range hrOnly {
+    for _, who := range []struct{ id, role int }{{testGeneralID, roleGeneral}, {testManagerID, roleManager}} {
+      mock := setupMockDB(t)
+      expectRole(mock, who.id, who.role)
+      w := performRequest(t, router, r.method, r.path, "", who.id, who.role)
+      if w.Code != http.StatusForbidden {
+        t.Errorf("%s %s（ロール%d）: ステータス%d、403を期待", r.method, r.path, who.role, w.Code)
+      }
+      if err := mock.ExpectationsWereMet(); err != nil {
+        t.Errorf("%s %s: %v", r.method, r.path, err)
+      }
+    }
+  }
+  for _, r := range managerOrHR {
+    mock := setupMockDB(t)
+    expectRole(mock, testGeneralID, roleGeneral)
+    w := performRequest(t, router, r.method, r.path, "", testGeneralID, roleGeneral)
+    if w.Code != http.StatusForbidden {
+      t.Errorf("%s %s（一般）: ステータス%d、403を期待", r.method, r.path, w.Code)
+    }
+    if err := mock.ExpectationsWereMet(); err != nil {
+      t.Errorf("%s %s: %v", r.method, r.path, err)
+    }
+  }
+}
+
+// ロールはトークンではなくTBL_EMPLOから読み込む（トークン発行後の降格を反映する）
+func TestRequireRoleUsesStoredRole(t *testing.T) {
+  router := setupRouter()
+  mock := setupMockDB(t)
+  expectRole(mock, testHRID, roleGeneral)
+  w := performRequest(t, router, http.MethodGet, "/api/closing/202504", "", testHRID, roleHR)
+  if w.Code != http.StatusForbidden {
+    t.Errorf("ステータス%d、403を期待", w.Code)
+  }
+  if err := mock.ExpectationsWereMet(); err != nil {
+    t.Error(err)
+  }
+}
@@ -7,22 +7,24 @@ import (
   "net/http"
   "os"
   "strconv"
+  "strings"
   "time"
 
   "github.com/gin-contrib/cors"
   "github.com/gin-gonic/gin"
   _ "github.com/lib/pq"
-  // bcryptは現在使用していないのでコメントアウト
-  // "golang.org/x/crypto/bcrypt"
 )
 
 // データ構造定義
 // 社員情報
 type Employee struct {
-  ID       int    `json:"id"`
-  Password string `json:"password,omitempty"`
-  Name     string `json:"name"`
-  IsAdmin  bool   `json:"isAdmin"` // 社員番号の先頭が2であれば上司
+  ID        int    `json:"id"`
+  Password  string `json:"password,omitempty"`
+  Name      string `json:"name"`
+  Role      int    `json:"role"`                // 社員のロール（1一般,2上司,3人事）
+  IsAdmin   bool   `json:"isAdmin"`             // 上司・人事であれば管理者
+  BirthDate string `json:"birthDate,omitempty"` // 生年月日（YYYY-MM-DD形式）
+  HireDate  string `json:"hireDate,omitempty"`  // 入社日（YYYY-MM-DD形式）
 }
 
 // 認証用リクエスト
@@ -37,7 +39,16 @@ type Attendance struct {
   Date       string    `json:"date"`
   StartTime  string    `json:"startTime,omitempty"`
   EndTime    string    `json:"endTime,omitempty"`
+  EndDayOffset int     `json:"endDayOffset,omitempty"` // 退勤が翌日の場合は1（日付をまたぐ勤務）
   LeaveType  int       `json:"leaveType,omitempty"` // 休暇タイプがある場合
+  LeaveUnit  int       `json:"leaveUnit,omitempty"`  // 休暇の単位（1全日,2午前半休,3午後半休,4時間単位、省略時は全日）
+  LeaveHours int       `json:"leaveHours,omitempty"` // 時間単位の休暇の時間数
+  PlannedLeave bool     `json:"plannedLeave,omitempty"` // 人事が時季指定した年次有給休暇
+  Segments   []AttendanceSegment `json:"segments,omitempty"` // 勤務区間（1日に複数回の出退勤）
+  Breaks     []AttendanceSegment `json:"breaks,omitempty"`   // 休憩区間
+  WorkedMinutes   int `json:"workedMinutes,omitempty"`   // 実労働時間（分、サーバーで計算）
+  BreakMinutes    int `json:"breakMinutes,omitempty"`    // 休憩時間（分、サーバーで計算）
+  OvertimeMinutes int `json:"overtimeMinutes,omitempty"` // 1日8時間を超える労働時間（分、サーバーで計算）
 }
 
 // 給与情報
@@ -52,8 +63,13 @@ type Salary struct {
   EmploymentInsurance int   `json:"employmentInsurance"`
   IncomeTax          int    `json:"incomeTax"`
   ResidentTax        int    `json:"residentTax"`
+  YearEndAdjustment  int    `json:"yearEndAdjustment"` // 年末調整の過不足額（プラスは還付、マイナスは追加徴収）
   TotalDeduction     int    `json:"totalDeduction"` // 計算項目
   NetSalary          int    `json:"netSalary"`      // 計算項目
+  ResidentTaxMissing bool   `json:"residentTaxMissing,omitempty"` // 住民税の通知が未登録（給与計算時のみ）
+  WorkTime           *WorkTimeSummary `json:"workTime,omitempty"` // 労働時間の集計（給与計算時のみ）
+  HealthGrade        int    `json:"healthGrade,omitempty"`  // 健康保険の等級（給与計算時のみ）
+  PensionGrade       int    `json:"pensionGrade,omitempty"` // 厚生年金の等級（給与計算時のみ）
 }
 
 // 人事考課情報
@@ -67,16 +83,16 @@ type Evaluation struct {
   ManagerComment    string `json:"managerComment,omitempty"`
 }
 
-// 休暇タイプのマッピング
-var leaveTypeMap = map[int]string{
-  1: "年次有給",
-  2: "産前",
-  3: "産後",
-  4: "育児",
-  5: "介護",
-  6: "子の看護",
-  7: "生理",
-  8: "母性健康管理",
+// 休暇タイプ（TBL_KYUKA、人事が管理するマスタ）
+type LeaveType struct {
+  ID               int      `json:"id"`
+  Name             string   `json:"name" binding:"required"`
+  Paid             bool     `json:"paid"`                // 有給
+  Statutory        bool     `json:"statutory"`           // 法定休暇
+  ConsumesAnnual   bool     `json:"consumesAnnualLeave"` // 年次有給休暇の残日数から消化する
+  NeedsCertificate bool     `json:"needsCertificate"`    // 証明書類の提出が必要
+  YearlyCapDays    *float64 `json:"yearlyCapDays"`       // 年度（4月始まり）の上限日数（nullは上限なし）
+  Retired          bool     `json:"retired,omitempty"`   // 廃止（新たに登録できない）
 }
 
 // グローバル変数
@@ -107,7 +123,18 @@ func handleDatabaseError(c *gin.Context, err error, message string) {
   }
 }
 
+// トランザクション内で検出した競合（同時に処理された申請など、409とcodeで返す）
+type conflictError struct {
+  code    string
+  message string
+}
+
+func (e *conflictError) Error() string {
+  return e.message
+}
+
 // トランザクション実行用ヘルパー関数
+// コールバックがconflictErrorを返した場合は409、sql.ErrNoRowsの場合は404を返す
 func executeWithTransaction(c *gin.Context, callback func(*sql.Tx) error, successMessage string) {
   // トランザクション開始
   tx, err := db.Begin()
@@ -130,7 +157,9 @@ func executeWithTransaction(c *gin.Context, callback func(*sql.Tx) error, succes
   // コールバック関数を実行
   if err := callback(tx); err != nil {
     tx.Rollback() // エラー発生時はロールバック
-    if err == sql.ErrNoRows {
+    if cerr, ok := err.(*conflictError); ok {
+      c.JSON(http.StatusConflict, gin.H{"error": cerr.message, "code": cerr.code})
+    } else if err == sql.ErrNoRows {
       c.JSON(http.StatusNotFound, gin.H{"error": "操作対象のデータが見つかりません"})
     } else {
       log.Printf("トランザクション実行エラー: %v", err)
@@ -158,6 +187,16 @@ func main() {
     log.Fatal("環境変数 NEON_CONNECT が設定されていません")
   }
 
+  // トークン署名設定
+  if err := loadTokenConfig(); err != nil {
+    log.Fatal(err)
+  }
+
+  // パスワード設定
+  if err := loadPasswordConfig(); err != nil {
+    log.Fatal(err)
+  }
+
   // 接続リトライロジック
   maxRetries := 5
   retryInterval := time.Second * 3
@@ -189,7 +228,64 @@ func main() {
   
   defer db.Close()
 
-  // Ginルーター設定
+  // サブコマンド: 勤怠締めと給与計算の手動実行
+  // 使い方: ./jinji-app close-month [YYYYMM]（省略時は前月）
+  if len(os.Args) > 1 && os.Args[1] == "close-month" {
+    yearMonth := time.Now().AddDate(0, -1, 0).Format("200601")
+    if len(os.Args) > 2 {
+      yearMonth = os.Args[2]
+    }
+    run, err := runMonthlyClose(yearMonth, closeTriggerManual, 0)
+    if err != nil {
+      log.Fatalf("勤怠締めエラー: %v", err)
+    }
+    log.Printf("勤怠締め完了 [%s]: 締め%d件 スキップ%d件 エラー%d件", run.Month, run.Closed, run.Skipped, run.Failed)
+    return
+  }
+
+  // サブコマンド: 既存パスワードの一括ハッシュ化
+  // 使い方: ./jinji-app hash-passwords
+  if len(os.Args) > 1 && os.Args[1] == "hash-passwords" {
+    count, err := hashAllPasswords()
+    if err != nil {
+      log.Fatalf("パスワード一括ハッシュ化エラー: %v", err)
+    }
+    log.Printf("%d件のパスワードをハッシュ化しました", count)
+    return
+  }
+
+  // サブコマンド: 源泉徴収税額表（月額表）のCSV登録
+  // 使い方: ./jinji-app load-tax-table YYYY file.csv
+  if len(os.Args) > 1 && os.Args[1] == "load-tax-table" {
+    if len(os.Args) != 4 {
+      log.Fatalf("使い方: %s load-tax-table YYYY file.csv", os.Args[0])
+    }
+    f, err := os.Open(os.Args[3])
+    if err != nil {
+      log.Fatalf("CSVファイルを開けません: %v", err)
+    }
+    defer f.Close()
+    count, err := importWithholdingTable(os.Args[2], f)
+    if err != nil {
+      log.Fatalf("源泉徴収税額表の登録エラー: %v", err)
+    }
+    log.Printf("%s年の源泉徴収税額表を登録しました（%d行）", os.Args[2], count)
+    return
+  }
+
+  // 勤怠の自動締めジョブ開始
+  startMonthlyCloseScheduler()
+
+  router := setupRouter()
+
+  // サーバー起動
+  port := ":8080"
+  fmt.Printf("サーバーが%sで起動しました\n", port)
+  router.Run(port)
+}
+
+// Ginルーター設定（ミドルウェアとルーティング）
+func setupRouter() *gin.Engine {
   router := gin.Default()
   
   // エラーハンドリングの改善
@@ -210,32 +306,104 @@ func main() {
   authorized := router.Group("/api")
   authorized.Use(authMiddleware())
   authorized.Use(checkDBConnection()) // DB接続チェックを追加
+  authorized.Use(loadRole())          // ロールをTBL_EMPLOから取得
   {
     // 社員情報
     authorized.GET("/employee/:id", getEmployee)
+    authorized.PUT("/employee/:id/role", requireRole(roleHR), updateEmployeeRole)
     
     // 勤怠関連
     authorized.GET("/attendance/:id", getAttendance)
     authorized.GET("/attendance/:id/:date", getAttendanceByDate)
+    authorized.GET("/attendance/:id/:date/history", getAttendanceHistory)
     authorized.POST("/attendance", createUpdateAttendance)
+    authorized.POST("/punch/in", punch(punchIn))
+    authorized.POST("/punch/out", punch(punchOut))
+    authorized.POST("/punch/break-start", punch(punchBreakStart))
+    authorized.POST("/punch/break-end", punch(punchBreakEnd))
+    authorized.GET("/corrections/:id", getCorrections)
+    authorized.POST("/corrections", submitCorrection)
+    authorized.POST("/corrections/:requestId/cancel", cancelCorrection)
     authorized.GET("/leave/:id", getLeaves)
-    authorized.POST("/leave", createLeave)
-    authorized.DELETE("/leave/:id/:date", deleteLeave)
+    authorized.GET("/leave/:id/balance", getLeaveBalance)
+    authorized.GET("/leave/compliance", requireRole(roleHR), getLeaveCompliance)
+    authorized.POST("/leave/planned", requireRole(roleHR), createPlannedLeave)
+    authorized.POST("/leave", requireRole(roleHR), createLeave)
+    authorized.DELETE("/leave/:id/:date", requireRole(roleHR), deleteLeave)
+    authorized.GET("/leave-types", getLeaveTypes)
+    authorized.POST("/leave-types", requireRole(roleHR), saveLeaveType)
+    authorized.GET("/leave-requests/:id", getLeaveRequests)
+    authorized.POST("/leave-requests", submitLeaveRequest)
+    authorized.POST("/leave-requests/:requestId/cancel", cancelLeaveRequest)
     
     // 給与関連
     authorized.GET("/salary/:id/:month", getSalary)
     authorized.GET("/salary/:id", getSalaries)
+
+    // 年末調整関連
+    authorized.GET("/year-end/:id/:year", getYearEnd)
+    authorized.POST("/year-end/:id/:year", submitYearEndDeclaration)
     
     // 人事考課関連
     authorized.GET("/evaluation/:id/:month", getEvaluation)
     authorized.GET("/evaluation/:id", getEvaluations)
     authorized.POST("/evaluation", updateEvaluation)
+
+    // チーム（上司・部下）関連
+    team := authorized.Group("/team")
+    team.Use(requireRole(roleManager, roleHR))
+    {
+      team.GET("", getTeam)
+      team.GET("/attendance", getTeamAttendance)
+      team.GET("/evaluations", getTeamEvaluations)
+      team.GET("/corrections", getTeamCorrections)
+      team.POST("/corrections/:requestId/approve", decideCorrection(true))
+      team.POST("/corrections/:requestId/reject", decideCorrection(false))
+      team.GET("/leave-requests", getTeamLeaveRequests)
+      team.POST("/leave-requests/:requestId/approve", decideLeaveRequest(true))
+      team.POST("/leave-requests/:requestId/reject", decideLeaveRequest(false))
+    }
+    authorized.POST("/hierarchy", requireRole(roleHR), setReportingLine)
+
+    // 勤務形態（登録は人事のみ）
+    authorized.GET("/schedule/:id", getWorkSchedule)
+    authorized.POST("/schedule", requireRole(roleHR), setWorkSchedule)
+
+    // 給与計算実行（人事のみ）
+    payroll := authorized.Group("/payroll")
+    payroll.Use(requireRole(roleHR))
+    {
+      payroll.POST("/runs", createPayrollRun)
+      payroll.GET("/runs", getPayrollRuns)
+      payroll.GET("/runs/:runId", getPayrollRun)
+      payroll.GET("/rates", getPayrollRates)
+      payroll.GET("/rates/:month", getPayrollRatesForMonth)
+      payroll.POST("/rates", schedulePayrollRates)
+      payroll.GET("/grade-tables/:month", getGradeTables)
+      payroll.POST("/grade-tables", saveGradeTable)
+      payroll.GET("/grades/:id", getEmployeeGrades)
+      payroll.POST("/grades", setEmployeeGrade)
+      payroll.POST("/grades/annual", runAnnualGradeDetermination)
+      payroll.POST("/grades/revision", runGradeRevision)
+      payroll.GET("/tax-tables/:year", getWithholdingTable)
+      payroll.POST("/tax-tables/:year", uploadWithholdingTable)
+      payroll.POST("/withholding", setWithholdingProfile)
+      payroll.POST("/year-end/:year", runYearEndAdjustment)
+      payroll.GET("/resident-tax/:year", getResidentTax)
+      payroll.POST("/resident-tax/:year", importResidentTax)
+    }
+
+    // 勤怠締め管理（人事のみ）
+    closing := authorized.Group("/closing/:month")
+    closing.Use(requireRole(roleHR))
+    {
+      closing.GET("", getClosingStatus)
+      closing.POST("/close", closeMonth)
+      closing.POST("/reopen", reopenMonth)
+    }
   }
 
-  // サーバー起動
-  port := ":8080"
-  fmt.Printf("サーバーが%sで起動しました\n", port)
-  router.Run(port)
+  return router
 }
 
 // 認証ミドルウェア
@@ -248,11 +416,20 @@ func authMiddleware() gin.HandlerFunc {
       return
     }
 
-    // トークン検証ロジック（実践ではJWTなどを使用）
-    // ここでは簡易的な実装
-    employeeID, err := strconv.Atoi(token)
-    if err != nil || employeeID < 10000 {
-      c.JSON(http.StatusUnauthorized, gin.H{"error": "無効なトークンです"})
+    // "Bearer "プレフィックスは任意
+    token = strings.TrimPrefix(token, "Bearer ")
+
+    // 署名と有効期限を検証
+    claims, err := parseToken(token)
+    if err != nil {
+      c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+      c.Abort()
+      return
+    }
+
+    employeeID, err := claims.EmployeeID()
+    if err != nil {
+      c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
       c.Abort()
       return
     }
@@ -273,7 +450,8 @@ func handleLogin(c *gin.Context) {
 
   var storedPassword string
   var employeeName string
-  err := db.QueryRow("SELECT emplps, emplnm FROM TBL_EMPLO WHERE emplid = $1", req.ID).Scan(&storedPassword, &employeeName)
+  var role int
+  err := db.QueryRow("SELECT emplps, emplnm, emplrl FROM TBL_EMPLO WHERE emplid = $1", req.ID).Scan(&storedPassword, &employeeName, &role)
   if err != nil {
     if err == sql.ErrNoRows {
       c.JSON(http.StatusUnauthorized, gin.H{"error": "ユーザーが見つかりません"})
@@ -283,29 +461,35 @@ func handleLogin(c *gin.Context) {
     return
   }
 
-  // 本来はbcryptによるハッシュ比較を行う
-  // 仕様に従い、現時点では単純比較を実装
-  // 実運用環境ではこの部分をbcryptを使用した安全な実装に変更すべき
-  // err = bcrypt.CompareHashAndPassword([]byte(storedPassword), []byte(req.Password))
-  // if err != nil {
-  //   c.JSON(http.StatusUnauthorized, gin.H{"error": "パスワードが一致しません"})
-  //   return
-  // }
-  
-  if storedPassword != req.Password {
+  // パスワード照合（平文で保存されている行は照合成功時にbcryptへ移行）
+  matched, needsRehash := verifyPassword(storedPassword, req.Password)
+  if !matched {
     c.JSON(http.StatusUnauthorized, gin.H{"error": "パスワードが一致しません"})
     return
   }
 
-  // 管理者権限の判定（社員番号の1桁目が2なら管理者）
-  isAdmin := req.ID >= 20000 && req.ID < 30000
+  if needsRehash {
+    // 移行に失敗してもログインは継続する（次回ログイン時に再試行）
+    if err := rehashPassword(req.ID, req.Password); err != nil {
+      log.Printf("パスワードハッシュ移行エラー [%d]: %v", req.ID, err)
+    }
+  }
+
+  // 署名付きトークンを発行
+  token, err := issueToken(req.ID, role)
+  if err != nil {
+    log.Printf("トークン発行エラー: %v", err)
+    c.JSON(http.StatusInternalServerError, gin.H{"error": "トークンの発行に失敗しました"})
+    return
+  }
 
   c.JSON(http.StatusOK, gin.H{
-    "token": strconv.Itoa(req.ID), // 簡易実装。実際にはJWTなどを使用
+    "token": token,
     "employee": Employee{
       ID:      req.ID,
       Name:    employeeName,
-      IsAdmin: isAdmin,
+      Role:    role,
+      IsAdmin: isAdminRole(role),
     },
   })
 }
@@ -318,91 +502,93 @@ func getEmployee(c *gin.Context) {
     return
   }
 
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, id) {
+    return
+  }
+
   var employee Employee
-  err = db.QueryRow("SELECT emplid, emplnm FROM TBL_EMPLO WHERE emplid = $1", id).
-    Scan(&employee.ID, &employee.Name)
+  var birthDate, hireDate sql.NullTime
+  err = db.QueryRow("SELECT emplid, emplnm, emplrl, emplbd, emplhd FROM TBL_EMPLO WHERE emplid = $1", id).
+    Scan(&employee.ID, &employee.Name, &employee.Role, &birthDate, &hireDate)
   
   if err != nil {
     handleDatabaseError(c, err, "社員情報の取得に失敗しました")
     return
   }
 
+  if birthDate.Valid {
+    employee.BirthDate = birthDate.Time.Format("2006-01-02")
+  }
+  if hireDate.Valid {
+    employee.HireDate = hireDate.Time.Format("2006-01-02")
+  }
+
   // 管理者権限の判定
-  employee.IsAdmin = id >= 20000 && id < 30000
+  employee.IsAdmin = isAdminRole(employee.Role)
 
   c.JSON(http.StatusOK, employee)
 }
 
-// 勤怠情報取得（月別）
-func getAttendance(c *gin.Context) {
+// ロール変更リクエスト
+type RoleRequest struct {
+  Role int `json:"role" binding:"required"`
+}
+
+// 社員のロール変更（人事のみ）
+func updateEmployeeRole(c *gin.Context) {
   id, err := strconv.Atoi(c.Param("id"))
   if err != nil {
     c.JSON(http.StatusBadRequest, gin.H{"error": "無効なID"})
     return
   }
 
-  // クエリパラメータから年月を取得（デフォルトは当月）
-  yearMonth := c.DefaultQuery("month", time.Now().Format("200601"))
-  
-  // 指定された年月の勤怠データを取得
-  rows, err := db.Query(`
-    SELECT a.atteid, a.attedt, a.attest, a.atteet
-    FROM TBL_ATTEN a
-    WHERE a.atteid = $1 AND TO_CHAR(a.attedt, 'YYYYMM') = $2
-    ORDER BY a.attedt
-  `, id, yearMonth)
-  
-  if err != nil {
-    handleDatabaseError(c, err, "勤怠データの取得に失敗しました")
+  var req RoleRequest
+  if err := c.ShouldBindJSON(&req); err != nil || roleNameMap[req.Role] == "" {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なロール"})
     return
   }
-  defer rows.Close()
 
-  var attendances []Attendance
-  for rows.Next() {
-    var att Attendance
-    var startTime, endTime sql.NullString
-    err := rows.Scan(&att.EmployeeID, &att.Date, &startTime, &endTime)
-    if err != nil {
-      c.JSON(http.StatusInternalServerError, gin.H{"error": "データ読み取りエラー"})
-      return
-    }
-    
-    if startTime.Valid {
-      att.StartTime = startTime.String
-    }
-    if endTime.Valid {
-      att.EndTime = endTime.String
-    }
-    
-    attendances = append(attendances, att)
+  // 人事が自分のロールを外すと人事不在になり得るため、自分自身の変更は受け付けない
+  if id == currentEmployeeID(c) {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "自分のロールは変更できません"})
+    return
   }
 
-  // 休暇情報も取得
-  leaveRows, err := db.Query(`
-    SELECT l.lereid, l.leredt, l.leretp
-    FROM TBL_LEAVE l
-    WHERE l.lereid = $1 AND TO_CHAR(l.leredt, 'YYYYMM') = $2
-    ORDER BY l.leredt
-  `, id, yearMonth)
-  
+  result, err := db.Exec("UPDATE TBL_EMPLO SET emplrl = $1 WHERE emplid = $2", req.Role, id)
   if err != nil {
-    handleDatabaseError(c, err, "休暇データの取得に失敗しました")
+    handleDatabaseError(c, err, "ロールの更新に失敗しました")
+    return
+  }
+  if rows, _ := result.RowsAffected(); rows == 0 {
+    c.JSON(http.StatusNotFound, gin.H{"error": "社員が見つかりません"})
     return
   }
-  defer leaveRows.Close()
 
-  // 休暇情報をマージ
-  var leaves []Attendance
-  for leaveRows.Next() {
-    var leave Attendance
-    err := leaveRows.Scan(&leave.EmployeeID, &leave.Date, &leave.LeaveType)
-    if err != nil {
-      c.JSON(http.StatusInternalServerError, gin.H{"error": "休暇データ読み取りエラー"})
-      return
-    }
-    
-    leaves = append(leaves, leave)
+  c.JSON(http.StatusOK, gin.H{"message": "ロールを更新しました"})
+}
+
+// 勤怠情報取得（月別）
+func getAttendance(c *gin.Context) {
+  id, err := strconv.Atoi(c.Param("id"))
+  if err != nil {
+    c.JSON(http.StatusBadRequest, gin.H{"error": "無効なID"})
+    return
+  }
+
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, id) {
+    return
+  }
+
+  // クエリパラメータから年月を取得（デフォルトは当月）
+  yearMonth := c.DefaultQuery("month", time.Now().Format("200601"))
+  
+  // 指定された年月の勤怠データと休暇データを取得
+  attendances, leaves, err := fetchMonthlyAttendance(id, yearMonth)
+  if err != nil {
+    handleDatabaseError(c, err, "勤怠データの取得に失敗しました")
+    return
   }
 
   // 応答を返す
@@ -420,16 +606,21 @@ func getAttendanceByDate(c *gin.Context) {
     return
   }
 
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, id) {
+    return
+  }
+
   date := c.Param("date") // YYYY-MM-DD形式
 
   // 勤怠データを取得
   var attendance Attendance
   var startTime, endTime sql.NullString
   err = db.QueryRow(`
-    SELECT atteid, attedt, attest, atteet
+    SELECT atteid, attedt, attest, atteet, atteeo
     FROM TBL_ATTEN
     WHERE atteid = $1 AND attedt = $2
-  `, id, date).Scan(&attendance.EmployeeID, &attendance.Date, &startTime, &endTime)
+  `, id, date).Scan(&attendance.EmployeeID, &attendance.Date, &startTime, &endTime, &attendance.EndDayOffset)
   
   if err != nil && err != sql.ErrNoRows {
     handleDatabaseError(c, err, "勤怠データの取得に失敗しました")
@@ -447,15 +638,22 @@ func getAttendanceByDate(c *gin.Context) {
     if endTime.Valid {
       attendance.EndTime = endTime.String
     }
+
+    // 勤務・休憩の区間と労働時間
+    if err := loadAttendanceDetail(&attendance, date); err != nil {
+      handleDatabaseError(c, err, "勤怠データの取得に失敗しました")
+      return
+    }
   }
 
   // 休暇情報も確認
-  var leaveType sql.NullInt64
+  var leaveType, leaveUnit, leaveHours sql.NullInt64
+  var planned sql.NullBool
   err = db.QueryRow(`
-    SELECT leretp
+    SELECT leretp, lerekb, lerehr, leresh
     FROM TBL_LEAVE
     WHERE lereid = $1 AND leredt = $2
-  `, id, date).Scan(&leaveType)
+  `, id, date).Scan(&leaveType, &leaveUnit, &leaveHours, &planned)
   
   if err != nil && err != sql.ErrNoRows {
     handleDatabaseError(c, err, "休暇データの取得に失敗しました")
@@ -464,6 +662,9 @@ func getAttendanceByDate(c *gin.Context) {
 
   if err != sql.ErrNoRows {
     attendance.LeaveType = int(leaveType.Int64)
+    attendance.LeaveUnit = int(leaveUnit.Int64)
+    attendance.LeaveHours = int(leaveHours.Int64)
+    attendance.PlannedLeave = planned.Bool
   }
 
   // 応答を返す
@@ -484,80 +685,38 @@ func createUpdateAttendance(c *gin.Context) {
     return
   }
 
-  // 勤怠締め日のチェック
-  date, err := time.Parse("2006-01-02", att.Date)
-  if err != nil {
-    c.JSON(http.StatusBadRequest, gin.H{"error": "無効な日付形式"})
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, att.EmployeeID) {
     return
   }
 
-  // 当月の最終日を取得
-  currentYear, currentMonth, _ := time.Now().Date()
-  lastDayOfMonth := time.Date(currentYear, currentMonth+1, 0, 0, 0, 0, 0, time.Local)
-  
-  // 翌月の第一金曜日を計算
-  firstDayNextMonth := time.Date(currentYear, currentMonth+1, 1, 0, 0, 0, 0, time.Local)
-  daysUntilFriday := (5 - int(firstDayNextMonth.Weekday()) + 7) % 7
-  if daysUntilFriday == 0 {
-    daysUntilFriday = 7
-  }
-  firstFridayNextMonth := firstDayNextMonth.AddDate(0, 0, daysUntilFriday)
-  
-  // 締め日チェック（現在の日付が翌月の第一金曜日を過ぎていないかチェック）
-  if date.Before(lastDayOfMonth.AddDate(0, -1, 0)) || time.Now().After(firstFridayNextMonth) {
-    c.JSON(http.StatusBadRequest, gin.H{"error": "入力期間外の勤怠は更新できません"})
+  // 休暇は休暇申請で上司の承認を得てから登録する（人事は直接登録できる）
+  if att.LeaveType > 0 && currentRole(c) != roleHR {
+    c.JSON(http.StatusConflict, gin.H{"error": "休暇は休暇申請から申請してください", "code": errCodeLeaveRequestRequired})
     return
   }
 
-  // トランザクションによる処理実行
-  executeWithTransaction(c, func(tx *sql.Tx) error {
-    if att.LeaveType > 0 {
-      // 休暇情報を登録
-      _, err := tx.Exec(`
-        INSERT INTO TBL_LEAVE (lereid, leredt, leretp)
-        VALUES ($1, $2, $3)
-        ON CONFLICT (lereid, leredt) DO UPDATE
-        SET leretp = $3
-      `, att.EmployeeID, att.Date, att.LeaveType)
-
-      if err != nil {
-        return err
-      }
-
-      // 関連する勤怠データを削除（休暇と出勤は排他的）
-      _, err = tx.Exec(`
-        DELETE FROM TBL_ATTEN
-        WHERE atteid = $1 AND attedt = $2
-      `, att.EmployeeID, att.Date)
-
-      if err != nil {
-        return err
-      }
-    } else {
-      // 休暇情報があれば削除
-      _, err := tx.Exec(`
-        DELETE FROM TBL_LEAVE
-        WHERE lereid = $1 AND leredt = $2
-      `, att.EmployeeID, att.Date)
+  // 入力内容のチェック（締め状態・勤務と休憩の区間・出退勤時刻）
+  if !checkAttendanceInput(c, &att) {
+    return
+  }
 
-      if err != nil {
-        return err
-      }
+  // 過去の日の勤怠は修正申請で上司の承認を得てから反映する（人事は直接修正できる）
+  if currentRole(c) != roleHR && att.Date < time.Now().Format("2006-01-02") {
+    c.JSON(http.StatusConflict, gin.H{"error": "過去の日の勤怠は修正申請から変更してください", "code": errCodeCorrectionRequired})
+    return
+  }
 
-      // 勤怠情報を登録・更新
-      _, err = tx.Exec(`
-        INSERT INTO TBL_ATTEN (atteid, attedt, attest, atteet)
-        VALUES ($1, $2, $3, $4)
-        ON CONFLICT (atteid, attedt) DO UPDATE
-        SET attest = $3, atteet = $4
-      `, att.EmployeeID, att.Date, att.StartTime, att.EndTime)
+  // 変更前の勤怠・休暇（変更履歴用）
+  before, err := fetchStoredAttendance(att.EmployeeID, att.Date)
+  if err != nil {
+    handleDatabaseError(c, err, "勤怠データの取得に失敗しました")
+    return
+  }
 
-      if err != nil {
-        return err
-      }
-    }
-    
-    return nil
+  // トランザクションによる処理実行
+  executeWithTransaction(c, func(tx *sql.Tx) error {
+    return saveAttendance(tx, &att, before, currentEmployeeID(c))
   }, "勤怠情報を更新しました")
 }
 
@@ -569,12 +728,17 @@ func getLeaves(c *gin.Context) {
     return
   }
 
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, id) {
+    return
+  }
+
   // クエリパラメータから年月を取得（デフォルトは当月）
   yearMonth := c.DefaultQuery("month", time.Now().Format("200601"))
   
   // 指定された年月の休暇データを取得
   rows, err := db.Query(`
-    SELECT lereid, leredt, leretp
+    SELECT lereid, leredt, leretp, lerekb, lerehr, leresh
     FROM TBL_LEAVE
     WHERE lereid = $1 AND TO_CHAR(leredt, 'YYYYMM') = $2
     ORDER BY leredt
@@ -589,7 +753,7 @@ func getLeaves(c *gin.Context) {
   var leaves []Attendance
   for rows.Next() {
     var leave Attendance
-    err := rows.Scan(&leave.EmployeeID, &leave.Date, &leave.LeaveType)
+    err := rows.Scan(&leave.EmployeeID, &leave.Date, &leave.LeaveType, &leave.LeaveUnit, &leave.LeaveHours, &leave.PlannedLeave)
     if err != nil {
       c.JSON(http.StatusInternalServerError, gin.H{"error": "データ読み取りエラー"})
       return
@@ -601,7 +765,7 @@ func getLeaves(c *gin.Context) {
   c.JSON(http.StatusOK, leaves)
 }
 
-// 休暇情報登録
+// 休暇情報登録（人事のみ、休暇申請を経ずに直接登録する）
 func createLeave(c *gin.Context) {
   var leave Attendance
   if err := c.ShouldBindJSON(&leave); err != nil {
@@ -615,34 +779,29 @@ func createLeave(c *gin.Context) {
     return
   }
 
-  // 休暇情報を登録
-  _, err := db.Exec(`
-    INSERT INTO TBL_LEAVE (lereid, leredt, leretp)
-    VALUES ($1, $2, $3)
-    ON CONFLICT (lereid, leredt) DO UPDATE
-    SET leretp = $3
-  `, leave.EmployeeID, leave.Date, leave.LeaveType)
-
-  if err != nil {
-    handleDatabaseError(c, err, "休暇データの登録に失敗しました")
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, leave.EmployeeID) {
     return
   }
 
-  // 関連する勤怠データがあれば削除（休暇と出勤は排他的）
-  _, err = db.Exec(`
-    DELETE FROM TBL_ATTEN
-    WHERE atteid = $1 AND attedt = $2
-  `, leave.EmployeeID, leave.Date)
+  // 入力内容のチェック（締め状態・年次有給休暇の残日数）
+  if !checkAttendanceInput(c, &leave) {
+    return
+  }
 
+  // 変更前の勤怠・休暇（変更履歴用）
+  before, err := fetchStoredAttendance(leave.EmployeeID, leave.Date)
   if err != nil {
-    handleDatabaseError(c, err, "関連勤怠データの削除に失敗しました")
+    handleDatabaseError(c, err, "勤怠データの取得に失敗しました")
     return
   }
 
-  c.JSON(http.StatusOK, gin.H{"message": "休暇情報を登録しました"})
+  executeWithTransaction(c, func(tx *sql.Tx) error {
+    return saveAttendance(tx, &leave, before, currentEmployeeID(c))
+  }, "休暇情報を登録しました")
 }
 
-// 休暇情報削除
+// 休暇情報削除（人事のみ、本人は休暇申請の取消で行う）
 func deleteLeave(c *gin.Context) {
   id, err := strconv.Atoi(c.Param("id"))
   if err != nil {
@@ -650,35 +809,36 @@ func deleteLeave(c *gin.Context) {
     return
   }
 
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, id) {
+    return
+  }
+
   date := c.Param("date")
   if date == "" {
     c.JSON(http.StatusBadRequest, gin.H{"error": "日付は必須です"})
     return
   }
 
-  // 休暇情報を削除
-  result, err := db.Exec(`
-    DELETE FROM TBL_LEAVE
-    WHERE lereid = $1 AND leredt = $2
-  `, id, date)
-
-  if err != nil {
-    handleDatabaseError(c, err, "休暇データの削除に失敗しました")
+  // 入力期間（締め状態）のチェック
+  if !checkAttendanceWritable(c, id, date) {
     return
   }
 
-  count, err := result.RowsAffected()
+  // 変更前の勤怠・休暇（変更履歴用）
+  before, err := fetchStoredAttendance(id, date)
   if err != nil {
-    c.JSON(http.StatusInternalServerError, gin.H{"error": "結果取得エラー"})
+    handleDatabaseError(c, err, "休暇データの取得に失敗しました")
     return
   }
-
-  if count == 0 {
+  if before == nil || before.LeaveType == 0 {
     c.JSON(http.StatusNotFound, gin.H{"error": "指定された休暇情報が見つかりません"})
     return
   }
 
-  c.JSON(http.StatusOK, gin.H{"message": "休暇情報を削除しました"})
+  executeWithTransaction(c, func(tx *sql.Tx) error {
+    return removeLeave(tx, before, currentEmployeeID(c))
+  }, "休暇情報を削除しました")
 }
 
 // 給与情報取得（月別）
@@ -689,6 +849,11 @@ func getSalary(c *gin.Context) {
     return
   }
 
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, id) {
+    return
+  }
+
   month := c.Param("month") // YYYYMM形式
   if len(month) != 6 {
     c.JSON(http.StatusBadRequest, gin.H{"error": "無効な月形式"})
@@ -697,13 +862,14 @@ func getSalary(c *gin.Context) {
 
   var salary Salary
   err = db.QueryRow(`
-    SELECT srlyid, srlymt, srlykh, srlyzg, srlyke, srlyka, srlyko, srlyky, srlysy, srlysz
+    SELECT srlyid, srlymt, srlykh, srlyzg, srlyke, srlyka, srlyko, srlyky, srlysy, srlysz, srlync
     FROM TBL_SALRY
     WHERE srlyid = $1 AND srlymt = $2
   `, id, month).Scan(
     &salary.EmployeeID, &salary.Month, &salary.BasicSalary, &salary.OvertimePay,
     &salary.HealthInsurance, &salary.NursingInsurance, &salary.PensionInsurance,
     &salary.EmploymentInsurance, &salary.IncomeTax, &salary.ResidentTax,
+    &salary.YearEndAdjustment,
   )
 
   if err != nil {
@@ -712,10 +878,7 @@ func getSalary(c *gin.Context) {
   }
 
   // 控除合計と手取り額を計算
-  salary.TotalDeduction = salary.HealthInsurance + salary.NursingInsurance +
-    salary.PensionInsurance + salary.EmploymentInsurance +
-    salary.IncomeTax + salary.ResidentTax
-  salary.NetSalary = salary.BasicSalary + salary.OvertimePay - salary.TotalDeduction
+  salary.calculateTotals()
 
   c.JSON(http.StatusOK, salary)
 }
@@ -728,9 +891,14 @@ func getSalaries(c *gin.Context) {
     return
   }
 
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, id) {
+    return
+  }
+
   // 給与データ取得（直近12ヶ月分）
   rows, err := db.Query(`
-    SELECT srlyid, srlymt, srlykh, srlyzg, srlyke, srlyka, srlyko, srlyky, srlysy, srlysz
+    SELECT srlyid, srlymt, srlykh, srlyzg, srlyke, srlyka, srlyko, srlyky, srlysy, srlysz, srlync
     FROM TBL_SALRY
     WHERE srlyid = $1
     ORDER BY srlymt DESC
@@ -750,6 +918,7 @@ func getSalaries(c *gin.Context) {
       &salary.EmployeeID, &salary.Month, &salary.BasicSalary, &salary.OvertimePay,
       &salary.HealthInsurance, &salary.NursingInsurance, &salary.PensionInsurance,
       &salary.EmploymentInsurance, &salary.IncomeTax, &salary.ResidentTax,
+      &salary.YearEndAdjustment,
     )
     if err != nil {
       c.JSON(http.StatusInternalServerError, gin.H{"error": "データ読み取りエラー"})
@@ -757,10 +926,7 @@ func getSalaries(c *gin.Context) {
     }
     
     // 控除合計と手取り額を計算
-    salary.TotalDeduction = salary.HealthInsurance + salary.NursingInsurance +
-      salary.PensionInsurance + salary.EmploymentInsurance +
-      salary.IncomeTax + salary.ResidentTax
-    salary.NetSalary = salary.BasicSalary + salary.OvertimePay - salary.TotalDeduction
+    salary.calculateTotals()
     
     salaries = append(salaries, salary)
   }
@@ -776,46 +942,26 @@ func getEvaluation(c *gin.Context) {
     return
   }
 
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, id) {
+    return
+  }
+
   month := c.Param("month") // YYYYMM形式
   if len(month) != 6 {
     c.JSON(http.StatusBadRequest, gin.H{"error": "無効な月形式"})
     return
   }
 
-  var eval Evaluation
-  var skillScore, behaviorScore, attitudeScore sql.NullInt64
-  err = db.QueryRow(`
-    SELECT kokaid, kokamt, kokabk, kokazg, kokake, kokaty, kokajs
-    FROM TBL_KOUKA
-    WHERE kokaid = $1 AND kokamt = $2
-  `, id, month).Scan(
-    &eval.EmployeeID, &eval.Month, &eval.EmployeeComment,
-    &skillScore, &behaviorScore, &attitudeScore, &eval.ManagerComment,
-  )
-
+  eval, err := fetchEvaluation(id, month)
   if err != nil {
     if err == sql.ErrNoRows {
       // レコードがない場合は新規作成に備えて基本情報だけセット
-      eval.EmployeeID = id
-      eval.Month = month
+      eval = &Evaluation{EmployeeID: id, Month: month}
     } else {
       handleDatabaseError(c, err, "人事考課データの取得に失敗しました")
       return
     }
-  } else {
-    // Null値の処理
-    if skillScore.Valid {
-      score := int(skillScore.Int64)
-      eval.SkillScore = &score
-    }
-    if behaviorScore.Valid {
-      score := int(behaviorScore.Int64)
-      eval.BehaviorScore = &score
-    }
-    if attitudeScore.Valid {
-      score := int(attitudeScore.Int64)
-      eval.AttitudeScore = &score
-    }
   }
 
   // 前月のデータも取得（存在する場合）
@@ -829,32 +975,10 @@ func getEvaluation(c *gin.Context) {
     previousMonth = fmt.Sprintf("%d%02d", year, m-1)
   }
   
-  var prevEval Evaluation
-  var prevSkillScore, prevBehaviorScore, prevAttitudeScore sql.NullInt64
-  err = db.QueryRow(`
-    SELECT kokaid, kokamt, kokabk, kokazg, kokake, kokaty, kokajs
-    FROM TBL_KOUKA
-    WHERE kokaid = $1 AND kokamt = $2
-  `, id, previousMonth).Scan(
-    &prevEval.EmployeeID, &prevEval.Month, &prevEval.EmployeeComment,
-    &prevSkillScore, &prevBehaviorScore, &prevAttitudeScore, &prevEval.ManagerComment,
-  )
-
   // エラーを無視（前月のデータがない場合もあるため）
-  if err == nil {
-    // Null値の処理
-    if prevSkillScore.Valid {
-      score := int(prevSkillScore.Int64)
-      prevEval.SkillScore = &score
-    }
-    if prevBehaviorScore.Valid {
-      score := int(prevBehaviorScore.Int64)
-      prevEval.BehaviorScore = &score
-    }
-    if prevAttitudeScore.Valid {
-      score := int(prevAttitudeScore.Int64)
-      prevEval.AttitudeScore = &score
-    }
+  prevEval, err := fetchEvaluation(id, previousMonth)
+  if err != nil {
+    prevEval = &Evaluation{}
   }
 
   c.JSON(http.StatusOK, gin.H{
@@ -871,6 +995,11 @@ func getEvaluations(c *gin.Context) {
     return
   }
 
+  // アクセス権チェック
+  if !authorizeEmployeeAccess(c, id) {
+    return
+  }
+
   // 人事考課データ取得（直近12ヶ月分）
   rows, err := db.Query(`
     SELECT kokaid, kokamt, kokabk, kokazg, kokake, kokaty, kokajs
@@ -933,18 +1062,32 @@ func updateEvaluation(c *gin.Context) {
     return
   }
 
-  // ユーザー権限チェック
-  userID, exists := c.Get("employeeID")
-  if !exists {
-    c.JSON(http.StatusUnauthorized, gin.H{"error": "認証情報がありません"})
+  // アクセス権チェック（本人・直属の上司・人事のみ）
+  if !authorizeEmployeeAccess(c, eval.EmployeeID) {
     return
   }
 
-  // 上司権限の場合は全フィールド更新可、一般社員は自分のコメントのみ更新可
-  isAdmin := userID.(int) >= 20000 && userID.(int) < 30000
-  
-  if !isAdmin && userID.(int) != eval.EmployeeID {
-    c.JSON(http.StatusForbidden, gin.H{"error": "他の社員の考課を更新する権限がありません"})
+  // 直属の上司の場合は全フィールド更新可、本人は自分のコメントのみ更新可
+  userID := currentEmployeeID(c)
+  isEvaluator, err := isDirectReport(userID, eval.EmployeeID)
+  if err != nil {
+    handleDatabaseError(c, err, "上司情報の取得に失敗しました")
+    return
+  }
+
+  if !isEvaluator && userID != eval.EmployeeID {
+    c.JSON(http.StatusForbidden, gin.H{"error": "直属の上司のみ部下の考課を更新できます"})
+    return
+  }
+
+  // 考課者（上司社員ID）は現在の上司関係から取得
+  managerID, err := currentManagerID(eval.EmployeeID)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      c.JSON(http.StatusBadRequest, gin.H{"error": "上司が登録されていません。人事に上司の登録を依頼してください"})
+    } else {
+      handleDatabaseError(c, err, "上司情報の取得に失敗しました")
+    }
     return
   }
 
@@ -974,7 +1117,7 @@ func updateEvaluation(c *gin.Context) {
       employeeCommentValue = sql.NullString{String: eval.EmployeeComment, Valid: true}
     }
     
-    if isAdmin {
+    if isEvaluator {
       // 上司の場合は評価スコアと上司コメントも設定
       if eval.SkillScore != nil {
         skillScoreValue = sql.NullInt64{Int64: int64(*eval.SkillScore), Valid: true}
@@ -993,7 +1136,7 @@ func updateEvaluation(c *gin.Context) {
     if err == nil {
       // 既存データがある場合は更新
       // 一般社員の場合は自分のコメントのみ更新
-      if !isAdmin {
+      if !isEvaluator {
         skillScoreValue = skillScore
         behaviorScoreValue = behaviorScore
         attitudeScoreValue = attitudeScore
@@ -1002,28 +1145,28 @@ func updateEvaluation(c *gin.Context) {
       
       _, err = tx.Exec(`
         UPDATE TBL_KOUKA
-        SET kokabk = $3, kokazg = $4, kokake = $5, kokaty = $6, kokajs = $7
+        SET kokabk = $3, kokazg = $4, kokake = $5, kokaty = $6, kokajs = $7, kokaji = $8
         WHERE kokaid = $1 AND kokamt = $2
       `, eval.EmployeeID, eval.Month, employeeCommentValue,
-         skillScoreValue, behaviorScoreValue, attitudeScoreValue, managerCommentValue)
+         skillScoreValue, behaviorScoreValue, attitudeScoreValue, managerCommentValue, managerID)
       
       if err != nil {
         return err
       }
     } else if err == sql.ErrNoRows {
       // 新規データ作成
-      if !isAdmin {
+      if !isEvaluator {
         // 一般社員は自分のコメントのみ設定可能
         _, err = tx.Exec(`
-          INSERT INTO TBL_KOUKA (kokaid, kokamt, kokabk)
-          VALUES ($1, $2, $3)
-        `, eval.EmployeeID, eval.Month, employeeCommentValue)
+          INSERT INTO TBL_KOUKA (kokaid, kokaji, kokamt, kokabk)
+          VALUES ($1, $2, $3, $4)
+        `, eval.EmployeeID, managerID, eval.Month, employeeCommentValue)
       } else {
         // 上司は全項目を設定可能
         _, err = tx.Exec(`
-          INSERT INTO TBL_KOUKA (kokaid, kokamt, kokabk, kokazg, kokake, kokaty, kokajs)
-          VALUES ($1, $2, $3, $4, $5, $6, $7)
-        `, eval.EmployeeID, eval.Month, employeeCommentValue,
+          INSERT INTO TBL_KOUKA (kokaid, kokaji, kokamt, kokabk, kokazg, kokake, kokaty, kokajs)
+          VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        `, eval.EmployeeID, managerID, eval.Month, employeeCommentValue,
            skillScoreValue, behaviorScoreValue, attitudeScoreValue, managerCommentValue)
       }
       
@@ -1038,90 +1181,3 @@ func updateEvaluation(c *gin.Context) {
     return nil
   }, "人事考課情報を更新しました")
 }
-
-// 給与計算関数（勤怠データから給与計算を行う）
-func calculateSalary(employeeID int, yearMonth string) error {
-  // 基本情報の取得
-  var basicSalary int
-  err := db.QueryRow(`
-    SELECT srlykh 
-    FROM TBL_SALRY 
-    WHERE srlyid = $1 
-    ORDER BY srlymt DESC 
-    LIMIT 1
-  `, employeeID).Scan(&basicSalary)
-  
-  if err != nil && err != sql.ErrNoRows {
-    return err
-  }
-  
-  // 初回の場合はデフォルト給与を設定
-  if err == sql.ErrNoRows {
-    basicSalary = 250000 // デフォルト基本給
-  }
-  
-  // 勤怠データから残業時間を集計
-  var overtimeMinutes int
-  err = db.QueryRow(`
-    SELECT COALESCE(SUM(
-      EXTRACT(EPOCH FROM (atteet - '18:00:00'::time))/60
-    ), 0)
-    FROM TBL_ATTEN
-    WHERE atteid = $1 
-    AND TO_CHAR(attedt, 'YYYYMM') = $2
-    AND atteet > '18:00:00'::time
-  `, employeeID, yearMonth).Scan(&overtimeMinutes)
-  
-  if err != nil {
-    return err
-  }
-  
-  // 残業手当計算（時給2000円と仮定）
-  overtimePay := (overtimeMinutes / 60) * 2000
-  
-  // 社会保険料計算
-  yearlyIncome := basicSalary * 12
-  healthInsurance := int(float64(basicSalary) * 0.05)    // 健康保険料: 5%
-  nursingInsurance := int(float64(basicSalary) * 0.018)  // 介護保険料: 1.8%
-  pensionInsurance := int(float64(basicSalary) * 0.0915) // 厚生年金: 9.15%
-  employmentInsurance := int(float64(basicSalary) * 0.005) // 雇用保険: 0.5%
-  
-  // 所得税計算（累進課税）
-  var incomeTaxRate float64
-  if yearlyIncome <= 1950000 {
-    incomeTaxRate = 0.05
-  } else if yearlyIncome <= 3300000 {
-    incomeTaxRate = 0.10
-  } else if yearlyIncome <= 6950000 {
-    incomeTaxRate = 0.20
-  } else if yearlyIncome <= 9000000 {
-    incomeTaxRate = 0.23
-  } else if yearlyIncome <= 18000000 {
-    incomeTaxRate = 0.33
-  } else if yearlyIncome <= 40000000 {
-    incomeTaxRate = 0.40
-  } else {
-    incomeTaxRate = 0.45
-  }
-  
-  incomeTax := int(float64(basicSalary) * incomeTaxRate)
-  
-  // 住民税計算（10%と仮定）
-  residentTax := int(float64(basicSalary) * 0.10)
-  
-  // 給与テーブルに登録
-  _, err = db.Exec(`
-    INSERT INTO TBL_SALRY (
-      srlyid, srlymt, srlykh, srlyzg, srlyke, srlyka, srlyko, srlyky, srlysy, srlysz
-    ) VALUES (
-      $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
-    ) ON CONFLICT (srlyid, srlymt) DO UPDATE SET
-      srlykh = $3, srlyzg = $4, srlyke = $5, srlyka = $6, 
-      srlyko = $7, srlyky = $8, srlysy = $9, srlysz = $10
-  `, 
-    employeeID, yearMonth, basicSalary, overtimePay, 
-    healthInsurance, nursingInsurance, pensionInsurance, 
-    employmentInsurance, incomeTax, residentTax)
-  
-  return err
-}
\ No newline at end of file
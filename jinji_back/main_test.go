@@ -0,0 +1,456 @@
+package main
+
+import (
+  "encoding/json"
+  "fmt"
+  "io"
+  "net/http"
+  "net/http/httptest"
+  "os"
+  "reflect"
+  "regexp"
+  "strings"
+  "testing"
+  "time"
+
+  "github.com/DATA-DOG/go-sqlmock"
+  "github.com/gin-gonic/gin"
+)
+
+// テストで使う社員（READMEのサンプルデータと同じ）
+const (
+  testGeneralID = 10001 // 一般（上司は20002）
+  testManagerID = 20002 // 上司
+  testHRID      = 30003 // 人事
+  testOtherID   = 10009 // 20002の部下ではない一般社員
+)
+
+func TestMain(m *testing.M) {
+  gin.SetMode(gin.TestMode)
+  gin.DefaultWriter = io.Discard
+  tokenSecret = []byte("test-secret")
+  os.Exit(m.Run())
+}
+
+// パッケージ変数dbをsqlmockに差し替える
+func setupMockDB(t *testing.T) sqlmock.Sqlmock {
+  t.Helper()
+  mockDB, mock, err := sqlmock.New()
+  if err != nil {
+    t.Fatalf("sqlmockの作成に失敗しました: %v", err)
+  }
+  prev := db
+  db = mockDB
+  t.Cleanup(func() {
+    db = prev
+    mockDB.Close()
+  })
+  return mock
+}
+
+// loadRoleのロール取得を期待する
+func expectRole(mock sqlmock.Sqlmock, employeeID int, role int) {
+  mock.ExpectQuery(regexp.QuoteMeta("SELECT emplrl FROM TBL_EMPLO WHERE emplid = $1")).
+    WithArgs(employeeID).
+    WillReturnRows(sqlmock.NewRows([]string{"emplrl"}).AddRow(role))
+}
+
+// isDirectReportの上司・部下関係の確認を期待する
+func expectDirectReport(mock sqlmock.Sqlmock, managerID int, employeeID int, exists bool) {
+  mock.ExpectQuery(`FROM TBL_JOSHI`).
+    WithArgs(employeeID, managerID).
+    WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(exists))
+}
+
+// 指定した社員としてリクエストを実行する
+func performRequest(t *testing.T, router http.Handler, method string, path string, body string, employeeID int, role int) *httptest.ResponseRecorder {
+  t.Helper()
+  token, err := issueToken(employeeID, role)
+  if err != nil {
+    t.Fatalf("トークンの発行に失敗しました: %v", err)
+  }
+  req := httptest.NewRequest(method, path, strings.NewReader(body))
+  req.Header.Set("Authorization", "Bearer "+token)
+  if body != "" {
+    req.Header.Set("Content-Type", "application/json")
+  }
+  w := httptest.NewRecorder()
+  router.ServeHTTP(w, req)
+  return w
+}
+
+// 結果が0件のクエリを期待する
+func expectNoRows(mock sqlmock.Sqlmock, query string) {
+  mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"none"}))
+}
+
+// 社員単位のアクセス権チェック（authorizeEmployeeAccess）の検証ケース
+type accessCase struct {
+  name     string
+  userID   int
+  role     int
+  targetID int
+  denied   bool // authorizeEmployeeAccessで拒否される
+  want     int
+}
+
+// 本人・上司（直属の部下）・上司（部下以外）・人事の4ケース
+var employeeAccessCases = []accessCase{
+  {"本人", testGeneralID, roleGeneral, testGeneralID, false, http.StatusOK},
+  {"上司から直属の部下", testManagerID, roleManager, testGeneralID, false, http.StatusOK},
+  {"上司から部下以外", testManagerID, roleManager, testOtherID, true, http.StatusForbidden},
+  {"人事", testHRID, roleHR, testGeneralID, false, http.StatusOK},
+}
+
+// 本人のみ（アクセス権チェックは上の4ケースで検証済みで、応答の内容を検証するハンドラ用）
+var selfAccessCases = []accessCase{
+  {"本人", testGeneralID, roleGeneral, testGeneralID, false, http.StatusOK},
+}
+
+// アクセス権チェックまでのDBアクセスを期待する
+func expectAccess(mock sqlmock.Sqlmock, tc accessCase) {
+  expectRole(mock, tc.userID, tc.role)
+  if tc.role == roleManager && tc.userID != tc.targetID {
+    expectDirectReport(mock, tc.userID, tc.targetID, tc.targetID == testGeneralID)
+  }
+}
+
+// 許可された場合の応答ボディの検証
+type accessCheck func(t *testing.T, tc accessCase, body []byte)
+
+// 社員単位のアクセス権チェックを行うハンドラを4ケースで検証する
+// stubはアクセスが許可された場合に続くDBアクセスを期待として設定し、checkは許可された場合の応答ボディを検証する
+func testEmployeeAccess(t *testing.T, method string, path func(targetID int) string, body func(targetID int) string, stub func(mock sqlmock.Sqlmock, tc accessCase), check accessCheck) {
+  t.Helper()
+  testEmployeeAccessCases(t, employeeAccessCases, method, path, body, stub, check)
+}
+
+// 期待するステータスが4ケースと異なるハンドラ用
+func testEmployeeAccessCases(t *testing.T, cases []accessCase, method string, path func(targetID int) string, body func(targetID int) string, stub func(mock sqlmock.Sqlmock, tc accessCase), check accessCheck) {
+  t.Helper()
+  router := setupRouter()
+  for _, tc := range cases {
+    t.Run(tc.name, func(t *testing.T) {
+      mock := setupMockDB(t)
+      expectAccess(mock, tc)
+      if !tc.denied && stub != nil {
+        stub(mock, tc)
+      }
+      reqBody := ""
+      if body != nil {
+        reqBody = body(tc.targetID)
+      }
+      w := performRequest(t, router, method, path(tc.targetID), reqBody, tc.userID, tc.role)
+      if w.Code != tc.want {
+        t.Errorf("ステータス%d、%dを期待: %s", w.Code, tc.want, w.Body.String())
+      } else if w.Code == http.StatusOK && check != nil {
+        check(t, tc, w.Body.Bytes())
+      }
+      if err := mock.ExpectationsWereMet(); err != nil {
+        t.Error(err)
+      }
+    })
+  }
+}
+
+// 応答ボディをJSONとして読み込む
+func decodeBody(t *testing.T, body []byte, v interface{}) {
+  t.Helper()
+  if err := json.Unmarshal(body, v); err != nil {
+    t.Fatalf("応答の読み込みに失敗しました: %v: %s", err, body)
+  }
+}
+
+// 更新系ハンドラの応答メッセージを検証する
+func checkMessage(message string) accessCheck {
+  return func(t *testing.T, tc accessCase, body []byte) {
+    var res struct {
+      Message string `json:"message"`
+    }
+    decodeBody(t, body, &res)
+    if res.Message != message {
+      t.Errorf("message=%q、%qを期待", res.Message, message)
+    }
+  }
+}
+
+// パス中の社員IDを置き換える
+func employeePath(format string) func(targetID int) string {
+  return func(targetID int) string {
+    return fmt.Sprintf(format, targetID)
+  }
+}
+
+// 社員IDをリクエストボディで指定する場合のパス
+func fixedPath(path string) func(targetID int) string {
+  return func(int) string {
+    return path
+  }
+}
+
+func TestGetEmployeeAccess(t *testing.T) {
+  testEmployeeAccess(t, http.MethodGet, employeePath("/api/employee/%d"), nil, func(mock sqlmock.Sqlmock, tc accessCase) {
+    mock.ExpectQuery("FROM TBL_EMPLO WHERE emplid = \\$1").
+      WithArgs(tc.targetID).
+      WillReturnRows(sqlmock.NewRows([]string{"emplid", "emplnm", "emplrl", "emplbd", "emplhd"}).
+        AddRow(tc.targetID, "佐藤 太郎", roleGeneral, time.Date(1990, 6, 15, 0, 0, 0, 0, time.UTC), nil))
+  }, func(t *testing.T, tc accessCase, body []byte) {
+    var employee Employee
+    decodeBody(t, body, &employee)
+    want := Employee{ID: tc.targetID, Name: "佐藤 太郎", Role: roleGeneral, BirthDate: "1990-06-15"}
+    if employee != want {
+      t.Errorf("社員情報%+v、%+vを期待", employee, want)
+    }
+  })
+}
+
+func TestGetAttendanceAccess(t *testing.T) {
+  testEmployeeAccess(t, http.MethodGet, employeePath("/api/attendance/%d?month=202504"), nil, func(mock sqlmock.Sqlmock, tc accessCase) {
+    mock.ExpectQuery("FROM TBL_ATTEN").
+      WithArgs(tc.targetID, "202504").
+      WillReturnRows(sqlmock.NewRows([]string{"atteid", "attedt", "attest", "atteet", "atteeo"}).
+        AddRow(tc.targetID, "2025-04-01", "09:00:00", "18:00:00", 0))
+    mock.ExpectQuery("FROM TBL_LEAVE").
+      WithArgs(tc.targetID, "202504").
+      WillReturnRows(sqlmock.NewRows([]string{"lereid", "leredt", "leretp", "lerekb", "lerehr", "leresh"}).
+        AddRow(tc.targetID, "2025-04-02", 1, leaveUnitFullDay, 0, false))
+  }, func(t *testing.T, tc accessCase, body []byte) {
+    var res struct {
+      Attendances []Attendance `json:"attendances"`
+      Leaves      []Attendance `json:"leaves"`
+    }
+    decodeBody(t, body, &res)
+    if len(res.Attendances) != 1 || res.Attendances[0].Date != "2025-04-01" || res.Attendances[0].EndTime != "18:00:00" {
+      t.Errorf("attendances=%+v、2025-04-01の1件を期待", res.Attendances)
+    }
+    if len(res.Leaves) != 1 || res.Leaves[0].Date != "2025-04-02" || res.Leaves[0].LeaveType != 1 {
+      t.Errorf("leaves=%+v、2025-04-02の1件を期待", res.Leaves)
+    }
+  })
+}
+
+func TestGetAttendanceByDateAccess(t *testing.T) {
+  testEmployeeAccess(t, http.MethodGet, employeePath("/api/attendance/%d/2025-04-01"), nil, func(mock sqlmock.Sqlmock, tc accessCase) {
+    mock.ExpectQuery("FROM TBL_ATTEN").
+      WithArgs(tc.targetID, "2025-04-01").
+      WillReturnRows(sqlmock.NewRows([]string{"atteid", "attedt", "attest", "atteet", "atteeo"}).
+        AddRow(tc.targetID, "2025-04-01", "09:00", "18:30", 0))
+    expectNoRows(mock, "FROM TBL_KUKAN")
+    expectNoRows(mock, "FROM TBL_KINMU")
+    expectNoRows(mock, "FROM TBL_LEAVE")
+  }, func(t *testing.T, tc accessCase, body []byte) {
+    var att Attendance
+    decodeBody(t, body, &att)
+    // 勤務形態の登録がない場合は9:00〜18:00（休憩12:00〜13:00）として計算する
+    if att.EmployeeID != tc.targetID || att.StartTime != "09:00" || att.EndTime != "18:30" {
+      t.Errorf("勤怠%+v、9:00〜18:30を期待", att)
+    }
+    if att.WorkedMinutes != 510 || att.BreakMinutes != 60 || att.OvertimeMinutes != 30 {
+      t.Errorf("worked=%d break=%d overtime=%d、510・60・30を期待", att.WorkedMinutes, att.BreakMinutes, att.OvertimeMinutes)
+    }
+  })
+}
+
+func TestGetLeavesAccess(t *testing.T) {
+  testEmployeeAccess(t, http.MethodGet, employeePath("/api/leave/%d?month=202504"), nil, func(mock sqlmock.Sqlmock, tc accessCase) {
+    mock.ExpectQuery("FROM TBL_LEAVE").
+      WithArgs(tc.targetID, "202504").
+      WillReturnRows(sqlmock.NewRows([]string{"lereid", "leredt", "leretp", "lerekb", "lerehr", "leresh"}).
+        AddRow(tc.targetID, "2025-04-10", 1, leaveUnitHourly, 2, false))
+  }, func(t *testing.T, tc accessCase, body []byte) {
+    var leaves []Attendance
+    decodeBody(t, body, &leaves)
+    want := Attendance{EmployeeID: tc.targetID, Date: "2025-04-10", LeaveType: 1, LeaveUnit: leaveUnitHourly, LeaveHours: 2}
+    if len(leaves) != 1 || !reflect.DeepEqual(leaves[0], want) {
+      t.Errorf("休暇%+v、%+vを期待", leaves, want)
+    }
+  })
+}
+
+// 給与（TBL_SALRY）の列
+var salaryColumns = []string{"srlyid", "srlymt", "srlykh", "srlyzg", "srlyke", "srlyka", "srlyko", "srlyky", "srlysy", "srlysz", "srlync"}
+
+func TestGetSalaryAccess(t *testing.T) {
+  testEmployeeAccess(t, http.MethodGet, employeePath("/api/salary/%d/202504"), nil, func(mock sqlmock.Sqlmock, tc accessCase) {
+    mock.ExpectQuery("FROM TBL_SALRY").
+      WithArgs(tc.targetID, "202504").
+      WillReturnRows(sqlmock.NewRows(salaryColumns).
+        AddRow(tc.targetID, "202504", 250000, 20000, 12475, 0, 22875, 1620, 5770, 10000, 0))
+  }, func(t *testing.T, tc accessCase, body []byte) {
+    var salary Salary
+    decodeBody(t, body, &salary)
+    if salary.EmployeeID != tc.targetID || salary.Month != "202504" || salary.IncomeTax != 5770 {
+      t.Errorf("給与%+v、202504の給与を期待", salary)
+    }
+    if salary.TotalDeduction != 52740 || salary.NetSalary != 217260 {
+      t.Errorf("totalDeduction=%d netSalary=%d、52740・217260を期待", salary.TotalDeduction, salary.NetSalary)
+    }
+  })
+}
+
+func TestGetSalariesAccess(t *testing.T) {
+  testEmployeeAccess(t, http.MethodGet, employeePath("/api/salary/%d"), nil, func(mock sqlmock.Sqlmock, tc accessCase) {
+    mock.ExpectQuery("FROM TBL_SALRY").
+      WithArgs(tc.targetID).
+      WillReturnRows(sqlmock.NewRows(salaryColumns).
+        AddRow(tc.targetID, "202505", 250000, 0, 12475, 0, 22875, 1500, 5500, 10000, 0).
+        AddRow(tc.targetID, "202504", 250000, 20000, 12475, 0, 22875, 1620, 5770, 10000, 0))
+  }, func(t *testing.T, tc accessCase, body []byte) {
+    var salaries []Salary
+    decodeBody(t, body, &salaries)
+    if len(salaries) != 2 || salaries[0].Month != "202505" || salaries[1].Month != "202504" {
+      t.Fatalf("給与%+v、202505・202504の2件を期待", salaries)
+    }
+    if salaries[0].NetSalary != 197650 || salaries[1].NetSalary != 217260 {
+      t.Errorf("netSalary=%d・%d、197650・217260を期待", salaries[0].NetSalary, salaries[1].NetSalary)
+    }
+  })
+}
+
+// 人事考課（TBL_KOUKA）の列
+var evaluationColumns = []string{"kokaid", "kokamt", "kokabk", "kokazg", "kokake", "kokaty", "kokajs"}
+
+func TestGetEvaluationAccess(t *testing.T) {
+  testEmployeeAccess(t, http.MethodGet, employeePath("/api/evaluation/%d/202504"), nil, func(mock sqlmock.Sqlmock, tc accessCase) {
+    mock.ExpectQuery("FROM TBL_KOUKA").
+      WithArgs(tc.targetID, "202504").
+      WillReturnRows(sqlmock.NewRows(evaluationColumns).
+        AddRow(tc.targetID, "202504", "目標を達成した", 4, 3, nil, "よくできました"))
+    expectNoRows(mock, "FROM TBL_KOUKA")
+  }, func(t *testing.T, tc accessCase, body []byte) {
+    var res struct {
+      Current  Evaluation `json:"current"`
+      Previous Evaluation `json:"previous"`
+    }
+    decodeBody(t, body, &res)
+    current := res.Current
+    if current.EmployeeID != tc.targetID || current.EmployeeComment != "目標を達成した" || current.ManagerComment != "よくできました" {
+      t.Errorf("当月の考課%+v、202504の考課を期待", current)
+    }
+    if current.SkillScore == nil || *current.SkillScore != 4 || current.AttitudeScore != nil {
+      t.Errorf("skillScore=%v attitudeScore=%v、4・nullを期待", current.SkillScore, current.AttitudeScore)
+    }
+    if res.Previous != (Evaluation{}) {
+      t.Errorf("前月の考課%+v、空を期待", res.Previous)
+    }
+  })
+}
+
+func TestGetEvaluationsAccess(t *testing.T) {
+  testEmployeeAccess(t, http.MethodGet, employeePath("/api/evaluation/%d"), nil, func(mock sqlmock.Sqlmock, tc accessCase) {
+    mock.ExpectQuery("FROM TBL_KOUKA").
+      WithArgs(tc.targetID).
+      WillReturnRows(sqlmock.NewRows(evaluationColumns).
+        AddRow(tc.targetID, "202504", "目標を達成した", 4, 3, 5, "よくできました").
+        AddRow(tc.targetID, "202503", "", nil, nil, nil, ""))
+  }, func(t *testing.T, tc accessCase, body []byte) {
+    var evaluations []Evaluation
+    decodeBody(t, body, &evaluations)
+    if len(evaluations) != 2 || evaluations[0].Month != "202504" || evaluations[1].Month != "202503" {
+      t.Fatalf("考課%+v、202504・202503の2件を期待", evaluations)
+    }
+    if evaluations[0].BehaviorScore == nil || *evaluations[0].BehaviorScore != 3 || evaluations[1].SkillScore != nil {
+      t.Errorf("考課%+v、202504は行動3・202503は未評価を期待", evaluations)
+    }
+  })
+}
+
+func TestCreateUpdateAttendanceAccess(t *testing.T) {
+  today := time.Now().Format("2006-01-02")
+  body := func(targetID int) string {
+    return fmt.Sprintf(`{"employeeId":%d,"date":"%s","startTime":"09:00","endTime":"18:00"}`, targetID, today)
+  }
+  testEmployeeAccess(t, http.MethodPost, fixedPath("/api/attendance"), body, func(mock sqlmock.Sqlmock, tc accessCase) {
+    expectNoRows(mock, "FROM TBL_SHIME")
+    expectNoRows(mock, "FROM TBL_GETSU")
+    if tc.role != roleHR {
+      mock.ExpectQuery("FROM TBL_LEAVE").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+    }
+    mock.ExpectQuery("FROM TBL_ATTEN").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+    expectNoRows(mock, "FROM TBL_ATTEN")
+    expectNoRows(mock, "FROM TBL_LEAVE")
+    mock.ExpectBegin()
+    mock.ExpectExec("DELETE FROM TBL_LEAVE").WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectExec("INSERT INTO TBL_ATTEN").WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec("DELETE FROM TBL_KUKAN").WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectExec("INSERT INTO TBL_HENKO").WithArgs(tc.targetID, today, nil, sqlmock.AnyArg(), tc.userID).
+      WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectCommit()
+  }, checkMessage("勤怠情報を更新しました"))
+}
+
+func TestUpdateEvaluationAccess(t *testing.T) {
+  // 考課を更新できるのは本人（コメントのみ）と直属の上司で、人事は参照のみ
+  cases := make([]accessCase, len(employeeAccessCases))
+  copy(cases, employeeAccessCases)
+  cases[3].want = http.StatusForbidden
+
+  body := func(targetID int) string {
+    return fmt.Sprintf(`{"employeeId":%d,"month":"202504","employeeComment":"目標を達成した"}`, targetID)
+  }
+  testEmployeeAccessCases(t, cases, http.MethodPost, fixedPath("/api/evaluation"), body, func(mock sqlmock.Sqlmock, tc accessCase) {
+    isEvaluator := tc.role == roleManager
+    expectDirectReport(mock, tc.userID, tc.targetID, isEvaluator)
+    if tc.role == roleHR {
+      return
+    }
+    mock.ExpectQuery("SELECT joshji").
+      WithArgs(tc.targetID).
+      WillReturnRows(sqlmock.NewRows([]string{"joshji"}).AddRow(testManagerID))
+    mock.ExpectBegin()
+    expectNoRows(mock, "FROM TBL_KOUKA")
+    mock.ExpectExec("INSERT INTO TBL_KOUKA").WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectCommit()
+  }, checkMessage("人事考課情報を更新しました"))
+}
+
+// 休暇の直接登録・削除は人事のみ（一般・上司はrequireRoleで拒否、TestAdminRoutesRequireRoleで検証）
+var hrOnlyAccessCases = []accessCase{
+  {"人事", testHRID, roleHR, testGeneralID, false, http.StatusOK},
+}
+
+// 休暇タイプ（介護、上限なし）の取得を期待する
+func expectLeaveType(mock sqlmock.Sqlmock) {
+  mock.ExpectQuery("FROM TBL_KYUKA").
+    WillReturnRows(sqlmock.NewRows([]string{"kyukid", "kyuknm", "kyukyk", "kyukht", "kyuksh", "kyuksm", "kyukjg", "kyukhs"}).
+      AddRow(5, "介護", false, true, false, false, nil, false))
+}
+
+func TestCreateLeaveAccess(t *testing.T) {
+  today := time.Now().Format("2006-01-02")
+  body := func(targetID int) string {
+    return fmt.Sprintf(`{"employeeId":%d,"date":"%s","leaveType":5}`, targetID, today)
+  }
+  testEmployeeAccessCases(t, hrOnlyAccessCases, http.MethodPost, fixedPath("/api/leave"), body, func(mock sqlmock.Sqlmock, tc accessCase) {
+    expectNoRows(mock, "FROM TBL_SHIME")
+    expectNoRows(mock, "FROM TBL_GETSU")
+    expectLeaveType(mock)
+    expectNoRows(mock, "FROM TBL_ATTEN")
+    expectNoRows(mock, "FROM TBL_LEAVE")
+    mock.ExpectBegin()
+    mock.ExpectExec("INSERT INTO TBL_LEAVE").WithArgs(tc.targetID, today, 5, leaveUnitFullDay, 0, false).
+      WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec("DELETE FROM TBL_ATTEN").WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectExec("INSERT INTO TBL_HENKO").WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectCommit()
+  }, checkMessage("休暇情報を登録しました"))
+}
+
+func TestDeleteLeaveAccess(t *testing.T) {
+  today := time.Now().Format("2006-01-02")
+  path := func(targetID int) string {
+    return fmt.Sprintf("/api/leave/%d/%s", targetID, today)
+  }
+  testEmployeeAccessCases(t, hrOnlyAccessCases, http.MethodDelete, path, nil, func(mock sqlmock.Sqlmock, tc accessCase) {
+    expectNoRows(mock, "FROM TBL_SHIME")
+    expectNoRows(mock, "FROM TBL_GETSU")
+    expectNoRows(mock, "FROM TBL_ATTEN")
+    mock.ExpectQuery("FROM TBL_LEAVE").
+      WillReturnRows(sqlmock.NewRows([]string{"leretp", "lerekb", "lerehr", "leresh"}).AddRow(5, leaveUnitFullDay, 0, false))
+    mock.ExpectBegin()
+    mock.ExpectExec("DELETE FROM TBL_LEAVE").WithArgs(tc.targetID, today).WillReturnResult(sqlmock.NewResult(0, 1))
+    expectNoRows(mock, "UPDATE TBL_KYUSE")
+    mock.ExpectExec("INSERT INTO TBL_HENKO").WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectCommit()
+  }, checkMessage("休暇情報を削除しました"))
+}
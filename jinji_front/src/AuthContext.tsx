@@ -4,6 +4,7 @@ import React, { createContext, useState, useEffect, ReactNode } from 'react';
 export interface Employee {
   id: number;
   name: string;
+  role: number; // 1一般,2上司,3人事
   isAdmin: boolean;
 }
 
@@ -4,6 +4,8 @@ import {
   AttendanceData, 
   AttendanceRecord, 
   LeaveRecord,
+  LeaveBalance,
+  LeaveType,
   SalaryData,
   EvaluationData
 } from './types';
@@ -139,6 +141,52 @@ export const updateAttendance = async (
   );
 };
 
+// 勤怠の修正申請（過去の日の勤怠は上司の承認後に反映）
+export const submitCorrection = async (
+  attendance: AttendanceRecord, 
+  reason: string,
+  token: string
+): Promise<ApiResponse<{message: string}>> => {
+  return fetchWithRetry<{message: string}>(
+    `${API_BASE_URL}/corrections`, 
+    {
+      method: 'POST',
+      headers: getHeaders(token),
+      body: JSON.stringify({ attendance, reason }),
+    }
+  );
+};
+
+// 休暇申請（上司の承認後に休暇として登録）
+export const submitLeaveRequest = async (
+  leave: AttendanceRecord, 
+  reason: string,
+  token: string
+): Promise<ApiResponse<{message: string}>> => {
+  return fetchWithRetry<{message: string}>(
+    `${API_BASE_URL}/leave-requests`, 
+    {
+      method: 'POST',
+      headers: getHeaders(token),
+      body: JSON.stringify({ leave, reason }),
+    }
+  );
+};
+
+// 打刻（出勤・退勤・休憩開始・休憩終了）
+export const punch = async (
+  kind: 'in' | 'out' | 'break-start' | 'break-end',
+  token: string
+): Promise<ApiResponse<{message: string, date: string, time: string}>> => {
+  return fetchWithRetry<{message: string, date: string, time: string}>(
+    `${API_BASE_URL}/punch/${kind}`, 
+    {
+      method: 'POST',
+      headers: getHeaders(token),
+    }
+  );
+};
+
 // 休暇情報登録
 export const createLeave = async (
   leave: LeaveRecord, 
@@ -154,6 +202,33 @@ export const createLeave = async (
   );
 };
 
+// 年次有給休暇の残日数取得
+export const getLeaveBalance = async (
+  employeeId: number, 
+  token: string
+): Promise<ApiResponse<LeaveBalance>> => {
+  return fetchWithRetry<LeaveBalance>(
+    `${API_BASE_URL}/leave/${employeeId}/balance`, 
+    {
+      method: 'GET',
+      headers: getHeaders(token),
+    }
+  );
+};
+
+// 休暇タイプ一覧取得
+export const getLeaveTypes = async (
+  token: string
+): Promise<ApiResponse<LeaveType[]>> => {
+  return fetchWithRetry<LeaveType[]>(
+    `${API_BASE_URL}/leave-types`, 
+    {
+      method: 'GET',
+      headers: getHeaders(token),
+    }
+  );
+};
+
 // 休暇情報削除
 export const deleteLeave = async (
   employeeId: number, 
@@ -1,8 +1,8 @@
 import React, { useState, useEffect, useCallback } from 'react';
 import { useNavigate } from 'react-router-dom';
 import { useAuth } from '../useAuth';
-import { getMonthlyAttendance, getDailyAttendance, updateAttendance } from '../api';
-import { AttendanceData, AttendanceRecord, LEAVE_TYPES, CalendarDay } from '../types';
+import { getMonthlyAttendance, getDailyAttendance, updateAttendance, submitCorrection, submitLeaveRequest, punch, getLeaveBalance, getLeaveTypes } from '../api';
+import { AttendanceData, AttendanceRecord, LeaveBalance, LeaveType, LEAVE_UNITS, CalendarDay } from '../types';
 import './common.css';
 
 const Kintai: React.FC = () => {
@@ -24,7 +24,13 @@ const Kintai: React.FC = () => {
   // フォーム状態
   const [startTime, setStartTime] = useState<string>('');
   const [endTime, setEndTime] = useState<string>('');
+  const [endNextDay, setEndNextDay] = useState<boolean>(false);
   const [leaveType, setLeaveType] = useState<number>(0);
+  const [leaveUnit, setLeaveUnit] = useState<number>(1);
+  const [leaveHours, setLeaveHours] = useState<number>(1);
+  const [reason, setReason] = useState<string>(''); // 修正申請の理由
+  const [leaveBalance, setLeaveBalance] = useState<LeaveBalance | null>(null);
+  const [leaveTypes, setLeaveTypes] = useState<LeaveType[]>([]);
   
   // 年月の文字列を取得
   const getYearMonthString = (date: Date): string => {
@@ -145,6 +151,10 @@ const Kintai: React.FC = () => {
         setAttendanceData(result.data);
         generateCalendar(currentDate, result.data);
       }
+      
+      // 年次有給休暇の残日数（入社日が未登録の場合などは表示しない）
+      const balance = await getLeaveBalance(employee.id, token);
+      setLeaveBalance(balance.data || null);
     } catch (err) {
       setError('勤怠データの取得中にエラーが発生しました');
       console.error(err);
@@ -184,7 +194,10 @@ const Kintai: React.FC = () => {
         setSelectedDayData(result.data);
         setStartTime(result.data.startTime || '');
         setEndTime(result.data.endTime || '');
+        setEndNextDay(result.data.endDayOffset === 1);
         setLeaveType(result.data.leaveType || 0);
+        setLeaveUnit(result.data.leaveUnit || 1);
+        setLeaveHours(result.data.leaveHours || 1);
       } else {
         // データがない場合は初期化
         setSelectedDayData({
@@ -193,7 +206,10 @@ const Kintai: React.FC = () => {
         });
         setStartTime('');
         setEndTime('');
+        setEndNextDay(false);
         setLeaveType(0);
+        setLeaveUnit(1);
+        setLeaveHours(1);
       }
     } catch (err) {
       setError('勤怠データの取得中にエラーが発生しました');
@@ -203,6 +219,16 @@ const Kintai: React.FC = () => {
     }
   }, [employee, token, updateCount]);
   
+  // 過去の日の勤怠は修正申請（人事は直接修正できる）
+  const needsCorrection = (date: Date): boolean => {
+    return employee?.role !== 3 && getDateString(date) < getDateString(new Date());
+  };
+  
+  // 休暇は休暇申請（人事は直接登録できる）
+  const needsLeaveRequest = (): boolean => {
+    return employee?.role !== 3 && leaveType > 0;
+  };
+  
   // 日付選択ハンドラ
   const handleDayClick = (day: CalendarDay) => {
     setSelectedDate(day.date);
@@ -220,20 +246,58 @@ const Kintai: React.FC = () => {
     
     try {
       // 休暇タイプが選択されている場合と出退勤時間が入力されている場合で処理を分ける
+      // 区間・計算項目はサーバー側で出退勤時刻から作り直す
       let attendanceRecord: AttendanceRecord = {
-        ...selectedDayData
+        ...selectedDayData,
+        segments: undefined,
+        breaks: undefined,
+        workedMinutes: undefined,
+        breakMinutes: undefined,
+        overtimeMinutes: undefined
       };
       
       if (leaveType > 0) {
         // 休暇の場合
         attendanceRecord.leaveType = leaveType;
+        attendanceRecord.leaveUnit = leaveUnit;
+        attendanceRecord.leaveHours = leaveUnit === 4 ? leaveHours : undefined;
         attendanceRecord.startTime = undefined;
         attendanceRecord.endTime = undefined;
+        attendanceRecord.endDayOffset = undefined;
       } else {
         // 通常勤怠の場合
         attendanceRecord.startTime = startTime || undefined;
         attendanceRecord.endTime = endTime || undefined;
+        attendanceRecord.endDayOffset = endTime && endNextDay ? 1 : undefined;
         attendanceRecord.leaveType = undefined;
+        attendanceRecord.leaveUnit = undefined;
+        attendanceRecord.leaveHours = undefined;
+      }
+      
+      if (needsLeaveRequest()) {
+        const request = await submitLeaveRequest(attendanceRecord, reason, token);
+        if (request.error) {
+          setError(request.error);
+          return;
+        }
+        setReason('');
+        setSuccess('休暇申請を登録しました（上司の承認後に反映されます）');
+        return;
+      }
+      
+      if (needsCorrection(selectedDate)) {
+        if (!reason.trim()) {
+          setError('修正理由を入力してください');
+          return;
+        }
+        const correction = await submitCorrection(attendanceRecord, reason, token);
+        if (correction.error) {
+          setError(correction.error);
+          return;
+        }
+        setReason('');
+        setSuccess('修正申請を登録しました（上司の承認後に反映されます）');
+        return;
       }
       
       const result = await updateAttendance(attendanceRecord, token);
@@ -266,6 +330,34 @@ const Kintai: React.FC = () => {
     }
   };
   
+  // 打刻処理（時刻はサーバー側で記録）
+  const handlePunch = async (kind: 'in' | 'out' | 'break-start' | 'break-end') => {
+    if (!employee || !token) return;
+    
+    setIsLoading(true);
+    setError(null);
+    setSuccess(null);
+    
+    try {
+      const result = await punch(kind, token);
+      if (result.error) {
+        setError(result.error);
+        return;
+      }
+      
+      setSuccess(`${result.data?.message}（${result.data?.time}）`);
+      
+      // 勤怠データを再取得
+      await fetchMonthlyAttendance();
+      await fetchDailyAttendance(selectedDate);
+    } catch (err) {
+      setError('打刻中にエラーが発生しました');
+      console.error(err);
+    } finally {
+      setIsLoading(false);
+    }
+  };
+  
   // 前月へ移動
   const goToPreviousMonth = () => {
     const newDate = new Date(currentDate.getFullYear(), currentDate.getMonth() - 1, 1);
@@ -295,6 +387,17 @@ const Kintai: React.FC = () => {
     fetchDailyAttendance(selectedDate);
   }, [employee, token, navigate, fetchMonthlyAttendance, fetchDailyAttendance, selectedDate]);
   
+  // 休暇タイプ（人事が管理するマスタ）
+  useEffect(() => {
+    if (!token) return;
+    getLeaveTypes(token).then((result) => setLeaveTypes(result.data || []));
+  }, [token]);
+  
+  // 休暇タイプ名
+  const leaveTypeName = (id: number): string => {
+    return leaveTypes.find((t) => t.id === id)?.name || '';
+  };
+  
   // 月変更時のデータ再取得
   useEffect(() => {
     if (fetchMonthlyAttendance) {
@@ -350,7 +453,7 @@ const Kintai: React.FC = () => {
           textOverflow: 'ellipsis'
         }}>
           {day.attendance.startTime && day.attendance.startTime.substring(0, 5)}
-          {day.attendance.endTime && ` - ${day.attendance.endTime.substring(0, 5)}`}
+          {day.attendance.endTime && ` - ${day.attendance.endDayOffset === 1 ? '翌' : ''}${day.attendance.endTime.substring(0, 5)}`}
         </div>
       )}
       {day.leave && (
@@ -364,7 +467,7 @@ const Kintai: React.FC = () => {
           overflow: 'hidden',
           textOverflow: 'ellipsis'
         }}>
-          {LEAVE_TYPES[day.leave.leaveType as keyof typeof LEAVE_TYPES]}
+          {leaveTypeName(day.leave.leaveType)}
         </div>
       )}
     </div>
@@ -404,6 +507,19 @@ const Kintai: React.FC = () => {
     </div>
   );
   
+  // 打刻ボタン部分
+  const renderPunchButtons = () => (
+    <div className="content-card">
+      <h3>打刻</h3>
+      <div style={{ display: 'flex', flexWrap: 'wrap', gap: '10px' }}>
+        <button onClick={() => handlePunch('in')} disabled={isLoading}>出勤</button>
+        <button onClick={() => handlePunch('out')} disabled={isLoading}>退勤</button>
+        <button className="secondary" onClick={() => handlePunch('break-start')} disabled={isLoading}>休憩開始</button>
+        <button className="secondary" onClick={() => handlePunch('break-end')} disabled={isLoading}>休憩終了</button>
+      </div>
+    </div>
+  );
+  
   // 勤怠情報入力フォーム部分
   const renderAttendanceForm = () => {
     const formattedDate = `${selectedDate.getFullYear()}年${selectedDate.getMonth() + 1}月${selectedDate.getDate()}日(${
@@ -417,6 +533,14 @@ const Kintai: React.FC = () => {
         {error && <div className="alert alert-error">{error}</div>}
         {success && <div className="alert alert-success">{success}</div>}
         
+        {selectedDayData?.workedMinutes !== undefined && (
+          <div style={{ marginBottom: '10px', color: '#555' }}>
+            実労働 {Math.floor(selectedDayData.workedMinutes / 60)}時間{selectedDayData.workedMinutes % 60}分
+            （休憩 {selectedDayData.breakMinutes || 0}分
+            {selectedDayData.overtimeMinutes ? `、8時間超 ${selectedDayData.overtimeMinutes}分` : ''}）
+          </div>
+        )}
+        
         <form onSubmit={handleSubmit}>
           <div className="form-group">
             <label>勤怠タイプ</label>
@@ -462,6 +586,14 @@ const Kintai: React.FC = () => {
                   value={endTime}
                   onChange={(e) => setEndTime(e.target.value)}
                 />
+                <label>
+                  <input
+                    type="checkbox"
+                    checked={endNextDay}
+                    onChange={(e) => setEndNextDay(e.target.checked)}
+                  />
+                  翌日
+                </label>
               </div>
             </>
           ) : (
@@ -472,12 +604,61 @@ const Kintai: React.FC = () => {
                 value={leaveType}
                 onChange={(e) => setLeaveType(parseInt(e.target.value, 10))}
               >
-                {Object.entries(LEAVE_TYPES).map(([value, label]) => (
+                {leaveTypes
+                  .filter((t) => !t.retired || t.id === leaveType)
+                  .map((t) => (
+                    <option key={t.id} value={t.id}>
+                      {t.name}
+                      {t.needsCertificate && '（要証明書類）'}
+                    </option>
+                  ))}
+              </select>
+              <label htmlFor="leaveUnit">単位（半休・時間単位の日は勤怠も登録できます）</label>
+              <select
+                id="leaveUnit"
+                value={leaveUnit}
+                onChange={(e) => setLeaveUnit(parseInt(e.target.value, 10))}
+              >
+                {Object.entries(LEAVE_UNITS).map(([value, label]) => (
                   <option key={value} value={value}>
                     {label}
                   </option>
                 ))}
               </select>
+              {leaveUnit === 4 && (
+                <>
+                  <label htmlFor="leaveHours">時間数</label>
+                  <input
+                    type="number"
+                    id="leaveHours"
+                    min={1}
+                    value={leaveHours}
+                    onChange={(e) => setLeaveHours(parseInt(e.target.value, 10) || 1)}
+                  />
+                </>
+              )}
+            </div>
+          )}
+          
+          {needsLeaveRequest() ? (
+            <div className="form-group">
+              <label htmlFor="reason">申請理由（任意、上司の承認後に休暇として登録されます）</label>
+              <input
+                type="text"
+                id="reason"
+                value={reason}
+                onChange={(e) => setReason(e.target.value)}
+              />
+            </div>
+          ) : needsCorrection(selectedDate) && (
+            <div className="form-group">
+              <label htmlFor="reason">修正理由（過去の日の勤怠は上司の承認後に反映されます）</label>
+              <input
+                type="text"
+                id="reason"
+                value={reason}
+                onChange={(e) => setReason(e.target.value)}
+              />
             </div>
           )}
           
@@ -488,7 +669,7 @@ const Kintai: React.FC = () => {
                   <span className="loading-spinner" style={{ marginRight: '8px' }}></span>
                   処理中...
                 </>
-              ) : '保存'}
+              ) : needsLeaveRequest() ? '休暇を申請' : needsCorrection(selectedDate) ? '修正を申請' : '保存'}
             </button>
           </div>
         </form>
@@ -498,10 +679,18 @@ const Kintai: React.FC = () => {
   
   // 休暇一覧部分
   const renderLeaveList = () => {
+    const balanceText = leaveBalance && (
+      <p>
+        年次有給休暇の残日数: {leaveBalance.remaining}日
+        {leaveBalance.nextGrantDate && `（次回付与 ${leaveBalance.nextGrantDate} ${leaveBalance.nextGrantDays}日）`}
+      </p>
+    );
+    
     if (!attendanceData || !attendanceData.leaves || attendanceData.leaves.length === 0) {
       return (
         <div className="content-card">
           <h3>休暇一覧</h3>
+          {balanceText}
           <p>当月の休暇はありません。</p>
         </div>
       );
@@ -510,6 +699,7 @@ const Kintai: React.FC = () => {
     return (
       <div className="content-card">
         <h3>休暇一覧</h3>
+        {balanceText}
         <table>
           <thead>
             <tr>
@@ -527,7 +717,13 @@ const Kintai: React.FC = () => {
                       ['日', '月', '火', '水', '木', '金', '土'][leaveDate.getDay()]
                     })`}
                   </td>
-                  <td>{LEAVE_TYPES[leave.leaveType as keyof typeof LEAVE_TYPES]}</td>
+                  <td>
+                    {leaveTypeName(leave.leaveType)}
+                    {leave.leaveUnit === 4
+                      ? `（${leave.leaveHours}時間）`
+                      : leave.leaveUnit && leave.leaveUnit !== 1 && `（${LEAVE_UNITS[leave.leaveUnit as keyof typeof LEAVE_UNITS]}）`}
+                    {leave.plannedLeave && '（時季指定）'}
+                  </td>
                 </tr>
               );
             })}
@@ -548,8 +744,9 @@ const Kintai: React.FC = () => {
             {renderCalendar()}
           </div>
           
-          {/* 右側: 勤怠情報フォーム */}
+          {/* 右側: 打刻・勤怠情報フォーム */}
           <div style={{ flex: '1', minWidth: '300px' }}>
+            {renderPunchButtons()}
             {renderAttendanceForm()}
             {renderLeaveList()}
           </div>
@@ -281,6 +281,12 @@ const Kyuyo: React.FC = () => {
                   <td>控除額合計</td>
                   <td style={{ textAlign: 'right' }}>{salary.totalDeduction.toLocaleString()}円</td>
                 </tr>
+                {salary.yearEndAdjustment !== 0 && (
+                  <tr>
+                    <td>年末調整（{salary.yearEndAdjustment > 0 ? '還付' : '徴収'}）</td>
+                    <td style={{ textAlign: 'right' }}>{Math.abs(salary.yearEndAdjustment).toLocaleString()}円</td>
+                  </tr>
+                )}
               </tbody>
             </table>
           </div>
@@ -6,7 +6,23 @@ export interface AttendanceRecord {
   date: string;
   startTime?: string;
   endTime?: string;
+  endDayOffset?: number; // 退勤が翌日の場合は1
   leaveType?: number;
+  leaveUnit?: number; // 休暇の単位（LEAVE_UNITS、省略時は全日）
+  leaveHours?: number; // 時間単位の休暇の時間数
+  segments?: AttendanceSegment[]; // 勤務区間（1日に複数回の出退勤）
+  breaks?: AttendanceSegment[]; // 休憩区間
+  workedMinutes?: number; // 実労働時間（分、サーバーで計算）
+  breakMinutes?: number; // 休憩時間（分、サーバーで計算）
+  overtimeMinutes?: number; // 1日8時間を超える労働時間（分、サーバーで計算）
+}
+
+// 勤務・休憩の区間（翌日の場合はオフセット1）
+export interface AttendanceSegment {
+  startTime: string;
+  startDayOffset?: number;
+  endTime?: string;
+  endDayOffset?: number;
 }
 
 // 勤怠データ（月別）
@@ -20,18 +36,41 @@ export interface LeaveRecord {
   employeeId: number;
   date: string;
   leaveType: number;
+  leaveUnit?: number;
+  leaveHours?: number;
+  plannedLeave?: boolean; // 人事が時季指定した年次有給休暇
+}
+
+// 年次有給休暇の残日数
+export interface LeaveBalance {
+  employeeId: number;
+  hireDate: string;
+  asOf: string; // 基準日
+  granted: number; // 有効な付与日数の合計
+  used: number;
+  remaining: number;
+  nextGrantDate: string;
+  nextGrantDays: number;
+}
+
+// 休暇タイプ（人事が管理するマスタ）
+export interface LeaveType {
+  id: number;
+  name: string;
+  paid: boolean; // 有給
+  statutory: boolean; // 法定休暇
+  consumesAnnualLeave: boolean; // 年次有給休暇の残日数から消化する
+  needsCertificate: boolean; // 証明書類の提出が必要
+  yearlyCapDays: number | null; // 年度（4月始まり）の上限日数
+  retired?: boolean; // 廃止（新たに登録できない）
 }
 
-// 休暇タイプ
-export const LEAVE_TYPES = {
-  1: '年次有給',
-  2: '産前',
-  3: '産後',
-  4: '育児',
-  5: '介護',
-  6: '子の看護',
-  7: '生理',
-  8: '母性健康管理',
+// 休暇の単位
+export const LEAVE_UNITS = {
+  1: '全日',
+  2: '午前半休',
+  3: '午後半休',
+  4: '時間単位',
 };
 
 // 給与データ
@@ -46,6 +85,7 @@ export interface SalaryData {
   employmentInsurance: number;
   incomeTax: number;
   residentTax: number;
+  yearEndAdjustment: number; // 年末調整の過不足額（プラスは還付）
   totalDeduction: number;
   netSalary: number;
 }